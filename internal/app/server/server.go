@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -9,12 +10,17 @@ import (
 	"github.com/gin-gonic/gin"
 	thisDocs "github.com/mibrgmv/document-service/docs"
 	"github.com/mibrgmv/document-service/internal/config"
+	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/handlers"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/repository/fs"
 	"github.com/mibrgmv/document-service/internal/repository/postgres"
 	"github.com/mibrgmv/document-service/internal/repository/redis"
+	"github.com/mibrgmv/document-service/internal/repository/s3"
 	"github.com/mibrgmv/document-service/internal/service"
 	"github.com/mibrgmv/document-service/pkg/database"
 	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/oidc"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -22,6 +28,11 @@ import (
 type Server struct {
 	cfg    *config.Config
 	server *http.Server
+
+	authService   service.AuthService
+	keyService    service.KeyService
+	uploadService service.UploadService
+	done          chan struct{}
 }
 
 func New(cfg *config.Config) *Server {
@@ -39,20 +50,132 @@ func New(cfg *config.Config) *Server {
 		log.Fatal(err)
 	}
 
-	jwtManager := jwt.NewManager(cfg.JWT.Secret, cfg.JWT.Expiration)
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := postgres.MoveLegacyData(context.Background(), pg, blobStore); err != nil {
+		log.Fatal("failed to move legacy documents: ", err)
+	}
+
+	if err := postgres.IndexLegacyText(context.Background(), pg, blobStore); err != nil {
+		log.Fatal("failed to index documents for search: ", err)
+	}
+
+	// A new key is picked up by the other instances within a refresh interval
+	// and by their JWKS clients within the cache lifetime after that.
+	publishDelay := cfg.JWT.KeyRefreshInterval + jwt.JWKSMaxAge
+	jwtManager := jwt.NewManager(jwt.Config{
+		Algorithm:         cfg.JWT.Algorithm,
+		Secret:            cfg.JWT.Secret,
+		Issuer:            cfg.JWT.Issuer,
+		Audience:          cfg.JWT.Audience,
+		Expiration:        cfg.JWT.Expiration,
+		RefreshExpiration: cfg.JWT.RefreshExpiration,
+		Leeway:            cfg.JWT.Leeway,
+		PublishDelay:      publishDelay,
+	})
 
 	userRepo := postgres.NewUserRepository(pg)
 	docRepo := postgres.NewDocumentRepository(pg)
+	uploadRepo := postgres.NewUploadRepository(pg)
+	folderRepo := postgres.NewFolderRepository(pg)
 	cacheRepo := redis.NewCacheRepository(rdb)
+	sessionRepo := redis.NewSessionRepository(rdb)
+	refreshRepo := postgres.NewRefreshTokenRepository(pg)
+	keyRepo := postgres.NewSigningKeyRepository(pg)
+	apiKeyRepo := postgres.NewAPIKeyRepository(pg)
+	oidcStateRepo := redis.NewOIDCStateRepository(rdb)
+	challengeRepo := redis.NewMFAChallengeRepository(rdb)
+	attemptRepo := redis.NewLoginAttemptRepository(rdb)
+	resetRepo := redis.NewPasswordResetRepository(rdb)
+	groupRepo := postgres.NewGroupRepository(pg)
+	linkRepo := postgres.NewShareLinkRepository(pg)
+	auditRepo := postgres.NewAuditRepository(pg)
+
+	oidcConfig := service.OIDCConfig{
+		LoginClaim: cfg.OIDC.LoginClaim,
+		StateTTL:   cfg.OIDC.StateTTL,
+	}
+	if cfg.OIDC.Issuer != "" {
+		oidcConfig.Provider = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			Leeway:       cfg.JWT.Leeway,
+		})
+	}
+
+	throttle := service.LoginThrottle{
+		FreeAttempts:     cfg.LoginThrottle.FreeAttempts,
+		IPFreeAttempts:   cfg.LoginThrottle.IPFreeAttempts,
+		BaseDelay:        cfg.LoginThrottle.BaseDelay,
+		MaxDelay:         cfg.LoginThrottle.MaxDelay,
+		LockoutThreshold: cfg.LoginThrottle.LockoutThreshold,
+		LockoutDuration:  cfg.LoginThrottle.LockoutDuration,
+		Window:           cfg.LoginThrottle.Window,
+	}
+
+	passwords := service.PasswordPolicy{
+		MinLength:               cfg.PasswordPolicy.MinLength,
+		RequireCharacterClasses: cfg.PasswordPolicy.RequireCharacterClasses,
+	}
+	if cfg.PasswordPolicy.BreachedList != "" {
+		passwords.Breached, err = service.ReadPasswordList(cfg.PasswordPolicy.BreachedList)
+		if err != nil {
+			log.Fatal("failed to read breached passwords: ", err)
+		}
+	}
+
+	auditor := service.NewAuditor(auditRepo)
+	authService := service.NewAuthService(userRepo, sessionRepo, refreshRepo, oidcStateRepo, challengeRepo, attemptRepo,
+		resetRepo, jwtManager, auditor, oidcConfig, throttle, passwords, cfg.AdminToken)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, auditor)
+	// A replaced key keeps signing until its successor is published, on an
+	// instance that refreshes its keys last, and the tokens it signed then
+	// are valid for their whole lifetime.
+	keyService := service.NewKeyService(keyRepo, jwtManager, cfg.JWT.Algorithm, cfg.JWT.KeyRotation,
+		publishDelay+cfg.JWT.KeyRefreshInterval+cfg.JWT.Expiration+cfg.JWT.Leeway)
+	access := service.NewAccessControl(userRepo, groupRepo)
+	docService := service.NewDocumentService(docRepo, folderRepo, access, auditor, cacheRepo, blobStore)
+	folderService := service.NewFolderService(folderRepo, docRepo)
+	groupService := service.NewGroupService(groupRepo, userRepo, auditor, cacheRepo)
+	linkService := service.NewLinkService(linkRepo, docRepo, access, auditor, attemptRepo, blobStore, throttle)
+	auditService := service.NewAuditService(auditRepo)
+	uploadService := service.NewUploadService(uploadRepo, folderRepo, blobStore, docService, access, cfg.Uploads.TTL, cfg.Server.MaxUploadSize)
 
-	authService := service.NewAuthService(userRepo, cacheRepo, jwtManager, cfg.AdminToken)
-	docService := service.NewDocumentService(docRepo, cacheRepo)
+	if cfg.JWT.Algorithm != jwt.HS256 {
+		if err := keyService.RefreshKeys(context.Background()); err != nil {
+			log.Fatal("failed to load signing keys: ", err)
+		}
+		// Tokens signed with a key another instance has just rotated in are
+		// accepted without waiting for the next refresh.
+		jwtManager.ReloadKeysOnMiss(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return keyService.RefreshKeys(ctx)
+		}, 10*time.Second)
+	}
 
 	authHandler := handlers.NewAuthHandler(authService)
+	keyHandler := handlers.NewKeyHandler(keyService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 	docHandler := handlers.NewDocumentHandler(docService)
+	uploadHandler := handlers.NewUploadHandler(uploadService, cfg.Server.MaxUploadSize)
+	folderHandler := handlers.NewFolderHandler(folderService, docService)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	linkHandler := handlers.NewLinkHandler(linkService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("invalid trusted proxies: ", err)
+	}
 	router.Use(handlers.CORSMiddleware())
+	router.Use(handlers.ClientMiddleware())
 
 	thisDocs.SwaggerInfo.Host = cfg.Server.Port
 	if cfg.Server.Port != "" {
@@ -60,44 +183,201 @@ func New(cfg *config.Config) *Server {
 	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", keyHandler.JWKS)
+
+	auth := handlers.AuthMiddleware(authService, apiKeyService)
+	read := handlers.RequireScope(domain.ScopeDocsRead)
+	write := handlers.RequireScope(domain.ScopeDocsWrite)
+	del := handlers.RequireScope(domain.ScopeDocsDelete)
+	admin := handlers.RequireScope(domain.ScopeAdmin)
+
+	transfer := handlers.TransferTimeout(cfg.Server.TransferTimeout)
+	bodyLimit := handlers.BodyLimit(cfg.Server.MaxUploadSize)
+
+	router.GET("/s/:token", transfer, linkHandler.OpenLink)
+	router.POST("/s/:token", transfer, linkHandler.OpenLink)
 
 	api := router.Group("/api")
 	{
 		api.POST("/register", authHandler.Register)
 		api.POST("/auth", authHandler.Auth)
+		api.POST("/auth/refresh", authHandler.Refresh)
+		api.POST("/auth/mfa", authHandler.VerifyMFA)
+		api.POST("/auth/password-reset", authHandler.ResetPassword)
+		api.GET("/auth/oidc", authHandler.OIDCLogin)
+		api.GET("/auth/oidc/callback", authHandler.OIDCCallback)
+		api.DELETE("/auth/sessions", auth, authHandler.LogoutAll)
 		api.DELETE("/auth/:token", authHandler.Logout)
 
 		docs := api.Group("/docs")
-		docs.Use(handlers.AuthMiddleware(jwtManager))
+		docs.Use(auth)
 		{
-			docs.GET("", docHandler.GetDocuments)
-			docs.HEAD("", docHandler.GetDocumentsHead)
-			docs.POST("", docHandler.UploadDocument)
-			docs.GET("/:id", docHandler.GetDocument)
-			docs.HEAD("/:id", docHandler.GetDocumentHead)
-			docs.DELETE("/:id", docHandler.DeleteDocument)
+			docs.GET("", read, docHandler.GetDocuments)
+			docs.HEAD("", read, docHandler.GetDocumentsHead)
+			docs.GET("/search", read, docHandler.SearchDocuments)
+			docs.POST("", write, transfer, bodyLimit, docHandler.UploadDocument)
+			docs.GET("/:id", read, transfer, docHandler.GetDocument)
+			docs.HEAD("/:id", read, docHandler.GetDocumentHead)
+			docs.PUT("/:id", write, transfer, bodyLimit, docHandler.UpdateDocument)
+			docs.PATCH("/:id", write, docHandler.UpdateDocumentMeta)
+			docs.DELETE("/:id", del, docHandler.DeleteDocument)
+			docs.GET("/:id/versions", read, docHandler.GetVersions)
+			docs.GET("/:id/versions/:version", read, transfer, docHandler.GetVersion)
+			docs.POST("/:id/versions/:version/restore", write, docHandler.RestoreVersion)
+			docs.GET("/:id/diff", read, docHandler.DiffVersions)
+			docs.GET("/:id/links", read, linkHandler.GetLinks)
+			docs.POST("/:id/links", write, linkHandler.CreateLink)
+			docs.DELETE("/:id/links/:link", write, linkHandler.RevokeLink)
+			docs.GET("/:id/links/:link/accesses", read, linkHandler.GetAccesses)
+		}
+
+		folders := api.Group("/folders")
+		folders.Use(auth)
+		{
+			folders.GET("", read, folderHandler.GetRootFolder)
+			folders.POST("", write, folderHandler.CreateFolder)
+			folders.GET("/:id", read, folderHandler.GetFolder)
+			folders.PATCH("/:id", write, folderHandler.UpdateFolder)
+			folders.DELETE("/:id", del, folderHandler.DeleteFolder)
+		}
+
+		api.GET("/paths/*path", auth, read, transfer, folderHandler.GetDocumentByPath)
+
+		groups := api.Group("/groups")
+		groups.Use(auth)
+		{
+			groups.GET("", read, groupHandler.GetGroups)
+			groups.POST("", write, groupHandler.CreateGroup)
+			groups.GET("/:name", read, groupHandler.GetGroup)
+			groups.DELETE("/:name", write, groupHandler.DeleteGroup)
+			groups.POST("/:name/members", write, groupHandler.AddMember)
+			groups.DELETE("/:name/members/:login", write, groupHandler.RemoveMember)
+		}
+
+		// The account routes below need no docs scope: they manage the
+		// account rather than documents and refuse API keys, and an API key
+		// created here cannot get scopes the token creating it lacks.
+		me := api.Group("/users/me")
+		me.Use(auth)
+		{
+			me.POST("/mfa", authHandler.EnrollTOTP)
+			me.POST("/mfa/confirm", authHandler.ConfirmTOTP)
+			me.DELETE("/mfa", authHandler.DisableTOTP)
+			me.POST("/password", authHandler.ChangePassword)
+		}
+
+		api.POST("/users/:login/password-reset", auth, admin, authHandler.IssuePasswordReset)
+		api.DELETE("/users/:login/lockout", auth, admin, authHandler.UnlockLogin)
+		api.GET("/audit", auth, admin, transfer, auditHandler.GetEvents)
+
+		keys := api.Group("/keys")
+		keys.Use(auth)
+		{
+			keys.GET("", apiKeyHandler.GetAPIKeys)
+			keys.POST("", apiKeyHandler.CreateAPIKey)
+			keys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+
+		uploads := api.Group("/uploads")
+		uploads.Use(handlers.TusResumable())
+		uploads.OPTIONS("", uploadHandler.Options)
+		uploads.Use(auth, write)
+		{
+			uploads.POST("", uploadHandler.CreateUpload)
+			uploads.HEAD("/:id", uploadHandler.GetUploadOffset)
+			uploads.PATCH("/:id", transfer, uploadHandler.PatchUpload)
+			uploads.DELETE("/:id", uploadHandler.DeleteUpload)
 		}
 	}
 
 	httpServer := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	return &Server{
-		cfg:    cfg,
-		server: httpServer,
+		cfg:           cfg,
+		server:        httpServer,
+		authService:   authService,
+		keyService:    keyService,
+		uploadService: uploadService,
+		done:          make(chan struct{}),
+	}
+}
+
+func newBlobStore(cfg *config.Config) (repository.BlobStore, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		return fs.NewBlobStore(cfg.Storage.Local.Path)
+	case "s3":
+		client, err := database.NewS3(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return s3.NewBlobStore(client, cfg.Storage.S3.Bucket), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
 	}
 }
 
 func (s *Server) Start() error {
+	go s.expire()
+	if s.cfg.JWT.Algorithm != jwt.HS256 {
+		go s.refreshKeys()
+	}
+
 	return s.server.ListenAndServe()
 }
 
 func (s *Server) Stop(ctx context.Context) error {
 	log.Println("Gracefully shutting down server...")
+	close(s.done)
 	return s.server.Shutdown(ctx)
 }
+
+func (s *Server) expire() {
+	ticker := time.NewTicker(s.cfg.Uploads.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			n, err := s.uploadService.ExpireUploads(context.Background())
+			if err != nil {
+				log.Printf("failed to expire uploads: %v", err)
+			} else if n > 0 {
+				log.Printf("expired %d abandoned uploads", n)
+			}
+
+			tokens, err := s.authService.ExpireRefreshTokens(context.Background())
+			if err != nil {
+				log.Printf("failed to expire refresh tokens: %v", err)
+			} else if tokens > 0 {
+				log.Printf("removed %d expired refresh tokens", tokens)
+			}
+		}
+	}
+}
+
+// refreshKeys picks up keys rotated by other instances and rotates the
+// signing key when it is due.
+func (s *Server) refreshKeys() {
+	ticker := time.NewTicker(s.cfg.JWT.KeyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.keyService.RefreshKeys(context.Background()); err != nil {
+				log.Printf("failed to refresh signing keys: %v", err)
+			}
+		}
+	}
+}
@@ -17,6 +17,15 @@ type Config struct {
 		ReadTimeout  time.Duration `yaml:"read_timeout"`
 		WriteTimeout time.Duration `yaml:"write_timeout"`
 		IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+		// TransferTimeout replaces the read and write timeouts on routes that
+		// stream document contents.
+		TransferTimeout time.Duration `yaml:"transfer_timeout"`
+		MaxUploadSize   int64         `yaml:"max_upload_size"`
+
+		// TrustedProxies may set X-Forwarded-For; the client IP used to
+		// throttle logins is taken from it only behind these.
+		TrustedProxies []string `yaml:"trusted_proxies"`
 	} `yaml:"server"`
 
 	Postgres struct {
@@ -37,9 +46,78 @@ type Config struct {
 
 	JWT struct {
 		Secret     string
+		Issuer     string        `yaml:"issuer"`
+		Audience   string        `yaml:"audience"`
 		Expiration time.Duration `yaml:"expiration"`
+
+		// Leeway is the clock skew tolerated when checking token times.
+		Leeway time.Duration `yaml:"leeway"`
+
+		// RefreshExpiration is how long a refresh token can be exchanged
+		// for a new access token.
+		RefreshExpiration time.Duration `yaml:"refresh_expiration"`
+
+		// Algorithm is RS256 or EdDSA to sign with rotating keys stored in
+		// the database, or HS256 to sign with Secret.
+		Algorithm          string        `yaml:"algorithm"`
+		KeyRotation        time.Duration `yaml:"key_rotation"`
+		KeyRefreshInterval time.Duration `yaml:"key_refresh_interval"`
 	} `yaml:"jwt"`
 
+	// OIDC enables login through an identity provider when Issuer is set.
+	OIDC struct {
+		Issuer       string `yaml:"issuer"`
+		ClientID     string `yaml:"client_id"`
+		ClientSecret string
+		RedirectURL  string   `yaml:"redirect_url"`
+		Scopes       []string `yaml:"scopes"`
+
+		// LoginClaim is the ID token claim new users get their login from.
+		LoginClaim string        `yaml:"login_claim"`
+		StateTTL   time.Duration `yaml:"state_ttl"`
+	} `yaml:"oidc"`
+
+	// LoginThrottle slows down password guessing. Failures past the free
+	// attempts double the delay before the next try, up to MaxDelay, and a
+	// login is locked out for LockoutDuration after LockoutThreshold failures.
+	LoginThrottle struct {
+		FreeAttempts     int64         `yaml:"free_attempts"`
+		IPFreeAttempts   int64         `yaml:"ip_free_attempts"`
+		BaseDelay        time.Duration `yaml:"base_delay"`
+		MaxDelay         time.Duration `yaml:"max_delay"`
+		LockoutThreshold int64         `yaml:"lockout_threshold"`
+		LockoutDuration  time.Duration `yaml:"lockout_duration"`
+		Window           time.Duration `yaml:"window"`
+	} `yaml:"login_throttle"`
+
+	// PasswordPolicy applies to new passwords. BreachedList is a file with
+	// one password per line that are refused, such as the most common ones.
+	PasswordPolicy struct {
+		MinLength               int    `yaml:"min_length"`
+		RequireCharacterClasses bool   `yaml:"require_character_classes"`
+		BreachedList            string `yaml:"breached_list"`
+	} `yaml:"password_policy"`
+
+	Storage struct {
+		Driver string `yaml:"driver"`
+		Local  struct {
+			Path string `yaml:"path"`
+		} `yaml:"local"`
+		S3 struct {
+			Endpoint  string `yaml:"endpoint"`
+			Region    string `yaml:"region"`
+			Bucket    string `yaml:"bucket"`
+			UseSSL    bool   `yaml:"use_ssl"`
+			AccessKey string
+			SecretKey string
+		} `yaml:"s3"`
+	} `yaml:"storage"`
+
+	Uploads struct {
+		TTL             time.Duration `yaml:"ttl"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval"`
+	} `yaml:"uploads"`
+
 	Migrations struct {
 		Path string `yaml:"path"`
 	} `yaml:"migrations"`
@@ -74,6 +152,19 @@ func Load() (*Config, error) {
 	cfg.JWT.Secret, err = getEnv("JWT_SECRET")
 	cfg.AdminToken, err = getEnv("ADMIN_TOKEN")
 
+	overrideEnv(&cfg.JWT.Algorithm, "JWT_ALGORITHM")
+	overrideEnv(&cfg.OIDC.Issuer, "OIDC_ISSUER")
+	overrideEnv(&cfg.OIDC.ClientID, "OIDC_CLIENT_ID")
+	overrideEnv(&cfg.OIDC.ClientSecret, "OIDC_CLIENT_SECRET")
+	overrideEnv(&cfg.OIDC.RedirectURL, "OIDC_REDIRECT_URL")
+	overrideEnv(&cfg.PasswordPolicy.BreachedList, "BREACHED_PASSWORDS")
+	overrideEnv(&cfg.Storage.Driver, "STORAGE_DRIVER")
+	overrideEnv(&cfg.Storage.Local.Path, "STORAGE_PATH")
+	overrideEnv(&cfg.Storage.S3.Endpoint, "S3_ENDPOINT")
+	overrideEnv(&cfg.Storage.S3.Bucket, "S3_BUCKET")
+	overrideEnv(&cfg.Storage.S3.AccessKey, "S3_ACCESS_KEY")
+	overrideEnv(&cfg.Storage.S3.SecretKey, "S3_SECRET_KEY")
+
 	return cfg, err
 }
 
@@ -83,3 +174,9 @@ func getEnv(key string) (string, error) {
 	}
 	return "", fmt.Errorf("could not get %s from environment", key)
 }
+
+func overrideEnv(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// APIKey lets scripts authenticate as a user without the password. Only a
+// hash of the key is stored; Prefix is its first characters, shown so the
+// owner can tell keys apart.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	Created   time.Time  `json:"created"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+	UserID    string     `json:"-"`
+	Login     string     `json:"-"`
+	UserAdmin bool       `json:"-"`
+	Hash      string     `json:"-"`
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// Audited actions.
+const (
+	AuditUpload      = "upload"
+	AuditRead        = "read"
+	AuditDelete      = "delete"
+	AuditShare       = "share"
+	AuditLogin       = "login"
+	AuditFailedLogin = "failed-login"
+	AuditAdmin       = "admin"
+	// AuditAccount covers changes users make to their own credentials:
+	// passwords, API keys and the second factor.
+	AuditAccount = "account"
+)
+
+// Outcomes of an audited action.
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"
+	AuditFailed  = "failed"
+)
+
+// AuditEvent records who did what to which document, from where and with
+// what outcome. Detail says more about the action, such as the version read
+// or the share link used.
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	ActorID    string    `json:"actor_id,omitempty"`
+	Actor      string    `json:"actor,omitempty"`
+	Action     string    `json:"action"`
+	DocumentID string    `json:"document_id,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Outcome    string    `json:"outcome"`
+}
+
+// AuditFilter selects audit events. Actor matches either the ID or the login
+// of the actor. Zero fields match everything; Before, if set, keeps the events
+// older than the one with that ID.
+type AuditFilter struct {
+	Actor      string
+	DocumentID string
+	From       time.Time
+	To         time.Time
+	Before     int64
+	Limit      int
+}
+
+// Client describes where a request comes from and, once it is authenticated,
+// who makes it.
+type Client struct {
+	IP        string
+	UserAgent string
+	UserID    string
+	Login     string
+}
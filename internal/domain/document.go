@@ -3,14 +3,29 @@ package domain
 import "time"
 
 type Document struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Mime    string    `json:"mime"`
-	File    bool      `json:"file"`
-	Public  bool      `json:"public"`
-	Created time.Time `json:"created"`
-	Grant   []string  `json:"grant"`
-	Owner   string    `json:"-"`
-	Data    []byte    `json:"-"`
-	JSON    string    `json:"-"`
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Mime        string    `json:"mime"`
+	File        bool      `json:"file"`
+	Public      bool      `json:"public"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+	Version     int       `json:"version"`
+	Grant       []Grant   `json:"grant"`
+	Size        int64     `json:"size"`
+	FolderID    string    `json:"folder_id,omitempty"`
+	Owner       string    `json:"-"`
+	StorageKey  string    `json:"-"`
+	Checksum    string    `json:"-"`
+	JSON        string    `json:"-"`
+	ContentText string    `json:"-"`
+}
+
+// SearchResult is a document matching a full-text query together with its
+// rank and a highlighted snippet of the matching text. The snippet is HTML
+// with the matches in <b> tags and everything else escaped.
+type SearchResult struct {
+	Document
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
 }
@@ -1,9 +1,20 @@
 package domain
 
 type DocumentMeta struct {
-	Name   string   `json:"name"`
-	File   bool     `json:"file"`
-	Public bool     `json:"public"`
-	Mime   string   `json:"mime"`
-	Grant  []string `json:"grant"`
+	Name     string  `json:"name"`
+	File     bool    `json:"file"`
+	Public   bool    `json:"public"`
+	Mime     string  `json:"mime"`
+	Grant    []Grant `json:"grant"`
+	FolderID string  `json:"folder_id"`
+}
+
+// DocumentMetaPatch is a partial DocumentMeta: only the fields that are set
+// are changed. An empty FolderID moves the document to the root.
+type DocumentMetaPatch struct {
+	Name     *string  `json:"name"`
+	Public   *bool    `json:"public"`
+	Mime     *string  `json:"mime"`
+	Grant    *[]Grant `json:"grant"`
+	FolderID *string  `json:"folder_id"`
 }
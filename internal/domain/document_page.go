@@ -0,0 +1,22 @@
+package domain
+
+import "github.com/mibrgmv/document-service/pkg/filter"
+
+// ListOptions filters, sorts and pages a document list. Sort is one of name,
+// created, size or mime; Cursor is the NextCursor of the previous page.
+type ListOptions struct {
+	Filter filter.Expr
+	Limit  int
+	Sort   string
+	Desc   bool
+	Cursor string
+	Total  bool
+}
+
+// DocumentPage is one page of a document list. NextCursor is empty on the
+// last page; Total is only set when it was asked for.
+type DocumentPage struct {
+	Docs       []Document `json:"docs"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Total      *int       `json:"total,omitempty"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/mibrgmv/document-service/pkg/diff"
+)
+
+// DocumentVersion is an immutable snapshot of a document's content.
+type DocumentVersion struct {
+	DocumentID  string    `json:"-"`
+	Version     int       `json:"version"`
+	Mime        string    `json:"mime"`
+	Size        int64     `json:"size"`
+	Author      string    `json:"author"`
+	Created     time.Time `json:"created"`
+	StorageKey  string    `json:"-"`
+	Checksum    string    `json:"-"`
+	JSON        string    `json:"-"`
+	ContentText string    `json:"-"`
+}
+
+type VersionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Unified string        `json:"unified"`
+	Changes []diff.Change `json:"changes,omitempty"`
+}
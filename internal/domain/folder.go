@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Folder groups a user's documents. Folders without a parent are at the root.
+type Folder struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	ParentID string    `json:"parent_id,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+	Owner    string    `json:"-"`
+}
+
+// FolderPatch renames or moves a folder. An empty ParentID moves it to the
+// root.
+type FolderPatch struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+// FolderContents lists the direct children of a folder, or of the root when
+// Folder is nil.
+type FolderContents struct {
+	Folder    *Folder    `json:"folder,omitempty"`
+	Folders   []Folder   `json:"folders"`
+	Documents []Document `json:"documents"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import "encoding/json"
+
+// Roles a document can be shared with, from least to most access. Viewers and
+// commenters read, editors also upload new versions, co-owners also share and
+// delete.
+const (
+	RoleViewer    = "viewer"
+	RoleCommenter = "commenter"
+	RoleEditor    = "editor"
+	RoleCoOwner   = "co-owner"
+
+	// RoleOwner is the role of the document's owner; it cannot be granted.
+	RoleOwner = "owner"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer:    1,
+	RoleCommenter: 2,
+	RoleEditor:    3,
+	RoleCoOwner:   4,
+	RoleOwner:     5,
+}
+
+// Grant shares a document with a user or a group in a role. Grantee is the
+// login of the user, or GroupPrefix and the name of the group; access checks
+// go by UserID or GroupID.
+type Grant struct {
+	Grantee string `json:"grantee"`
+	Role    string `json:"role"`
+	UserID  string `json:"-"`
+	GroupID string `json:"-"`
+}
+
+// UnmarshalJSON also accepts a plain string, as grant lists used to be lists
+// of logins, and reads it as a viewer grant.
+func (g *Grant) UnmarshalJSON(data []byte) error {
+	var grantee string
+	if err := json.Unmarshal(data, &grantee); err == nil {
+		*g = Grant{Grantee: grantee, Role: RoleViewer}
+		return nil
+	}
+
+	type grant Grant
+	return json.Unmarshal(data, (*grant)(g))
+}
+
+// ValidRole reports whether the role can be granted.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok && role != RoleOwner
+}
+
+// RoleAtLeast reports whether role gives at least the access of min.
+func RoleAtLeast(role, min string) bool {
+	return role != "" && roleRanks[role] >= roleRanks[min]
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// GroupPrefix marks a grantee as a group rather than a login, as in
+// "group:finance".
+const GroupPrefix = "group:"
+
+// Group is a named set of users that documents can be shared with. The owner
+// manages its members and is always one of them.
+type Group struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	Created time.Time     `json:"created"`
+	Members []GroupMember `json:"members,omitempty"`
+	Owner   string        `json:"-"`
+}
+
+type GroupMember struct {
+	ID    string `json:"-"`
+	Login string `json:"login"`
+}
+
+// GroupName returns the name of the group a grantee refers to and whether it
+// refers to a group at all.
+func GroupName(grantee string) (string, bool) {
+	return strings.CutPrefix(grantee, GroupPrefix)
+}
@@ -0,0 +1,30 @@
+package domain
+
+// MFAChallenge is returned by login instead of a token pair when the user has
+// two-factor authentication enabled. The token is exchanged together with a
+// code for the token pair.
+type MFAChallenge struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// AuthResult is the outcome of a password login: a token pair, or a challenge
+// if a second factor is required.
+type AuthResult struct {
+	*TokenPair
+	MFA *MFAChallenge `json:"mfa,omitempty"`
+}
+
+// PendingLogin is what is kept of a login between the password and the
+// second factor.
+type PendingLogin struct {
+	UserID string   `json:"user_id"`
+	Login  string   `json:"login"`
+	Scopes []string `json:"scopes"`
+}
+
+// TOTPEnrollment is shown to the user once to set up an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
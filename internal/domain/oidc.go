@@ -0,0 +1,9 @@
+package domain
+
+// OIDCState is what is kept between sending a user to the identity provider
+// and the provider redirecting them back, keyed by the state parameter.
+type OIDCState struct {
+	Verifier string   `json:"verifier"`
+	Nonce    string   `json:"nonce"`
+	Scopes   []string `json:"scopes"`
+}
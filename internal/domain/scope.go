@@ -0,0 +1,24 @@
+package domain
+
+// Scopes limit what a credential may do on behalf of its user. Only admins
+// can be granted ScopeAdmin.
+const (
+	ScopeDocsRead   = "docs:read"
+	ScopeDocsWrite  = "docs:write"
+	ScopeDocsDelete = "docs:delete"
+	ScopeAdmin      = "admin"
+)
+
+var Scopes = []string{ScopeDocsRead, ScopeDocsWrite, ScopeDocsDelete, ScopeAdmin}
+
+// DefaultScopes are granted when a login asks for none.
+var DefaultScopes = []string{ScopeDocsRead, ScopeDocsWrite, ScopeDocsDelete}
+
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
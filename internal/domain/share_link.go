@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// ShareLink gives anyone who has its token access to a document without
+// logging in. Only a hash of the token is stored; Prefix is its first
+// characters, shown so the owner can tell links apart.
+type ShareLink struct {
+	ID           string     `json:"id"`
+	DocumentID   string     `json:"document_id"`
+	Prefix       string     `json:"prefix"`
+	Protected    bool       `json:"protected"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	Created      time.Time  `json:"created"`
+	Expires      *time.Time `json:"expires,omitempty"`
+	Revoked      *time.Time `json:"revoked,omitempty"`
+	CreatedBy    string     `json:"-"`
+	Hash         string     `json:"-"`
+	Password     string     `json:"-"`
+}
+
+// Outcomes of an attempt to open a share link.
+const (
+	LinkServed        = "served"
+	LinkNoPassword    = "no_password"
+	LinkWrongPassword = "wrong_password"
+	LinkThrottled     = "throttled"
+	LinkRevoked       = "revoked"
+	LinkExpired       = "expired"
+	LinkUsedUp        = "used_up"
+	LinkFailed        = "failed"
+)
+
+// LinkAccess records an attempt to open a share link.
+type LinkAccess struct {
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	LinkID    string    `json:"-"`
+}
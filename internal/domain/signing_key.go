@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// SigningKey is a stored token signing key. A key retires once a newer one
+// replaces it and is kept until then so the tokens it signed stay valid.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey string
+	Created    time.Time
+	Retires    *time.Time
+}
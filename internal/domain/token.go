@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// TokenPair is what a client receives on login and on refresh: a short-lived
+// access token and the refresh token to exchange for the next pair.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// RefreshToken is the stored record of an issued refresh token. Only the
+// hash of the token is kept. Every refresh adds a token to the family of the
+// login it descends from and marks the previous one used.
+type RefreshToken struct {
+	Hash          string
+	FamilyID      string
+	UserID        string
+	Login         string
+	AccessJTI     string
+	AccessExpires time.Time
+	Scopes        []string
+	Used          bool
+	Revoked       bool
+	Created       time.Time
+	Expires       time.Time
+}
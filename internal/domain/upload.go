@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+const (
+	UploadInProgress = "uploading"
+	UploadFinished   = "finished"
+)
+
+// Upload is a resumable upload in progress. Every received chunk is kept in
+// the blob store under its own key until the upload completes.
+type Upload struct {
+	ID      string
+	Owner   string
+	Meta    DocumentMeta
+	Length  int64
+	Offset  int64
+	Chunks  []string
+	State   string
+	Created time.Time
+	Expires time.Time
+}
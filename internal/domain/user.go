@@ -6,5 +6,23 @@ type User struct {
 	ID       string    `json:"id"`
 	Login    string    `json:"login"`
 	Password string    `json:"-"`
+	Admin    bool      `json:"admin"`
 	Created  time.Time `json:"created"`
+
+	// OIDCIssuer and OIDCSubject identify users provisioned on their first
+	// sign-in through an identity provider. Such users have no password.
+	OIDCIssuer  string `json:"-"`
+	OIDCSubject string `json:"-"`
+
+	// TOTPSecret is set on enrollment; the second factor is required at
+	// login once TOTPEnabled is set by confirming a code.
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `json:"mfa"`
+}
+
+// PasswordReset is issued by an admin and lets the user set a new password
+// once.
+type PasswordReset struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+type createdAPIKey struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create an API key for scripts and CI jobs. Send it in the X-API-Key header or as
+// @Description "Authorization: ApiKey KEY". The key is returned only once and cannot have scopes
+// @Description the current token does not have
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "Name, scopes (docs:read, docs:write, docs:delete, admin) and optional expiry"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+
+	var req CreateAPIKeyRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	key, secret, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), userID, req.Name, req.Scopes,
+		c.GetStringSlice("scopes"), req.Expires)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: createdAPIKey{APIKey: key, Key: secret},
+	})
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of the current user with their last use
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+
+	keys, err := h.apiKeyService.GetAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: gin.H{"keys": keys},
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key of the current user
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+	id := c.Param("id")
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), id, userID); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{id: true},
+	})
+}
+
+// sessionOnly refuses requests authenticated with an API key, so a leaked
+// key cannot be used to mint more keys.
+func sessionOnly(c *gin.Context) bool {
+	if _, ok := c.Get("api_key_id"); ok {
+		c.JSON(http.StatusForbidden, Response{
+			Error: &Error{Code: 403, Text: "API keys cannot manage API keys"},
+		})
+		return false
+	}
+	return true
+}
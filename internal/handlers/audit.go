@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+var auditCSVHeader = []string{"id", "time", "actor_id", "actor", "action", "document_id", "detail", "ip", "user_agent", "outcome"}
+
+// GetEvents godoc
+// @Summary Get audit events
+// @Description Query the audit log of uploads, reads, deletes, shares, logins, account changes
+// @Description (passwords, API keys, TOTP) and administrative actions, newest first. Pass the id of the last event as before to get the following page.
+// @Description With format csv or jsonl every matching event is exported as a file, without paging
+// @Tags audit
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json,text/csv,application/x-ndjson
+// @Param actor query string false "Actor user ID or login"
+// @Param document_id query string false "Document ID"
+// @Param from query string false "Events at or after this time (RFC 3339)"
+// @Param to query string false "Events before this time (RFC 3339)"
+// @Param before query integer false "Events older than the one with this id"
+// @Param limit query integer false "Page size (default 100, at most 1000)"
+// @Param format query string false "json, csv or jsonl (default json)"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /audit [get]
+func (h *AuditHandler) GetEvents(c *gin.Context) {
+	filter := domain.AuditFilter{
+		Actor:      c.Query("actor"),
+		DocumentID: c.Query("document_id"),
+	}
+
+	var err error
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid from"},
+		})
+		return
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid to"},
+		})
+		return
+	}
+	if value := c.Query("before"); value != "" {
+		if filter.Before, err = strconv.ParseInt(value, 10, 64); err != nil || filter.Before <= 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Error: &Error{Code: 400, Text: "invalid before"},
+			})
+			return
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit <= 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Error: &Error{Code: 400, Text: "invalid limit"},
+			})
+			return
+		}
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+	case "csv", "jsonl":
+		h.exportEvents(c, filter, format)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "format must be json, csv or jsonl"},
+		})
+		return
+	}
+
+	events, err := h.auditService.GetEvents(c.Request.Context(), filter)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: events,
+	})
+}
+
+// exportEvents streams the events as they are read. An error after the first
+// event has been written can only cut the file short.
+func (h *AuditHandler) exportEvents(c *gin.Context, filter domain.AuditFilter, format string) {
+	started := false
+	csvWriter := csv.NewWriter(c.Writer)
+	jsonEncoder := json.NewEncoder(c.Writer)
+
+	start := func() error {
+		started = true
+		contentType := "application/x-ndjson"
+		if format == "csv" {
+			contentType = "text/csv; charset=utf-8"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="audit.`+format+`"`)
+		c.Status(http.StatusOK)
+
+		if format == "csv" {
+			return csvWriter.Write(auditCSVHeader)
+		}
+		return nil
+	}
+
+	write := func(event *domain.AuditEvent) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if format == "jsonl" {
+			return jsonEncoder.Encode(event)
+		}
+		return csvWriter.Write([]string{
+			strconv.FormatInt(event.ID, 10), event.Time.UTC().Format(time.RFC3339), csvText(event.ActorID),
+			csvText(event.Actor), event.Action, csvText(event.DocumentID), csvText(event.Detail), event.IP,
+			csvText(event.UserAgent), event.Outcome,
+		})
+	}
+
+	err := h.auditService.ExportEvents(c.Request.Context(), filter, write)
+	if err != nil && !started {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+	if !started {
+		start()
+	}
+	csvWriter.Flush()
+}
+
+// csvText keeps spreadsheets from evaluating text that clients control, such
+// as a login or a user agent starting with =, as a formula.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
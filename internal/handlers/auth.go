@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mibrgmv/document-service/internal/service"
@@ -35,7 +39,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.Register(c.Request.Context(), req.Token, req.Login, req.Pswd); err != nil {
+	if err := h.authService.Register(c.Request.Context(), req.Token, req.Login, req.Pswd, req.Admin); err != nil {
 		c.JSON(http.StatusBadRequest, Response{
 			Error: &Error{Code: 400, Text: err.Error()},
 		})
@@ -49,7 +53,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Auth godoc
 // @Summary User authentication
-// @Description Authenticate user and get JWT token
+// @Description Authenticate user and get a short-lived JWT access token with a refresh token.
+// @Description The tokens carry the requested scopes; the admin scope is granted only to admins.
+// @Description Users with two-factor authentication get an "mfa" challenge instead, to pass to /auth/mfa.
+// @Description Repeated failures slow down further attempts for the login and the client IP; 429 responses
+// @Description carry a Retry-After header
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -57,6 +65,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} Response
 // @Failure 400 {object} Response
 // @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 429 {object} Response
 // @Router /auth [post]
 func (h *AuthHandler) Auth(c *gin.Context) {
 	var req AuthRequest
@@ -67,33 +77,147 @@ func (h *AuthHandler) Auth(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.Authenticate(c.Request.Context(), req.Login, req.Pswd)
+	tokens, err := h.authService.Authenticate(c.Request.Context(), c.ClientIP(), req.Login, req.Pswd,
+		strings.Fields(req.Scope))
 	if err != nil {
+		setRetryAfter(c, err)
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: tokens,
+	})
+}
+
+// OIDCLogin godoc
+// @Summary Login through identity provider
+// @Description Redirect to the OpenID Connect provider to sign in (authorization code flow with PKCE).
+// @Description The provider redirects back to /auth/oidc/callback. Users are created on their first sign-in
+// @Tags auth
+// @Param scope query string false "Space-separated scopes, as for /auth"
+// @Success 302
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/oidc [get]
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	url, err := h.authService.OIDCLoginURL(c.Request.Context(), strings.Fields(c.Query("scope")))
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, url)
+}
+
+// OIDCCallback godoc
+// @Summary Identity provider callback
+// @Description Finish the sign-in started with /auth/oidc and get tokens as from /auth
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	// The provider reports a denied or failed sign-in instead of a code.
+	if providerErr := c.Query("error"); providerErr != "" {
+		text := providerErr
+		if description := c.Query("error_description"); description != "" {
+			text += ": " + description
+		}
 		c.JSON(http.StatusUnauthorized, Response{
-			Error: &Error{Code: 401, Text: err.Error()},
+			Error: &Error{Code: 401, Text: text},
+		})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "code and state are required"},
+		})
+		return
+	}
+
+	tokens, err := h.authService.OIDCCallback(c.Request.Context(), code, state)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: tokens,
+	})
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token
+// @Description can be used once; reusing one revokes every token descending from the same login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.BindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, Response{
-		Response: gin.H{"token": token},
+		Response: tokens,
 	})
 }
 
 // Logout godoc
 // @Summary User logout
-// @Description Logout user and invalidate token
+// @Description Logout user and revoke the token until it expires
 // @Tags auth
 // @Produce json
 // @Param token path string true "JWT Token"
 // @Success 200 {object} Response
+// @Failure 401 {object} Response
 // @Failure 500 {object} Response
 // @Router /auth/{token} [delete]
 func (h *AuthHandler) Logout(c *gin.Context) {
 	token := c.Param("token")
 	if err := h.authService.Logout(c.Request.Context(), token); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Error: &Error{Code: 500, Text: "internal error"},
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
@@ -102,3 +226,63 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		Response: gin.H{token: true},
 	})
 }
+
+// LogoutAll godoc
+// @Summary Logout all sessions
+// @Description Revoke every token issued to the current user, including the one used for this request
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/sessions [delete]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	if err := h.authService.LogoutAll(c.Request.Context(), userID); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{"sessions": true},
+	})
+}
+
+// UnlockLogin godoc
+// @Summary Unlock login
+// @Description Lift the lockout of a login after failed attempts and reset its failure count. Requires the admin scope
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Param login path string true "User login"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/{login}/lockout [delete]
+func (h *AuthHandler) UnlockLogin(c *gin.Context) {
+	login := c.Param("login")
+	if err := h.authService.UnlockLogin(c.Request.Context(), login); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{login: true},
+	})
+}
+
+// setRetryAfter tells a client that was throttled when to try again.
+func setRetryAfter(c *gin.Context, err error) {
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+var errInvalidRange = errors.New("invalid range")
+
+func documentETag(doc *domain.Document) string {
+	if doc.Checksum == "" {
+		return ""
+	}
+	return `"` + doc.Checksum + `"`
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since for responses that
+// are not served through http.ServeContent. If-None-Match takes precedence, as
+// required by RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etag != "" && etagMatches(header, etag)
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// fullDownload reports whether serving the document sends all of it or a part
+// that includes its first byte. Files are judged the way http.ServeContent
+// answers the request: answers without content (304, 412, 416) and ranges
+// that resume or seek past the start do not count, while a Range ignored
+// because of If-Range or asking for more than the file does.
+func fullDownload(r *http.Request, doc *domain.Document) bool {
+	etag := documentETag(doc)
+	if notModified(r, etag, doc.Updated) {
+		return false
+	}
+	if !doc.File {
+		return true
+	}
+
+	if preconditionFailed(r, etag, doc.Updated) {
+		return false
+	}
+
+	header := r.Header.Get("Range")
+	if header == "" || !ifRangeMatches(r, etag, doc.Updated) {
+		return true
+	}
+
+	ranges, err := parseRanges(header, doc.Size)
+	if err != nil {
+		return false
+	}
+	var total int64
+	for _, rng := range ranges {
+		if rng.start == 0 {
+			return true
+		}
+		total += rng.length
+	}
+	// ServeContent sends the whole file rather than more than its size.
+	return total > doc.Size
+}
+
+// preconditionFailed evaluates If-Match and If-Unmodified-Since like
+// http.ServeContent.
+func preconditionFailed(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-Match"); header != "" {
+		return !strongETagMatches(header, etag)
+	}
+
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" && !modified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && modified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// ifRangeMatches reports whether the Range header applies: ServeContent
+// ignores it, and sends the whole file, unless If-Range is absent or names
+// the current ETag or modification time.
+func ifRangeMatches(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return true
+	}
+
+	header := strings.TrimSpace(r.Header.Get("If-Range"))
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, `"`) || strings.HasPrefix(header, "W/") {
+		return etag != "" && !strings.HasPrefix(header, "W/") && header == etag
+	}
+	if modified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(header)
+	return err == nil && modified.Truncate(time.Second).Equal(t)
+}
+
+type byteRange struct {
+	start, length int64
+}
+
+// parseRanges parses a Range header for content of the given size as
+// http.ServeContent does. An error means ServeContent answers 416.
+func parseRanges(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, errInvalidRange
+	}
+
+	var ranges []byteRange
+	noOverlap := false
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var rng byteRange
+		if first == "" {
+			// A suffix range: the last bytes of the content.
+			if last == "" || last[0] == '-' {
+				return nil, errInvalidRange
+			}
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			n = min(n, size)
+			rng.start = size - n
+			rng.length = n
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errInvalidRange
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			rng.start = start
+			if last == "" {
+				rng.length = size - start
+			} else {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || start > end {
+					return nil, errInvalidRange
+				}
+				end = min(end, size-1)
+				rng.length = end - start + 1
+			}
+		}
+		ranges = append(ranges, rng)
+	}
+
+	if noOverlap && len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
+
+func strongETagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate != "" && candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullDownload(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	content := strings.Repeat("x", 100)
+	doc := &domain.Document{File: true, Size: int64(len(content)), Checksum: "abc", Updated: updated}
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"whole file", "GET", nil, true},
+		{"not modified", "GET", map[string]string{"If-None-Match": `"abc"`}, false},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": updated.Add(-time.Hour).Format(http.TimeFormat)}, true},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, false},
+		{"precondition failed", "GET", map[string]string{"If-Match": `"other"`}, false},
+		{"unmodified since failed", "GET", map[string]string{"If-Unmodified-Since": updated.Add(-time.Hour).Format(http.TimeFormat)}, false},
+		{"first bytes", "GET", map[string]string{"Range": "bytes=0-9"}, true},
+		{"resume", "GET", map[string]string{"Range": "bytes=5-"}, false},
+		{"last bytes", "GET", map[string]string{"Range": "bytes=-10"}, false},
+		{"suffix longer than the file", "GET", map[string]string{"Range": "bytes=-999999999"}, true},
+		{"multi-range with the first byte", "GET", map[string]string{"Range": "bytes=1-,0-0"}, true},
+		{"multi-range without the first byte", "GET", map[string]string{"Range": "bytes=10-19,30-39"}, false},
+		{"ranges larger than the file", "GET", map[string]string{"Range": "bytes=1-60,40-99"}, true},
+		{"range past the end", "GET", map[string]string{"Range": "bytes=200-"}, false},
+		{"unknown unit", "GET", map[string]string{"Range": "items=0-5"}, false},
+		{"malformed range", "GET", map[string]string{"Range": "bytes=9-5"}, false},
+		{"if-range with another etag", "GET", map[string]string{"Range": "bytes=5-", "If-Range": `"other"`}, true},
+		{"if-range with the etag", "GET", map[string]string{"Range": "bytes=5-", "If-Range": `"abc"`}, false},
+		{"if-range with another date", "GET", map[string]string{"Range": "bytes=5-", "If-Range": updated.Add(-time.Hour).Format(http.TimeFormat)}, true},
+		{"if-range with the date", "GET", map[string]string{"Range": "bytes=5-", "If-Range": updated.Format(http.TimeFormat)}, false},
+		{"post with range", "POST", map[string]string{"Range": "bytes=5-", "If-Range": `"other"`}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/s/token", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.want, fullDownload(r, doc))
+
+			// The answer must agree with what ServeContent actually sends.
+			w := httptest.NewRecorder()
+			w.Header().Set("ETag", documentETag(doc))
+			http.ServeContent(w, r, "file", doc.Updated, strings.NewReader(content))
+			served := w.Code == http.StatusOK ||
+				w.Code == http.StatusPartialContent && (strings.HasPrefix(w.Header().Get("Content-Range"), "bytes 0-") ||
+					strings.Contains(w.Body.String(), "Content-Range: bytes 0-"))
+			assert.Equal(t, served, tt.want, "ServeContent answered %d", w.Code)
+		})
+	}
+}
+
+func TestFullDownload_JSONIgnoresRange(t *testing.T) {
+	doc := &domain.Document{Checksum: "abc", Updated: time.Now()}
+
+	r := httptest.NewRequest("GET", "/s/token", nil)
+	r.Header.Set("Range", "bytes=5-")
+	assert.True(t, fullDownload(r, doc))
+
+	r.Header.Set("If-None-Match", `"abc"`)
+	assert.False(t, fullDownload(r, doc))
+}
@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/pkg/filter"
 )
 
 type DocumentHandler struct {
@@ -21,9 +26,11 @@ func NewDocumentHandler(docService service.DocumentService) *DocumentHandler {
 
 // UploadDocument godoc
 // @Summary Upload document
-// @Description Upload a new document (file or JSON)
+// @Description Upload a new document (file or JSON). The file is streamed to storage,
+// @Description so the meta part must precede the file part in the form.
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept multipart/form-data
 // @Produce json
 // @Param meta formData string true "Document metadata JSON"
@@ -32,47 +39,87 @@ func NewDocumentHandler(docService service.DocumentService) *DocumentHandler {
 // @Success 200 {object} Response
 // @Failure 400 {object} Response
 // @Failure 401 {object} Response
+// @Failure 413 {object} Response
 // @Failure 500 {object} Response
 // @Router /docs [post]
 func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	userID := c.MustGet("user_id").(string)
 
-	metaStr := c.PostForm("meta")
-	var meta DocumentMeta
-	if err := json.Unmarshal([]byte(metaStr), &meta); err != nil {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, Response{
-			Error: &Error{Code: 400, Text: "invalid meta data"},
+			Error: &Error{Code: 400, Text: "invalid multipart form"},
 		})
 		return
 	}
 
-	file, err := c.FormFile("file")
-	var fileData []byte
-	if err == nil {
-		file, err := file.Open()
+	var meta *DocumentMeta
+	var jsonData string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			c.JSON(http.StatusBadRequest, Response{
-				Error: &Error{Code: 400, Text: "invalid file"},
+			status := errorStatus(err)
+			if status == http.StatusInternalServerError {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, Response{
+				Error: &Error{Code: status, Text: "invalid multipart form"},
 			})
 			return
 		}
-		defer file.Close()
 
-		fileData, err = io.ReadAll(file)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, Response{
-				Error: &Error{Code: 400, Text: "invalid file"},
-			})
-			return
+		switch part.FormName() {
+		case "meta":
+			meta = &DocumentMeta{}
+			if err := json.NewDecoder(part).Decode(meta); err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Error: &Error{Code: 400, Text: "invalid meta data"},
+				})
+				return
+			}
+		case "json":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				status := errorStatus(err)
+				c.JSON(status, Response{
+					Error: &Error{Code: status, Text: err.Error()},
+				})
+				return
+			}
+			jsonData = string(data)
+		case "file":
+			if meta == nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Error: &Error{Code: 400, Text: "meta must be sent before file"},
+				})
+				return
+			}
+			if meta.File {
+				h.uploadDocument(c, meta, part, "", userID)
+				return
+			}
 		}
 	}
 
-	jsonData := c.PostForm("json")
+	if meta == nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid meta data"},
+		})
+		return
+	}
+
+	h.uploadDocument(c, meta, http.NoBody, jsonData, userID)
+}
 
-	doc, err := h.docService.UploadDocument(c.Request.Context(), meta.ToDomain(), fileData, jsonData, userID)
+func (h *DocumentHandler) uploadDocument(c *gin.Context, meta *DocumentMeta, content io.Reader, jsonData, userID string) {
+	doc, err := h.docService.UploadDocument(c.Request.Context(), meta.ToDomain(), content, jsonData, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Error: &Error{Code: 500, Text: err.Error()},
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
@@ -91,17 +138,28 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 
 // GetDocuments godoc
 // @Summary Get documents list
-// @Description Get list of documents with optional filtering
+// @Description Get a page of documents with optional filtering and sorting. Pass next_cursor
+// @Description from the response as cursor to get the following page, with the same sort and order.
+// @Description The filter combines comparisons of name, mime, file, public, created, updated, size
+// @Description and version with and, or, not and parentheses, e.g.
+// @Description mime ~ "image/*" and created > 2026-01-01 and not public = true
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
-// @Param user_id query string false "User ID to filter (default: current user)"
-// @Param key query string false "Filter key (name, mime, public)"
-// @Param value query string false "Filter value"
-// @Param limit query integer false "Limit number of documents"
+// @Param user_id query string false "List the documents of another user, requires the admin scope (default: current user)"
+// @Param filter query string false "Filter expression"
+// @Param key query string false "Filter key (name, mime, public), deprecated in favor of filter"
+// @Param value query string false "Filter value, deprecated in favor of filter"
+// @Param limit query integer false "Page size (default 100, at most 1000)"
+// @Param sort query string false "Sort by name, created, size or mime (default name)"
+// @Param order query string false "asc or desc (default asc)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query boolean false "Include the total number of documents"
 // @Success 200 {object} Response
 // @Failure 400 {object} Response
 // @Failure 401 {object} Response
+// @Failure 403 {object} Response
 // @Failure 500 {object} Response
 // @Router /docs [get]
 func (h *DocumentHandler) GetDocuments(c *gin.Context) {
@@ -110,21 +168,131 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 	if targetID == "" {
 		targetID = userID
 	}
+	// Listing what someone else can see bypasses the access checks, so it is
+	// left to admins.
+	if targetID != userID && !slices.Contains(c.GetStringSlice("scopes"), domain.ScopeAdmin) {
+		c.JSON(http.StatusForbidden, Response{
+			Error: &Error{Code: 403, Text: fmt.Sprintf("scope %q required to list documents of another user", domain.ScopeAdmin)},
+		})
+		return
+	}
+
+	opts := domain.ListOptions{
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+		Total:  c.Query("total") == "true",
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Error: &Error{Code: 400, Text: "invalid limit"},
+			})
+			return
+		}
+		opts.Limit = limit
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "order must be asc or desc"},
+		})
+		return
+	}
+
+	expr := c.Query("filter")
+	legacy, err := legacyFilter(c.Query("key"), c.Query("value"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: err.Error()},
+		})
+		return
+	}
+	if legacy != "" {
+		if expr != "" {
+			expr = "(" + legacy + ") and (" + expr + ")"
+		} else {
+			expr = legacy
+		}
+	}
+
+	page, err := h.docService.GetDocuments(c.Request.Context(), targetID, expr, opts)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: page,
+	})
+}
+
+// legacyFilter turns the key/value filter older clients send into a filter
+// expression. Only the keys these clients could filter by are accepted; the
+// others are left to filter.
+func legacyFilter(key, value string) (string, error) {
+	if key == "" || value == "" {
+		return "", nil
+	}
+	switch key {
+	case "name", "mime":
+		return key + " = " + filter.Quote(value), nil
+	case "public":
+		return fmt.Sprintf("public = %t", value == "true"), nil
+	default:
+		return "", errors.New("key must be name, mime or public")
+	}
+}
+
+// SearchDocuments godoc
+// @Summary Search documents
+// @Description Full-text search over the names, JSON content and text files visible to the user.
+// @Description Supports web search syntax: "quoted phrases", or, -excluded. Results are ranked
+// @Description and carry an HTML-escaped snippet with matches wrapped in <b></b>
+// @Tags documents
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query integer false "Number of results (default 20, at most 100)"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/search [get]
+func (h *DocumentHandler) SearchDocuments(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
 
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	filterKey := c.Query("key")
-	filterValue := c.Query("value")
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Error: &Error{Code: 400, Text: "invalid limit"},
+			})
+			return
+		}
+	}
 
-	docs, err := h.docService.GetDocuments(c.Request.Context(), targetID, filterKey, filterValue, limit)
+	results, err := h.docService.SearchDocuments(c.Request.Context(), userID, c.Query("q"), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Error: &Error{Code: 500, Text: err.Error()},
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, Response{
-		Data: gin.H{"docs": docs},
+		Data: gin.H{"docs": results},
 	})
 }
 
@@ -133,6 +301,7 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 // @Description HEAD request for documents list
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /docs [head]
 func (h *DocumentHandler) GetDocumentsHead(c *gin.Context) {
 	c.Status(http.StatusOK)
@@ -140,14 +309,24 @@ func (h *DocumentHandler) GetDocumentsHead(c *gin.Context) {
 
 // GetDocument godoc
 // @Summary Get document
-// @Description Get document by ID. Returns file or JSON based on document type
+// @Description Get document by ID. Returns file or JSON based on document type.
+// @Description Files support byte ranges (including multiple ranges) and conditional requests.
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json,application/octet-stream
 // @Param id path string true "Document ID"
+// @Param Range header string false "Byte ranges, e.g. bytes=0-1023"
+// @Param If-Range header string false "ETag or date the range request depends on"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Date of a cached copy"
 // @Success 200 {object} Response
+// @Success 206 {file} file
+// @Success 304
 // @Failure 403 {object} Response
 // @Failure 404 {object} Response
+// @Failure 412
+// @Failure 416
 // @Failure 500 {object} Response
 // @Router /docs/{id} [get]
 func (h *DocumentHandler) GetDocument(c *gin.Context) {
@@ -155,23 +334,43 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 	login := c.MustGet("login").(string)
 	id := c.Param("id")
 
-	doc, err := h.docService.GetDocument(c.Request.Context(), id, userID, login)
+	doc, content, err := h.docService.GetDocument(c.Request.Context(), id, userID, login)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "access denied" {
-			status = http.StatusForbidden
-		}
+		status := errorStatus(err)
 		c.JSON(status, Response{
 			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
 
+	if content != nil {
+		defer content.Close()
+	}
+
+	serveDocument(c, doc, content)
+}
+
+// serveDocument writes the document content. Files go through
+// http.ServeContent, which answers Range, If-Range and the conditional headers
+// based on the ETag and Last-Modified set here.
+func serveDocument(c *gin.Context, doc *domain.Document, content io.ReadSeeker) {
+	etag := documentETag(doc)
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+
 	if doc.File {
-		c.Header("Content-Type", doc.Mime)
-		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", doc.Name))
-		c.Header("Content-Length", strconv.Itoa(len(doc.Data)))
-		c.Data(http.StatusOK, doc.Mime, doc.Data)
+		if doc.Mime != "" {
+			c.Header("Content-Type", doc.Mime)
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": doc.Name}))
+		http.ServeContent(c.Writer, c.Request, doc.Name, doc.Updated, content)
+		return
+	}
+
+	c.Header("Last-Modified", doc.Updated.UTC().Format(http.TimeFormat))
+	if notModified(c.Request, etag, doc.Updated) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -182,34 +381,84 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 
 // GetDocumentHead godoc
 // @Summary HEAD document
-// @Description HEAD request for document
+// @Description HEAD request for document. Returns the same headers as GET without the body
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path string true "Document ID"
 // @Router /docs/{id} [head]
 func (h *DocumentHandler) GetDocumentHead(c *gin.Context) {
-	c.Status(http.StatusOK)
+	h.GetDocument(c)
+}
+
+// UpdateDocumentMeta godoc
+// @Summary Update document metadata
+// @Description Change the name, mime type, public flag or grants of a document.
+// @Description Fields that are omitted keep their current value. The owner and co-owners may change them;
+// @Description only the owner can move the document to another folder
+// @Tags documents
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param meta body domain.DocumentMetaPatch true "Fields to change"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id} [patch]
+func (h *DocumentHandler) UpdateDocumentMeta(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	var patch domain.DocumentMetaPatch
+	if err := c.BindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	doc, err := h.docService.UpdateDocumentMeta(c.Request.Context(), c.Param("id"), userID, login, &patch)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: doc,
+	})
 }
 
 // DeleteDocument godoc
 // @Summary Delete document
-// @Description Delete document by ID
+// @Description Delete document by ID. The owner and co-owners may delete it
 // @Tags documents
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
 // @Param id path string true "Document ID"
 // @Success 200 {object} Response
 // @Failure 401 {object} Response
 // @Failure 403 {object} Response
+// @Failure 404 {object} Response
 // @Failure 500 {object} Response
 // @Router /docs/{id} [delete]
 func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
 	login := c.MustGet("login").(string)
 	id := c.Param("id")
 
-	if err := h.docService.DeleteDocument(c.Request.Context(), id, login); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Error: &Error{Code: 500, Text: err.Error()},
+	if err := h.docService.DeleteDocument(c.Request.Context(), id, userID, login); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
 		})
 		return
 	}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyFilter(t *testing.T) {
+	tests := []struct {
+		name, key, value string
+		want             string
+		wantErr          bool
+	}{
+		{"no key", "", "x", "", false},
+		{"no value", "name", "", "", false},
+		{"name", "name", `a "b"`, `name = "a \"b\""`, false},
+		{"mime", "mime", "text/plain", `mime = "text/plain"`, false},
+		{"public", "public", "true", "public = true", false},
+		{"not public", "public", "no", "public = false", false},
+		{"other field", "size", "1", "", true},
+		{"expression as key", "name = \"a\" or public", "true", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := legacyFilter(tt.key, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
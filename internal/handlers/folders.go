@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+type FolderHandler struct {
+	folderService service.FolderService
+	docService    service.DocumentService
+}
+
+func NewFolderHandler(folderService service.FolderService, docService service.DocumentService) *FolderHandler {
+	return &FolderHandler{folderService: folderService, docService: docService}
+}
+
+// CreateFolder godoc
+// @Summary Create folder
+// @Description Create a folder at the root or inside another folder
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateFolderRequest true "Folder name and optional parent"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /folders [post]
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req CreateFolderRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	folder, err := h.folderService.CreateFolder(c.Request.Context(), req.Name, req.ParentID, userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: folder,
+	})
+}
+
+// GetRootFolder godoc
+// @Summary List root folder
+// @Description List the folders and documents at the root of the user's hierarchy
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /folders [get]
+func (h *FolderHandler) GetRootFolder(c *gin.Context) {
+	h.getFolder(c, "")
+}
+
+// GetFolder godoc
+// @Summary List folder
+// @Description Get a folder with the folders and documents directly inside it
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Folder ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /folders/{id} [get]
+func (h *FolderHandler) GetFolder(c *gin.Context) {
+	h.getFolder(c, c.Param("id"))
+}
+
+func (h *FolderHandler) getFolder(c *gin.Context, id string) {
+	userID := c.MustGet("user_id").(string)
+
+	contents, err := h.folderService.GetFolder(c.Request.Context(), id, userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: contents,
+	})
+}
+
+// UpdateFolder godoc
+// @Summary Rename or move folder
+// @Description Change the name and/or parent of a folder. An empty parent_id moves it to the root.
+// @Description A folder cannot be moved into itself or one of its descendants
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Folder ID"
+// @Param request body domain.FolderPatch true "Fields to change"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /folders/{id} [patch]
+func (h *FolderHandler) UpdateFolder(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var patch domain.FolderPatch
+	if err := c.BindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	folder, err := h.folderService.UpdateFolder(c.Request.Context(), c.Param("id"), userID, &patch)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: folder,
+	})
+}
+
+// DeleteFolder godoc
+// @Summary Delete folder
+// @Description Delete an empty folder
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Folder ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /folders/{id} [delete]
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	id := c.Param("id")
+
+	if err := h.folderService.DeleteFolder(c.Request.Context(), id, userID); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{id: true},
+	})
+}
+
+// GetDocumentByPath godoc
+// @Summary Get document by path
+// @Description Resolve a path such as /reports/2026/q3.pdf in the user's folders and return
+// @Description the document like GET /docs/{id}
+// @Tags folders
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json,application/octet-stream
+// @Param path path string true "Folder path and document name"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /paths/{path} [get]
+func (h *FolderHandler) GetDocumentByPath(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	resolved, err := h.folderService.ResolvePath(c.Request.Context(), userID, c.Param("path"))
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	doc, content, err := h.docService.GetDocument(c.Request.Context(), resolved.ID, userID, login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	if content != nil {
+		defer content.Close()
+	}
+
+	serveDocument(c, doc, content)
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+type GroupHandler struct {
+	groupService service.GroupService
+}
+
+func NewGroupHandler(groupService service.GroupService) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// CreateGroup godoc
+// @Summary Create group
+// @Description Create a group that documents can be shared with by putting "group:NAME" in their
+// @Description grant list. The creator owns the group and is its first member
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateGroupRequest true "Group name: 2-50 letters, digits, - or _"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups [post]
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	var req CreateGroupRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(c.Request.Context(), req.Name, userID, login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: group,
+	})
+}
+
+// GetGroups godoc
+// @Summary List groups
+// @Description List the groups the current user is a member of
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups [get]
+func (h *GroupHandler) GetGroups(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	groups, err := h.groupService.GetGroups(c.Request.Context(), userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: groups,
+	})
+}
+
+// GetGroup godoc
+// @Summary Get group
+// @Description Get a group with its members. Only members can see a group
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name path string true "Group name"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups/{name} [get]
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	group, err := h.groupService.GetGroup(c.Request.Context(), c.Param("name"), userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: group,
+	})
+}
+
+// DeleteGroup godoc
+// @Summary Delete group
+// @Description Delete a group together with the grants made to it. Only the owner can delete a group
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name path string true "Group name"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups/{name} [delete]
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	name := c.Param("name")
+
+	if err := h.groupService.DeleteGroup(c.Request.Context(), name, userID); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{name: true},
+	})
+}
+
+// AddGroupMember godoc
+// @Summary Add group member
+// @Description Add a user to the group. Only the owner can add members
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Group name"
+// @Param request body AddGroupMemberRequest true "Login of the user to add"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups/{name}/members [post]
+func (h *GroupHandler) AddMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req AddGroupMemberRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	group, err := h.groupService.AddMember(c.Request.Context(), c.Param("name"), userID, req.Login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: group,
+	})
+}
+
+// RemoveGroupMember godoc
+// @Summary Remove group member
+// @Description Remove a user from the group. The owner can remove any other member; members can
+// @Description remove themselves to leave the group
+// @Tags groups
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name path string true "Group name"
+// @Param login path string true "Login of the member"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /groups/{name}/members/{login} [delete]
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.Param("login")
+
+	if err := h.groupService.RemoveMember(c.Request.Context(), c.Param("name"), userID, login); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{login: true},
+	})
+}
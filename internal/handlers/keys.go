@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+)
+
+type KeyHandler struct {
+	keyService service.KeyService
+}
+
+func NewKeyHandler(keyService service.KeyService) *KeyHandler {
+	return &KeyHandler{keyService: keyService}
+}
+
+// JWKS serves the public keys access tokens are signed with as a JWK set
+// (RFC 7517). It lives outside /api at the well-known path, so it is not in
+// the Swagger docs.
+func (h *KeyHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwt.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, h.keyService.JWKS())
+}
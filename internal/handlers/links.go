@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+type LinkHandler struct {
+	linkService service.LinkService
+}
+
+func NewLinkHandler(linkService service.LinkService) *LinkHandler {
+	return &LinkHandler{linkService: linkService}
+}
+
+type createdLink struct {
+	*domain.ShareLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// CreateLink godoc
+// @Summary Create share link
+// @Description Create a link that gives access to the document without logging in, at /s/{token}.
+// @Description The link can expire, require a password and allow a limited number of downloads.
+// @Description The token is returned only once. The owner and co-owners can create links
+// @Tags links
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body CreateLinkRequest true "Optional expiry, password and download limit"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/links [post]
+func (h *LinkHandler) CreateLink(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	var req CreateLinkRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	link, token, err := h.linkService.CreateLink(c.Request.Context(), c.Param("id"), userID, login,
+		req.Expires, req.Password, req.MaxDownloads)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: createdLink{ShareLink: link, Token: token, URL: "/s/" + token},
+	})
+}
+
+// GetLinks godoc
+// @Summary List share links
+// @Description List the share links of a document with their download counts, including revoked
+// @Description and expired ones
+// @Tags links
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/links [get]
+func (h *LinkHandler) GetLinks(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	links, err := h.linkService.GetLinks(c.Request.Context(), c.Param("id"), userID, login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: links,
+	})
+}
+
+// RevokeLink godoc
+// @Summary Revoke share link
+// @Description Stop a share link from working. It stays listed together with its access log
+// @Tags links
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param link path string true "Link ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/links/{link} [delete]
+func (h *LinkHandler) RevokeLink(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+	linkID := c.Param("link")
+
+	if err := h.linkService.RevokeLink(c.Request.Context(), c.Param("id"), linkID, userID, login); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{linkID: true},
+	})
+}
+
+// GetLinkAccesses godoc
+// @Summary List share link accesses
+// @Description List the attempts to open a share link with their client IP, user agent and outcome
+// @Description (served, no_password, wrong_password, throttled, revoked, expired, used_up, failed), most recent first
+// @Tags links
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param link path string true "Link ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/links/{link}/accesses [get]
+func (h *LinkHandler) GetAccesses(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	accesses, err := h.linkService.GetAccesses(c.Request.Context(), c.Param("id"), c.Param("link"), userID, login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: accesses,
+	})
+}
+
+// OpenLink serves the document behind a share link to anyone who has it. The
+// password of a protected link is sent in the X-Share-Password header, or as
+// the password form field when posted from a browser. It lives outside /api,
+// so it is not in the Swagger docs.
+func (h *LinkHandler) OpenLink(c *gin.Context) {
+	// The token is in the URL, which must not leak to other sites or caches.
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-store")
+
+	password := c.GetHeader("X-Share-Password")
+	if password == "" && c.Request.Method == http.MethodPost {
+		password = c.PostForm("password")
+	}
+
+	access := domain.LinkAccess{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	download := func(doc *domain.Document) bool {
+		return fullDownload(c.Request, doc)
+	}
+
+	doc, content, err := h.linkService.OpenLink(c.Request.Context(), c.Param("token"), password, access, download)
+	if err != nil {
+		setRetryAfter(c, err)
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	if content != nil {
+		defer content.Close()
+	}
+
+	serveDocument(c, doc, content)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMFA godoc
+// @Summary Second login step
+// @Description Exchange the MFA token returned by /auth, together with a TOTP code or a recovery code,
+// @Description for a token pair. After 5 wrong codes the MFA token is no longer accepted.
+// @Description Wrong codes are throttled like wrong passwords; 429 responses carry a Retry-After header
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFARequest true "MFA token and code"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 429 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/mfa [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req MFARequest
+	if err := c.BindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	tokens, err := h.authService.VerifyMFA(c.Request.Context(), c.ClientIP(), req.Token, req.Code)
+	if err != nil {
+		setRetryAfter(c, err)
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: tokens,
+	})
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a TOTP secret and its otpauth:// URI for an authenticator app. Two-factor
+// @Description authentication is enabled once a code is confirmed
+// @Tags mfa
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/me/mfa [post]
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+
+	enrollment, err := h.authService.EnrollTOTP(c.Request.Context(), userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: enrollment,
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app. Returns
+// @Description recovery codes, each usable once instead of a code; they are shown only once
+// @Tags mfa
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body MFACodeRequest true "TOTP code"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/me/mfa/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+
+	var req MFACodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: gin.H{"recovery_codes": codes},
+	})
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Turn off two-factor authentication; requires a TOTP code or a recovery code
+// @Tags mfa
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body MFACodeRequest true "TOTP code or recovery code"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/me/mfa [delete]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+
+	var req MFACodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{"mfa": false},
+	})
+}
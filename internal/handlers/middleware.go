@@ -1,15 +1,40 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
 )
 
-func AuthMiddleware(jwtManager *jwt.Manager) gin.HandlerFunc {
+// AuthMiddleware accepts a JWT in the Authorization header or the token
+// query parameter, or an API key in the X-API-Key header or as
+// "Authorization: ApiKey KEY".
+func AuthMiddleware(authService service.AuthService, apiKeyService service.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKey(c); key != "" {
+			apiKey, err := apiKeyService.ValidateAPIKey(c.Request.Context(), key)
+			if err != nil {
+				status := errorStatus(err)
+				c.JSON(status, Response{
+					Error: &Error{Code: status, Text: err.Error()},
+				})
+				c.Abort()
+				return
+			}
+
+			setUser(c, apiKey.UserID, apiKey.Login)
+			c.Set("api_key_id", apiKey.ID)
+			c.Set("scopes", apiKey.Scopes)
+			c.Next()
+			return
+		}
+
 		token := c.GetHeader("Authorization")
 		if token == "" {
 			token = c.Query("token")
@@ -23,28 +48,90 @@ func AuthMiddleware(jwtManager *jwt.Manager) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := jwtManager.ValidateToken(strings.TrimPrefix(token, "Bearer "))
+		claims, err := authService.ValidateToken(c.Request.Context(), strings.TrimPrefix(token, "Bearer "))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, Response{
-				Error: &Error{Code: 401, Text: "invalid token"},
+			// The error says why the token was rejected: malformed, expired,
+			// wrong audience and so on.
+			status := errorStatus(err)
+			if status == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, err.Error()))
+			}
+			c.JSON(status, Response{
+				Error: &Error{Code: status, Text: err.Error()},
 			})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("login", claims.Login)
+		setUser(c, claims.UserID, claims.Login)
+		c.Set("scopes", claims.Scopes())
+		c.Next()
+	}
+}
+
+// setUser makes the authenticated user known to the handlers and to the
+// audit log.
+func setUser(c *gin.Context, userID, login string) {
+	c.Set("user_id", userID)
+	c.Set("login", login)
+
+	client := service.ClientFrom(c.Request.Context())
+	client.UserID, client.Login = userID, login
+	c.Request = c.Request.WithContext(service.WithClient(c.Request.Context(), client))
+}
+
+// ClientMiddleware attaches the IP and user agent of the client to the
+// request context, where the audit log picks them up.
+func ClientMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithClient(c.Request.Context(), domain.Client{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}))
 		c.Next()
 	}
 }
 
+// RequireScope rejects requests whose token or API key was not granted the
+// scope. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(c.GetStringSlice("scopes"), scope) {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			c.JSON(http.StatusForbidden, Response{
+				Error: &Error{Code: 403, Text: fmt.Sprintf("scope %q required", scope)},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "ApiKey "); ok {
+		return key
+	}
+	return ""
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, Range, If-Range, If-None-Match, If-Modified-Since, "+
+			"Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Range, Content-Disposition, ETag, Last-Modified, Location, "+
+			"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Document-Id, "+
+			"WWW-Authenticate")
 
-		if c.Request.Method == "OPTIONS" {
+		// Plain OPTIONS requests fall through so tus clients can discover
+		// the upload capabilities; only CORS preflights are answered here.
+		if c.Request.Method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -52,3 +139,26 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// BodyLimit caps the request body; reading past the limit fails with
+// *http.MaxBytesError.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// TransferTimeout extends the connection deadlines set by the server for
+// routes that stream large bodies.
+func TransferTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deadline := time.Now().Add(timeout)
+
+		rc := http.NewResponseController(c.Writer)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+
+		c.Next()
+	}
+}
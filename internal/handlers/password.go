@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Set a new password; requires the current one. Every session of the user is ended,
+// @Description and a new token pair with the scopes of the current token is returned
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 429 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/me/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	if !sessionOnly(c) {
+		return
+	}
+	userID := c.MustGet("user_id").(string)
+	scopes := c.MustGet("scopes").([]string)
+
+	var req ChangePasswordRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	tokens, err := h.authService.ChangePassword(c.Request.Context(), c.ClientIP(), userID, req.Current, req.Pswd, scopes)
+	if err != nil {
+		setRetryAfter(c, err)
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: tokens,
+	})
+}
+
+// IssuePasswordReset godoc
+// @Summary Issue password reset
+// @Description Get a one-time token, valid for 24 hours, with which the user sets a new password
+// @Description through /auth/password-reset. Requires the admin scope
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Param login path string true "User login"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /users/{login}/password-reset [post]
+func (h *AuthHandler) IssuePasswordReset(c *gin.Context) {
+	reset, err := h.authService.IssuePasswordReset(c.Request.Context(), c.Param("login"))
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: reset,
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with a token issued by an admin. Every session of the user is ended
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 500 {object} Response
+// @Router /auth/password-reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.BindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid request"},
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.Pswd); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Response: gin.H{"password": true},
+	})
+}
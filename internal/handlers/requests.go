@@ -1,24 +1,61 @@
 package handlers
 
-import "github.com/mibrgmv/document-service/internal/domain"
+import (
+	"time"
 
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+// AuthRequest.Scope is a space-separated list of scopes; docs:read,
+// docs:write and docs:delete are granted if it is empty.
 type AuthRequest struct {
 	Login string `json:"login"`
 	Pswd  string `json:"pswd"`
+	Scope string `json:"scope,omitempty" example:"docs:read docs:write"`
+}
+
+type MFARequest struct {
+	Token string `json:"token"`
+	Code  string `json:"code"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type ChangePasswordRequest struct {
+	Current string `json:"current_pswd"`
+	Pswd    string `json:"pswd"`
+}
+
+type ResetPasswordRequest struct {
+	Token string `json:"token"`
+	Pswd  string `json:"pswd"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name    string     `json:"name"`
+	Scopes  []string   `json:"scopes"`
+	Expires *time.Time `json:"expires"`
 }
 
 type RegisterRequest struct {
 	Token string `json:"token"`
 	Login string `json:"login"`
 	Pswd  string `json:"pswd"`
+	Admin bool   `json:"admin,omitempty"`
 }
 
 type DocumentMeta struct {
-	Name   string   `json:"name"`
-	File   bool     `json:"file"`
-	Public bool     `json:"public"`
-	Mime   string   `json:"mime"`
-	Grant  []string `json:"grant"`
+	Name   string         `json:"name"`
+	File   bool           `json:"file"`
+	Public bool           `json:"public"`
+	Mime   string         `json:"mime"`
+	Grant  []domain.Grant `json:"grant"`
 }
 
 func (m *DocumentMeta) ToDomain() *domain.DocumentMeta {
@@ -30,3 +67,22 @@ func (m *DocumentMeta) ToDomain() *domain.DocumentMeta {
 		Grant:  m.Grant,
 	}
 }
+
+type CreateFolderRequest struct {
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+}
+
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type AddGroupMemberRequest struct {
+	Login string `json:"login"`
+}
+
+type CreateLinkRequest struct {
+	Expires      *time.Time `json:"expires"`
+	Password     string     `json:"password"`
+	MaxDownloads int        `json:"max_downloads"`
+}
@@ -1,5 +1,12 @@
 package handlers
 
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
 type Response struct {
 	Error    interface{} `json:"error,omitempty"`
 	Response interface{} `json:"response,omitempty"`
@@ -10,3 +17,49 @@ type Error struct {
 	Code int    `json:"code"`
 	Text string `json:"text"`
 }
+
+func errorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, service.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrNotDiffable), errors.Is(err, service.ErrInvalidMeta),
+		errors.Is(err, service.ErrInvalidName), errors.Is(err, service.ErrEmptyQuery),
+		errors.Is(err, service.ErrInvalidSort), errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidFilter),
+		errors.Is(err, service.ErrInvalidScope), errors.Is(err, service.ErrInvalidExpiry),
+		errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrWeakPassword),
+		errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrUnknownGroup),
+		errors.Is(err, service.ErrUnknownUser), errors.Is(err, service.ErrInvalidRange),
+		errors.Is(err, service.ErrInvalidDownloads):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrOffsetMismatch), errors.Is(err, service.ErrUploadFinished),
+		errors.Is(err, service.ErrFolderExists),
+		errors.Is(err, service.ErrFolderCycle), errors.Is(err, service.ErrFolderNotEmpty),
+		errors.Is(err, service.ErrOIDCLogin), errors.Is(err, service.ErrMFAEnabled),
+		errors.Is(err, service.ErrMFANotEnrolled), errors.Is(err, service.ErrMFAUnavailable),
+		errors.Is(err, service.ErrPasswordUnavailable), errors.Is(err, service.ErrGroupExists),
+		errors.Is(err, service.ErrAlreadyMember), errors.Is(err, service.ErrGroupOwner):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrTokenRevoked),
+		errors.Is(err, service.ErrTokenReused), errors.Is(err, service.ErrInvalidAPIKey),
+		errors.Is(err, service.ErrInvalidIDToken), errors.Is(err, service.ErrOIDCExchange),
+		errors.Is(err, service.ErrInvalidMFAToken), errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidResetToken),
+		errors.Is(err, service.ErrLinkPassword):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrOIDCDisabled),
+		errors.Is(err, service.ErrInvalidLink):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrLinkExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrAccessDenied), errors.Is(err, service.ErrScopeNotAllowed),
+		errors.Is(err, service.ErrWrongPassword):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+)
+
+// UploadHandler serves the tus 1.0 resumable upload protocol
+// (https://tus.io/protocols/resumable-upload) with the creation, termination
+// and expiration extensions.
+type UploadHandler struct {
+	uploadService service.UploadService
+	maxSize       int64
+}
+
+func NewUploadHandler(uploadService service.UploadService, maxSize int64) *UploadHandler {
+	return &UploadHandler{uploadService: uploadService, maxSize: maxSize}
+}
+
+// TusResumable rejects requests that speak a different protocol version and
+// adds the Tus-Resumable header to every response.
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+			c.Header("Tus-Version", tusVersion)
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Options godoc
+// @Summary Upload capabilities
+// @Description tus discovery: supported versions, extensions and the maximum upload size
+// @Tags uploads
+// @Success 204
+// @Router /uploads [options]
+func (h *UploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if h.maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Create upload
+// @Description Start a resumable upload (tus creation extension). Upload-Metadata may contain
+// @Description filename (or name), filetype (or mime), public ("true"/"false"), grant (comma separated logins, each optionally followed by :role)
+// @Description and folder (folder ID)
+// @Tags uploads
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Length header integer true "Total size in bytes"
+// @Param Upload-Metadata header string false "tus metadata"
+// @Success 201
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 412
+// @Failure 413 {object} Response
+// @Failure 500 {object} Response
+// @Router /uploads [post]
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid Upload-Length"},
+		})
+		return
+	}
+
+	meta, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid Upload-Metadata"},
+		})
+		return
+	}
+
+	upload, err := h.uploadService.CreateUpload(c.Request.Context(), meta, length, userID)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	c.Header("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetUploadOffset godoc
+// @Summary Upload offset
+// @Description Get the number of bytes received so far
+// @Tags uploads
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Failure 412
+// @Router /uploads/{id} [head]
+func (h *UploadHandler) GetUploadOffset(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	upload, err := h.uploadService.GetUpload(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		c.Status(errorStatus(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload godoc
+// @Summary Upload chunk
+// @Description Append bytes at Upload-Offset. The upload becomes a document once all bytes
+// @Description are received; its ID is returned in the Document-Id header
+// @Tags uploads
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Offset header integer true "Offset of the chunk"
+// @Success 204
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 412
+// @Failure 415 {object} Response
+// @Failure 500 {object} Response
+// @Router /uploads/{id} [patch]
+func (h *UploadHandler) PatchUpload(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, Response{
+			Error: &Error{Code: 415, Text: "content type must be application/offset+octet-stream"},
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid Upload-Offset"},
+		})
+		return
+	}
+
+	upload, doc, err := h.uploadService.WriteChunk(c.Request.Context(), c.Param("id"), userID, offset, c.Request.Body)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	if doc != nil {
+		c.Header("Document-Id", doc.ID)
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary Terminate upload
+// @Description Abort an upload and discard the received bytes (tus termination extension)
+// @Tags uploads
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 204
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 412
+// @Failure 500 {object} Response
+// @Router /uploads/{id} [delete]
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if err := h.uploadService.TerminateUpload(c.Request.Context(), c.Param("id"), userID); err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header: comma separated
+// pairs of a key and an optional base64 encoded value.
+func parseUploadMetadata(header string) (*domain.DocumentMeta, error) {
+	meta := &domain.DocumentMeta{File: true, Grant: []domain.Grant{}}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, errors.New("malformed metadata pair")
+		}
+
+		var value string
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			value = string(decoded)
+		}
+
+		switch fields[0] {
+		case "filename", "name":
+			meta.Name = value
+		case "filetype", "mime":
+			meta.Mime = value
+		case "public":
+			meta.Public = value == "true"
+		case "folder", "folder_id":
+			meta.FolderID = value
+		case "grant":
+			for _, grant := range strings.Split(value, ",") {
+				// Group grantees carry a colon of their own: group:name:role.
+				grant, prefix := strings.TrimSpace(grant), ""
+				if name, ok := domain.GroupName(grant); ok {
+					grant, prefix = name, domain.GroupPrefix
+				}
+				grantee, role, _ := strings.Cut(grant, ":")
+				if grantee != "" {
+					meta.Grant = append(meta.Grant, domain.Grant{Grantee: prefix + grantee, Role: role})
+				}
+			}
+		}
+	}
+
+	return meta, nil
+}
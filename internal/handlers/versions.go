@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateDocument godoc
+// @Summary Update document content
+// @Description Upload new content for a document, creating a new immutable version.
+// @Description File documents take a file part, JSON documents a json part. Editors may update documents
+// @Description shared with them
+// @Tags versions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param file formData file false "New file content"
+// @Param json formData string false "New JSON data (if not file)"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 413 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id} [put]
+func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+	id := c.Param("id")
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid multipart form"},
+		})
+		return
+	}
+
+	var jsonData string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			status := errorStatus(err)
+			if status == http.StatusInternalServerError {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, Response{
+				Error: &Error{Code: status, Text: "invalid multipart form"},
+			})
+			return
+		}
+
+		switch part.FormName() {
+		case "json":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				status := errorStatus(err)
+				c.JSON(status, Response{
+					Error: &Error{Code: status, Text: err.Error()},
+				})
+				return
+			}
+			jsonData = string(data)
+		case "file":
+			h.updateDocument(c, id, userID, login, part, "")
+			return
+		}
+	}
+
+	h.updateDocument(c, id, userID, login, http.NoBody, jsonData)
+}
+
+func (h *DocumentHandler) updateDocument(c *gin.Context, id, userID, login string, content io.Reader, jsonData string) {
+	doc, err := h.docService.UpdateDocument(c.Request.Context(), id, userID, login, content, jsonData)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: doc,
+	})
+}
+
+// GetVersions godoc
+// @Summary List document versions
+// @Description Get the version history of a document, oldest first
+// @Tags versions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/versions [get]
+func (h *DocumentHandler) GetVersions(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	versions, err := h.docService.GetVersions(c.Request.Context(), c.Param("id"), userID, login)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: gin.H{"versions": versions},
+	})
+}
+
+// GetVersion godoc
+// @Summary Get document version
+// @Description Get the content of a specific version. Supports the same range and conditional headers as GET /docs/{id}
+// @Tags versions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json,application/octet-stream
+// @Param id path string true "Document ID"
+// @Param version path integer true "Version number"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/versions/{version} [get]
+func (h *DocumentHandler) GetVersion(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid version"},
+		})
+		return
+	}
+
+	doc, content, err := h.docService.GetVersion(c.Request.Context(), c.Param("id"), userID, login, version)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	if content != nil {
+		defer content.Close()
+	}
+
+	serveDocument(c, doc, content)
+}
+
+// RestoreVersion godoc
+// @Summary Restore document version
+// @Description Make the content of an earlier version current by adding it as a new version
+// @Tags versions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param version path integer true "Version number"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/versions/{version}/restore [post]
+func (h *DocumentHandler) RestoreVersion(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "invalid version"},
+		})
+		return
+	}
+
+	doc, err := h.docService.RestoreVersion(c.Request.Context(), c.Param("id"), userID, login, version)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: doc,
+	})
+}
+
+// DiffVersions godoc
+// @Summary Diff document versions
+// @Description Compare two versions of a JSON document. Returns a unified text diff
+// @Description and, when both versions are valid JSON, a list of structural changes
+// @Tags versions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param from query integer true "Base version"
+// @Param to query integer true "Target version"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /docs/{id}/diff [get]
+func (h *DocumentHandler) DiffVersions(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	login := c.MustGet("login").(string)
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Error: &Error{Code: 400, Text: "from and to must be version numbers"},
+		})
+		return
+	}
+
+	result, err := h.docService.DiffVersions(c.Request.Context(), c.Param("id"), userID, login, from, to)
+	if err != nil {
+		status := errorStatus(err)
+		c.JSON(status, Response{
+			Error: &Error{Code: status, Text: err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Data: result,
+	})
+}
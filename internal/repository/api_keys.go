@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
+	// GetAPIKeyByHash returns the key with the login of its user.
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	GetUserAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id, userID string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
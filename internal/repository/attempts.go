@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttemptRepository counts failed logins per key, such as a login or a
+// client IP, and keeps keys locked out for a while.
+type LoginAttemptRepository interface {
+	// LockedFor returns how long logins for the key are still refused.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// FailLogin counts a failed login and returns the failures within window.
+	FailLogin(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	// ResetLogin clears the failures and the lock of the key.
+	ResetLogin(ctx context.Context, key string) error
+}
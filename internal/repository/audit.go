@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type AuditRepository interface {
+	AddEvent(ctx context.Context, event *domain.AuditEvent) error
+	// GetEvents calls fn for each event matching the filter, newest first,
+	// and stops at the first error fn returns.
+	GetEvents(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEvent) error) error
+}
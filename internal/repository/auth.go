@@ -9,5 +9,21 @@ import (
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *domain.User) error
 	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	GetUserBySubject(ctx context.Context, issuer, subject string) (*domain.User, error)
 	UserExists(ctx context.Context, login string) (bool, error)
+	SetPassword(ctx context.Context, userID, hash string) error
+
+	// SetTOTPSecret stores the secret of an enrollment that is not confirmed
+	// yet; it fails with ErrNotFound if TOTP is already enabled.
+	SetTOTPSecret(ctx context.Context, userID, secret string) error
+	// EnableTOTP confirms the enrollment and replaces the recovery codes.
+	EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodes []string) error
+	DisableTOTP(ctx context.Context, userID string) error
+	// UseTOTPStep records the time step of a code and reports false if that
+	// step or a later one has been used already.
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	// UseRecoveryCode marks the code used and reports false if there is no
+	// such unused code.
+	UseRecoveryCode(ctx context.Context, userID, hash string) (bool, error)
 }
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// BlobInfo describes a stored blob. Checksum is the hex encoded SHA-256 of the
+// content and is only known to the store right after Put.
+type BlobInfo struct {
+	Key      string
+	Size     int64
+	Checksum string
+	Modified time.Time
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (*BlobInfo, error)
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+}
@@ -0,0 +1,171 @@
+// Package blobtest checks that a repository.BlobStore behaves the way the
+// services expect, so every implementation can be tested against the same
+// contract.
+package blobtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run tests the store. Keys are created under prefix and removed again.
+// largeSize is the size of the biggest blob put, which should be large enough
+// for the store to take another path than for small blobs, such as a
+// multi-part upload.
+func Run(t *testing.T, store repository.BlobStore, prefix string, largeSize int) {
+	ctx := context.Background()
+	key := func(name string) string {
+		k := prefix + name
+		t.Cleanup(func() { store.Delete(context.Background(), k) })
+		return k
+	}
+
+	t.Run("put and get", func(t *testing.T) {
+		k := key("docs/a")
+		content := []byte("hello, world")
+
+		info, err := store.Put(ctx, k, bytes.NewReader(content))
+		require.NoError(t, err)
+		assert.Equal(t, k, info.Key)
+		assert.Equal(t, int64(len(content)), info.Size)
+		assert.Equal(t, checksum(content), info.Checksum)
+		assert.False(t, info.Modified.IsZero())
+
+		assert.Equal(t, content, read(t, store, k))
+
+		stat, err := store.Stat(ctx, k)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), stat.Size)
+	})
+
+	t.Run("put replaces", func(t *testing.T) {
+		k := key("docs/b")
+
+		_, err := store.Put(ctx, k, strings.NewReader("first version"))
+		require.NoError(t, err)
+		_, err = store.Put(ctx, k, strings.NewReader("second"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []byte("second"), read(t, store, k))
+	})
+
+	t.Run("empty blob", func(t *testing.T) {
+		k := key("docs/empty")
+
+		info, err := store.Put(ctx, k, strings.NewReader(""))
+		require.NoError(t, err)
+		assert.Zero(t, info.Size)
+		assert.Empty(t, read(t, store, k))
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		k := key("docs/c")
+		_, err := store.Put(ctx, k, strings.NewReader("hello, world"))
+		require.NoError(t, err)
+
+		blob, err := store.Get(ctx, k)
+		require.NoError(t, err)
+		defer blob.Close()
+
+		offset, err := blob.Seek(7, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), offset)
+		assertRead(t, blob, "world")
+
+		offset, err = blob.Seek(-12, io.SeekCurrent)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), offset)
+		assertRead(t, blob, "hello")
+
+		size, err := blob.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), size)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		k := key("uploads/u1/0")
+		_, err := store.Put(ctx, k, strings.NewReader("part"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Delete(ctx, k))
+
+		_, err = store.Get(ctx, k)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		_, err = store.Stat(ctx, k)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		// Deleting is idempotent, so cleanups can be retried.
+		assert.NoError(t, store.Delete(ctx, k))
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := store.Get(ctx, prefix+"missing")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		_, err = store.Stat(ctx, prefix+"missing")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("large blob", func(t *testing.T) {
+		k := key("docs/large")
+		content := make([]byte, largeSize)
+		rng := rand.New(rand.NewPCG(1, 2))
+		for i := range content {
+			content[i] = byte(rng.Uint32())
+		}
+
+		// A reader of unknown length, as an upload request body is.
+		info, err := store.Put(ctx, k, io.MultiReader(bytes.NewReader(content)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(largeSize), info.Size)
+		assert.Equal(t, checksum(content), info.Checksum)
+
+		blob, err := store.Get(ctx, k)
+		require.NoError(t, err)
+		defer blob.Close()
+
+		_, err = blob.Seek(int64(largeSize-100), io.SeekStart)
+		require.NoError(t, err)
+		tail, err := io.ReadAll(blob)
+		require.NoError(t, err)
+		assert.Equal(t, content[largeSize-100:], tail)
+
+		_, err = blob.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		all, err := io.ReadAll(blob)
+		require.NoError(t, err)
+		assert.Equal(t, checksum(content), checksum(all))
+	})
+}
+
+func read(t *testing.T, store repository.BlobStore, key string) []byte {
+	t.Helper()
+	blob, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer blob.Close()
+
+	content, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	return content
+}
+
+func assertRead(t *testing.T, r io.Reader, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	_, err := io.ReadFull(r, got)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+}
+
+func checksum(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
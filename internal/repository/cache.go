@@ -8,8 +8,8 @@ import (
 )
 
 type CacheRepository interface {
-	SetDocuments(ctx context.Context, key string, docs []domain.Document, expiration time.Duration) error
-	GetDocuments(ctx context.Context, key string) ([]domain.Document, error)
+	SetDocuments(ctx context.Context, key string, page *domain.DocumentPage, expiration time.Duration) error
+	GetDocuments(ctx context.Context, key string) (*domain.DocumentPage, error)
 	SetDocument(ctx context.Context, key string, doc *domain.Document, expiration time.Duration) error
 	GetDocument(ctx context.Context, key string) (*domain.Document, error)
 	Delete(ctx context.Context, key string) error
@@ -9,7 +9,16 @@ import (
 type DocumentRepository interface {
 	CreateDocument(ctx context.Context, doc *domain.Document) error
 	GetDocumentByID(ctx context.Context, id string) (*domain.Document, error)
-	GetUserDocuments(ctx context.Context, login string, limit int) ([]domain.Document, error)
-	DeleteDocument(ctx context.Context, id, owner string) error
+	// GetUserDocuments and SearchDocuments return the documents the user owns,
+	// that are public or that are shared with the user or one of the groups.
+	GetUserDocuments(ctx context.Context, userID string, groupIDs []string, opts domain.ListOptions) (*domain.DocumentPage, error)
+	SearchDocuments(ctx context.Context, userID string, groupIDs []string, query string, limit int) ([]domain.SearchResult, error)
+	GetFolderDocuments(ctx context.Context, owner, folderID string) ([]domain.Document, error)
+	GetDocumentByName(ctx context.Context, owner, folderID, name string) (*domain.Document, error)
+	UpdateDocumentMeta(ctx context.Context, doc *domain.Document) error
+	DeleteDocument(ctx context.Context, id string) (*domain.Document, error)
 	DocumentExists(ctx context.Context, id string) (bool, error)
+	AddVersion(ctx context.Context, version *domain.DocumentVersion) error
+	GetVersions(ctx context.Context, docID string) ([]domain.DocumentVersion, error)
+	GetVersion(ctx context.Context, docID string, version int) (*domain.DocumentVersion, error)
 }
@@ -0,0 +1,11 @@
+package repository
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrCycle         = errors.New("cycle")
+	ErrNotEmpty      = errors.New("not empty")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type FolderRepository interface {
+	CreateFolder(ctx context.Context, folder *domain.Folder) error
+	GetFolderByID(ctx context.Context, id string) (*domain.Folder, error)
+	GetFolderByName(ctx context.Context, owner, parentID, name string) (*domain.Folder, error)
+	GetSubfolders(ctx context.Context, owner, parentID string) ([]domain.Folder, error)
+	UpdateFolder(ctx context.Context, folder *domain.Folder) error
+	DeleteFolder(ctx context.Context, id, owner string) error
+}
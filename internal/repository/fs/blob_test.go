@@ -0,0 +1,42 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mibrgmv/document-service/internal/repository/blobtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobStore(t *testing.T) {
+	store, err := NewBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	blobtest.Run(t, store, "", 4<<20)
+}
+
+func TestBlobStore_Layout(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewBlobStore(root)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Put(ctx, "uploads/u1/0", strings.NewReader("part"))
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, "uploads/u1/0"))
+
+	// The directories emptied by the delete are removed, the root is kept.
+	_, err = os.Stat(filepath.Join(root, "uploads"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(root)
+	assert.NoError(t, err)
+
+	for _, key := range []string{"../escape", "/abs", "a//b", "."} {
+		_, err := store.Put(ctx, key, strings.NewReader("x"))
+		assert.Error(t, err, key)
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type GroupRepository interface {
+	// CreateGroup stores the group together with its members; it fails with
+	// ErrConflict when the name is taken.
+	CreateGroup(ctx context.Context, group *domain.Group) error
+	GetGroupByName(ctx context.Context, name string) (*domain.Group, error)
+	// GetUserGroups lists the groups the user is a member of, without their
+	// members.
+	GetUserGroups(ctx context.Context, userID string) ([]domain.Group, error)
+	AddMember(ctx context.Context, groupID, userID string) error
+	RemoveMember(ctx context.Context, groupID, userID string) error
+	// DeleteGroup deletes the group and the grants made to it, and returns
+	// the IDs of the documents that were shared with it.
+	DeleteGroup(ctx context.Context, id string) ([]string, error)
+}
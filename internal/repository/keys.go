@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type SigningKeyRepository interface {
+	// GetSigningKeys returns the keys that have not retired by now.
+	GetSigningKeys(ctx context.Context, now time.Time) ([]domain.SigningKey, error)
+	// RotateSigningKey stores the key and retires the keys it replaces,
+	// unless an active key of the same algorithm created after notBefore
+	// exists.
+	RotateSigningKey(ctx context.Context, key *domain.SigningKey, notBefore, retires time.Time) error
+	DeleteRetiredKeys(ctx context.Context, now time.Time) error
+}
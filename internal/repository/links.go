@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type ShareLinkRepository interface {
+	CreateLink(ctx context.Context, link *domain.ShareLink) error
+	GetLinkByHash(ctx context.Context, hash string) (*domain.ShareLink, error)
+	GetDocumentLinks(ctx context.Context, docID string) ([]domain.ShareLink, error)
+	// RevokeLink fails with ErrNotFound unless the document has such a link
+	// that is not revoked yet.
+	RevokeLink(ctx context.Context, docID, id string, at time.Time) error
+	// UseLink counts a download and reports false, without counting, if the
+	// link is revoked, expired or has no downloads left.
+	UseLink(ctx context.Context, id string, now time.Time) (bool, error)
+	LogAccess(ctx context.Context, access *domain.LinkAccess) error
+	GetAccesses(ctx context.Context, linkID string) ([]domain.LinkAccess, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+// MFAChallengeRepository keeps password logins waiting for a second factor,
+// keyed by the hash of the challenge token.
+type MFAChallengeRepository interface {
+	SaveChallenge(ctx context.Context, hash string, login *domain.PendingLogin, ttl time.Duration) error
+	GetChallenge(ctx context.Context, hash string) (*domain.PendingLogin, error)
+	// FailChallenge counts a wrong code and returns the count so far. The
+	// count is kept for ttl.
+	FailChallenge(ctx context.Context, hash string, ttl time.Duration) (int64, error)
+	DeleteChallenge(ctx context.Context, hash string) error
+}
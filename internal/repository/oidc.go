@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type OIDCStateRepository interface {
+	SaveOIDCState(ctx context.Context, state string, oidcState *domain.OIDCState, ttl time.Duration) error
+	// TakeOIDCState returns the state and deletes it, so each can be used once.
+	TakeOIDCState(ctx context.Context, state string) (*domain.OIDCState, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type PasswordResetRepository interface {
+	SaveResetToken(ctx context.Context, hash, userID string, ttl time.Duration) error
+	// TakeResetToken returns the user the token was issued for and deletes
+	// it, so each can be used once.
+	TakeResetToken(ctx context.Context, hash string) (string, error)
+}
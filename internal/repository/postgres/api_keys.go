@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type apiKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) repository.APIKeyRepository {
+	return &apiKeyRepository{pool: pool}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	sql := `
+	insert into api_keys (id, user_id, name, prefix, hash, scopes, created, expires)
+	values ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, sql, key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.Scopes,
+		key.Created, key.Expires)
+	return err
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	sql := `
+	select k.id, k.user_id, u.login, u.admin, k.name, k.prefix, k.hash, k.scopes, k.created, k.expires, k.last_used
+	from api_keys k
+	join users u on u.id = k.user_id
+	where k.hash = $1
+	`
+
+	var key domain.APIKey
+	err := r.pool.QueryRow(ctx, sql, hash).Scan(&key.ID, &key.UserID, &key.Login, &key.UserAdmin, &key.Name, &key.Prefix,
+		&key.Hash, &key.Scopes, &key.Created, &key.Expires, &key.LastUsed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetUserAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	sql := `
+	select id, user_id, name, prefix, scopes, created, expires, last_used
+	from api_keys
+	where user_id = $1
+	order by created desc
+	`
+
+	rows, err := r.pool.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		var key domain.APIKey
+		err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scopes, &key.Created,
+			&key.Expires, &key.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) DeleteAPIKey(ctx context.Context, id, userID string) error {
+	sql := `
+	delete from api_keys
+	where id = $1 and user_id = $2
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	// Recording every request would mean a write per call; a minute is
+	// precise enough to tell whether a key is still in use.
+	sql := `
+	update api_keys
+	set last_used = $2
+	where id = $1 and (last_used is null or last_used < $2 - interval '1 minute')
+	`
+
+	_, err := r.pool.Exec(ctx, sql, id, usedAt)
+	return err
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type auditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) repository.AuditRepository {
+	return &auditRepository{pool: pool}
+}
+
+func (r *auditRepository) AddEvent(ctx context.Context, event *domain.AuditEvent) error {
+	sql := `
+	insert into audit_events (time, actor_id, actor, action, document_id, detail, ip, user_agent, outcome)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	returning id
+	`
+
+	return r.pool.QueryRow(ctx, sql, event.Time, nullable(event.ActorID), nullable(event.Actor), event.Action,
+		nullable(event.DocumentID), nullable(event.Detail), event.IP, event.UserAgent, event.Outcome).Scan(&event.ID)
+}
+
+func (r *auditRepository) GetEvents(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEvent) error) error {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Actor != "" {
+		where("(actor_id = $%[1]d or actor = $%[1]d)", filter.Actor)
+	}
+	if filter.DocumentID != "" {
+		where("document_id = $%d", filter.DocumentID)
+	}
+	if !filter.From.IsZero() {
+		where("time >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("time < $%d", filter.To)
+	}
+	if filter.Before != 0 {
+		where("id < $%d", filter.Before)
+	}
+
+	sql := `
+	select id, time, coalesce(actor_id, ''), coalesce(actor, ''), action, coalesce(document_id, ''),
+	       coalesce(detail, ''), ip, user_agent, outcome
+	from audit_events
+	`
+	if len(conditions) > 0 {
+		sql += "where " + strings.Join(conditions, " and ") + "\n"
+	}
+	sql += "order by id desc"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		sql += fmt.Sprintf(" limit $%d", len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event domain.AuditEvent
+		err := rows.Scan(&event.ID, &event.Time, &event.ActorID, &event.Actor, &event.Action, &event.DocumentID,
+			&event.Detail, &event.IP, &event.UserAgent, &event.Outcome)
+		if err != nil {
+			return err
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
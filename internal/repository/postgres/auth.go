@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/repository"
@@ -16,32 +18,70 @@ func NewUserRepository(pool *pgxpool.Pool) repository.UserRepository {
 	return &userRepository{pool: pool}
 }
 
+const userColumns = `id, login, password, admin, created, coalesce(oidc_issuer, ''), coalesce(oidc_subject, ''),
+	coalesce(totp_secret, ''), totp_enabled`
+
+func scanUser(row pgx.Row) (*domain.User, error) {
+	var user domain.User
+	err := row.Scan(&user.ID, &user.Login, &user.Password, &user.Admin, &user.Created, &user.OIDCIssuer,
+		&user.OIDCSubject, &user.TOTPSecret, &user.TOTPEnabled)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) CreateUser(ctx context.Context, user *domain.User) error {
 	sql := `
-	insert into users (id, login, password, created)
-	values ($1, $2, $3, $4)
+	insert into users (id, login, password, admin, created, oidc_issuer, oidc_subject)
+	values ($1, $2, $3, $4, $5, nullif($6, ''), nullif($7, ''))
 	`
 
-	_, err := r.pool.Exec(ctx, sql, user.ID, user.Login, user.Password, user.Created)
+	_, err := r.pool.Exec(ctx, sql, user.ID, user.Login, user.Password, user.Admin, user.Created,
+		user.OIDCIssuer, user.OIDCSubject)
 	return err
 }
 
 func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*domain.User, error) {
 	sql := `
-	select id, login, password, created
-	from users 
+	select ` + userColumns + `
+	from users
 	where login = $1
 	`
 
-	row := r.pool.QueryRow(ctx, sql, login)
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, login))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return user, err
+}
 
-	var user domain.User
-	err := row.Scan(&user.ID, &user.Login, &user.Password, &user.Created)
-	if err != nil {
-		return nil, err
+func (r *userRepository) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	sql := `
+	select ` + userColumns + `
+	from users
+	where id = $1
+	`
+
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
 	}
+	return user, err
+}
 
-	return &user, nil
+func (r *userRepository) GetUserBySubject(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	sql := `
+	select ` + userColumns + `
+	from users
+	where oidc_issuer = $1 and oidc_subject = $2
+	`
+
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, issuer, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return user, err
 }
 
 func (r *userRepository) UserExists(ctx context.Context, login string) (bool, error) {
@@ -53,3 +93,119 @@ func (r *userRepository) UserExists(ctx context.Context, login string) (bool, er
 	err := r.pool.QueryRow(ctx, sql, login).Scan(&exists)
 	return exists, err
 }
+
+func (r *userRepository) SetPassword(ctx context.Context, userID, hash string) error {
+	sql := `
+	update users
+	set password = $2
+	where id = $1
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, userID, hash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetTOTPSecret(ctx context.Context, userID, secret string) error {
+	sql := `
+	update users
+	set totp_secret = $2
+	where id = $1 and not totp_enabled
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodes []string) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `
+		update users
+		set totp_enabled = true, totp_last_step = $2
+		where id = $1 and totp_secret is not null
+		`
+
+		tag, err := tx.Exec(ctx, sql, userID, step)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+func (r *userRepository) DisableTOTP(ctx context.Context, userID string) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `
+		update users
+		set totp_secret = null, totp_enabled = false, totp_last_step = 0
+		where id = $1
+		`
+
+		if _, err := tx.Exec(ctx, sql, userID); err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, nil)
+	})
+}
+
+func (r *userRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	sql := `
+	update users
+	set totp_last_step = $2
+	where id = $1 and totp_last_step < $2
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *userRepository) UseRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	sql := `
+	update recovery_codes
+	set used = now()
+	where user_id = $1 and hash = $2 and used is null
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, hashes []string) error {
+	if _, err := tx.Exec(ctx, `delete from recovery_codes where user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		sql := `
+		insert into recovery_codes (user_id, hash)
+		values ($1, $2)
+		`
+
+		if _, err := tx.Exec(ctx, sql, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
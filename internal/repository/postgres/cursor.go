@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+// sortColumns maps the sort keys accepted by GetUserDocuments to columns.
+var sortColumns = map[string]string{
+	"name":    "name",
+	"created": "created",
+	"size":    "size",
+	"mime":    "coalesce(mime, '')",
+}
+
+// cursor is the position after the last document of a page: the value of the
+// sort column and the ID breaking ties. It records the sort it was created
+// for so it cannot be replayed against a different order.
+type cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"i"`
+}
+
+func encodeCursor(opts domain.ListOptions, doc *domain.Document) string {
+	var value any
+	switch opts.Sort {
+	case "name":
+		value = doc.Name
+	case "created":
+		value = doc.Created
+	case "size":
+		value = doc.Size
+	case "mime":
+		value = doc.Mime
+	}
+
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(cursor{Sort: opts.Sort, Desc: opts.Desc, Value: raw, ID: doc.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort value and ID stored in the cursor, typed for
+// use as query arguments.
+func decodeCursor(opts domain.ListOptions) (any, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+	if err != nil {
+		return nil, "", repository.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != opts.Sort || c.Desc != opts.Desc || c.ID == "" {
+		return nil, "", repository.ErrInvalidCursor
+	}
+
+	var value any
+	switch c.Sort {
+	case "name", "mime":
+		var s string
+		err = json.Unmarshal(c.Value, &s)
+		value = s
+	case "created":
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		value = t
+	case "size":
+		var n int64
+		err = json.Unmarshal(c.Value, &n)
+		value = n
+	}
+	if err != nil {
+		return nil, "", repository.ErrInvalidCursor
+	}
+
+	return value, c.ID, nil
+}
@@ -2,7 +2,11 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/repository"
@@ -16,22 +20,87 @@ func NewDocumentRepository(pool *pgxpool.Pool) repository.DocumentRepository {
 	return &documentRepository{pool: pool}
 }
 
+// grantsOf selects the grants of the documents row named table as a JSON
+// array, to be scanned into a grantList.
+func grantsOf(table string) string {
+	return fmt.Sprintf(`coalesce((select json_agg(json_build_object(
+			'grantee', coalesce(u.login, '%[2]s' || ug.name), 'role', g.role,
+			'user_id', g.user_id, 'group_id', g.group_id) order by u.login, ug.name)
+		from document_grants g
+		left join users u on u.id = g.user_id
+		left join user_groups ug on ug.id = g.group_id
+		where g.document_id = %[1]s.id), '[]')`, table, domain.GroupPrefix)
+}
+
+// visibleTo matches the rows of table the user given as $1 can read, either
+// directly or through one of the groups given as $2.
+func visibleTo(table string) string {
+	return fmt.Sprintf(`(%[1]s.owner = $1 or %[1]s.public = true or exists(select 1 from document_grants g
+		where g.document_id = %[1]s.id and (g.user_id = $1 or g.group_id = any($2))))`, table)
+}
+
+// grantList scans the grants selected by grantsOf together with the IDs of
+// their grantees, which are left out of the JSON form of domain.Grant.
+type grantList []domain.Grant
+
+func (l *grantList) UnmarshalJSON(data []byte) error {
+	var rows []struct {
+		Grantee string `json:"grantee"`
+		Role    string `json:"role"`
+		UserID  string `json:"user_id"`
+		GroupID string `json:"group_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+
+	*l = make(grantList, len(rows))
+	for i, row := range rows {
+		(*l)[i] = domain.Grant{Grantee: row.Grantee, Role: row.Role, UserID: row.UserID, GroupID: row.GroupID}
+	}
+	return nil
+}
+
+// CreateDocument stores the document together with its first version.
 func (r *documentRepository) CreateDocument(ctx context.Context, doc *domain.Document) error {
-	sql := `
-	insert into documents (id, name, mime, file, public, created, grant_list, owner, data, json)
-	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `
+		insert into documents (id, name, mime, file, public, created, updated, version, owner, storage_key, size, checksum, json, folder_id, content_text)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		`
 
-	_, err := r.pool.Exec(ctx, sql, doc.ID, doc.Name, doc.Mime, doc.File, doc.Public,
-		doc.Created, doc.Grant, doc.Owner, doc.Data, doc.JSON)
-	return err
+		_, err := tx.Exec(ctx, sql, doc.ID, doc.Name, doc.Mime, doc.File, doc.Public, doc.Created, doc.Updated,
+			doc.Version, doc.Owner, nullable(doc.StorageKey), doc.Size, nullable(doc.Checksum), doc.JSON,
+			nullable(doc.FolderID), doc.ContentText)
+		if err != nil {
+			return err
+		}
+
+		if err := replaceGrants(ctx, tx, doc.ID, doc.Grant); err != nil {
+			return err
+		}
+
+		return insertVersion(ctx, tx, &domain.DocumentVersion{
+			DocumentID:  doc.ID,
+			Version:     doc.Version,
+			Mime:        doc.Mime,
+			Size:        doc.Size,
+			Author:      doc.Owner,
+			Created:     doc.Created,
+			StorageKey:  doc.StorageKey,
+			Checksum:    doc.Checksum,
+			JSON:        doc.JSON,
+			ContentText: doc.ContentText,
+		})
+	})
 }
 
 func (r *documentRepository) GetDocumentByID(ctx context.Context, id string) (*domain.Document, error) {
 	sql := `
 	select
 		id, name, mime, file, public,
-	    created, grant_list, owner, data, json
+	    created, updated, version, ` + grantsOf("documents") + `, owner, coalesce(storage_key, ''),
+	    size, coalesce(checksum, ''), coalesce(json, ''), coalesce(folder_id, '')
 	from documents 
 	where id = $1
 	`
@@ -39,8 +108,11 @@ func (r *documentRepository) GetDocumentByID(ctx context.Context, id string) (*d
 	row := r.pool.QueryRow(ctx, sql, id)
 
 	var doc domain.Document
-	err := row.Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public, &doc.Created,
-		&doc.Grant, &doc.Owner, &doc.Data, &doc.JSON)
+	err := row.Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public, &doc.Created, &doc.Updated,
+		&doc.Version, (*grantList)(&doc.Grant), &doc.Owner, &doc.StorageKey, &doc.Size, &doc.Checksum, &doc.JSON, &doc.FolderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -48,41 +120,235 @@ func (r *documentRepository) GetDocumentByID(ctx context.Context, id string) (*d
 	return &doc, nil
 }
 
-func (r *documentRepository) GetUserDocuments(ctx context.Context, ownerID string, limit int) ([]domain.Document, error) {
+// GetUserDocuments returns a page of the documents visible to the user. Pages
+// are keyset paginated on the sort column and the ID, so documents added or
+// removed between requests do not shift later pages.
+func (r *documentRepository) GetUserDocuments(ctx context.Context, userID string, groupIDs []string, opts domain.ListOptions) (*domain.DocumentPage, error) {
+	column, ok := sortColumns[opts.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", opts.Sort)
+	}
+
+	direction, compare := "asc", ">"
+	if opts.Desc {
+		direction, compare = "desc", "<"
+	}
+
+	where := visibleTo("documents")
+	args := []any{userID, groupIDs}
+
+	if opts.Filter != nil {
+		condition, err := compileFilter(opts.Filter, &args)
+		if err != nil {
+			return nil, err
+		}
+		where += " and " + condition
+	}
+
+	page := &domain.DocumentPage{Docs: []domain.Document{}}
+
+	if opts.Total {
+		var total int
+		if err := r.pool.QueryRow(ctx, "select count(*) from documents where "+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	if opts.Cursor != "" {
+		value, id, err := decodeCursor(opts)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value, id)
+		where += fmt.Sprintf(" and (%s, id) %s ($%d, $%d)", column, compare, len(args)-1, len(args))
+	}
+
+	sql := fmt.Sprintf(`
+	select id, name, mime, file, public, created, updated, version, %s, size, coalesce(folder_id, '')
+	from documents
+	where %s
+	order by %s %s, id %s
+	limit %d
+	`, grantsOf("documents"), where, column, direction, direction, opts.Limit+1)
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc domain.Document
+		err := rows.Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public, &doc.Created, &doc.Updated,
+			&doc.Version, (*grantList)(&doc.Grant), &doc.Size, &doc.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		page.Docs = append(page.Docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// One row more than the limit is read to learn whether a next page exists.
+	if len(page.Docs) > opts.Limit {
+		page.Docs = page.Docs[:opts.Limit]
+		page.NextCursor = encodeCursor(opts, &page.Docs[opts.Limit-1])
+	}
+
+	return page, nil
+}
+
+// SearchDocuments runs a web search style query (quoted phrases, or, -term)
+// over the name, JSON content and text of the documents visible to the user,
+// best matches first. Snippets are only built for the returned page, from the
+// same leading part of the content that is indexed. The content is HTML
+// escaped first, so the <b> tags around matches are the only markup in them.
+func (r *documentRepository) SearchDocuments(ctx context.Context, userID string, groupIDs []string, query string, limit int) ([]domain.SearchResult, error) {
+	sql := `
+	select id, name, mime, file, public, created, updated, version, ` + grantsOf("matches") + `, size, coalesce(folder_id, ''), rank,
+	       ts_headline('simple', ` + htmlEscaped(`concat_ws(' ', name, left(json, 100000), left(content_text, 100000))`) + `,
+	                   query, 'MaxFragments=2, MinWords=5, MaxWords=20, StartSel=<b>, StopSel=</b>')
+	from (
+		select d.*, ts_rank(search, query) as rank, query
+		from documents d, websearch_to_tsquery('simple', $3) query
+		where search @@ query and ` + visibleTo("d") + `
+		order by rank desc, created desc
+		limit $4
+	) matches
+	order by rank desc, created desc
+	`
+
+	rows, err := r.pool.Query(ctx, sql, userID, groupIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domain.SearchResult{}
+	for rows.Next() {
+		var res domain.SearchResult
+		err := rows.Scan(&res.ID, &res.Name, &res.Mime, &res.File, &res.Public, &res.Created, &res.Updated,
+			&res.Version, (*grantList)(&res.Grant), &res.Size, &res.FolderID, &res.Rank, &res.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	return results, rows.Err()
+}
+
+// htmlEscaped wraps a text expression to escape the characters HTML treats
+// specially.
+func htmlEscaped(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// GetFolderDocuments lists the owner's documents directly inside the folder;
+// an empty folderID lists the root.
+func (r *documentRepository) GetFolderDocuments(ctx context.Context, owner, folderID string) ([]domain.Document, error) {
 	sql := `
-	select id, name, mime, file, public, created, grant_list
+	select id, name, mime, file, public, created, updated, version, ` + grantsOf("documents") + `, size, coalesce(folder_id, '')
 	from documents
-	where (owner = $1 or $1 = any(grant_list) or public = true)
-	order by name, created limit $2
+	where owner = $1 and folder_id is not distinct from $2
+	order by name, created
 	`
 
-	rows, err := r.pool.Query(ctx, sql, ownerID, limit)
+	rows, err := r.pool.Query(ctx, sql, owner, nullable(folderID))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var docs []domain.Document
+	docs := []domain.Document{}
 	for rows.Next() {
 		var doc domain.Document
-		err := rows.Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public, &doc.Created, &doc.Grant)
+		err := rows.Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public, &doc.Created, &doc.Updated,
+			&doc.Version, (*grantList)(&doc.Grant), &doc.Size, &doc.FolderID)
 		if err != nil {
 			return nil, err
 		}
 		docs = append(docs, doc)
 	}
 
-	return docs, nil
+	return docs, rows.Err()
 }
 
-func (r *documentRepository) DeleteDocument(ctx context.Context, id, owner string) error {
+// GetDocumentByName finds the owner's document with the given name inside
+// the folder. Names are not unique, so the most recently created one wins.
+func (r *documentRepository) GetDocumentByName(ctx context.Context, owner, folderID, name string) (*domain.Document, error) {
+	sql := `
+	select id
+	from documents
+	where owner = $1 and folder_id is not distinct from $2 and name = $3
+	order by created desc
+	limit 1
+	`
+
+	var id string
+	err := r.pool.QueryRow(ctx, sql, owner, nullable(folderID), name).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetDocumentByID(ctx, id)
+}
+
+// DeleteDocument deletes the document with its grants; who may do so is
+// checked by the caller.
+func (r *documentRepository) DeleteDocument(ctx context.Context, id string) (*domain.Document, error) {
 	sql := `
 	delete from documents
-    where id = $1 and owner = $2
+    where id = $1
+	returning id, file, public, owner, coalesce(storage_key, '')
 	`
 
-	_, err := r.pool.Exec(ctx, sql, id, owner)
-	return err
+	var doc domain.Document
+	err := r.pool.QueryRow(ctx, sql, id).Scan(&doc.ID, &doc.File, &doc.Public, &doc.Owner, &doc.StorageKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// UpdateDocumentMeta stores the name, mime, public flag, grants and folder of
+// doc. The mime type of the current version is kept in sync.
+func (r *documentRepository) UpdateDocumentMeta(ctx context.Context, doc *domain.Document) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `
+		update documents
+		set name = $2, mime = $3, public = $4, folder_id = $5
+		where id = $1
+		returning version
+		`
+
+		var version int
+		err := tx.QueryRow(ctx, sql, doc.ID, doc.Name, doc.Mime, doc.Public, nullable(doc.FolderID)).Scan(&version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := replaceGrants(ctx, tx, doc.ID, doc.Grant); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `update document_versions set mime = $3 where document_id = $1 and version = $2`,
+			doc.ID, version, doc.Mime)
+		return err
+	})
 }
 
 func (r *documentRepository) DocumentExists(ctx context.Context, id string) (bool, error) {
@@ -94,3 +360,119 @@ func (r *documentRepository) DocumentExists(ctx context.Context, id string) (boo
 	err := r.pool.QueryRow(ctx, sql, id).Scan(&exists)
 	return exists, err
 }
+
+// AddVersion appends a version to the document and makes it the current
+// content. The assigned version number is stored in version.Version.
+func (r *documentRepository) AddVersion(ctx context.Context, version *domain.DocumentVersion) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var current int
+		err := tx.QueryRow(ctx, `select version from documents where id = $1 for update`, version.DocumentID).Scan(&current)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		version.Version = current + 1
+		if err := insertVersion(ctx, tx, version); err != nil {
+			return err
+		}
+
+		sql := `
+		update documents
+		set version = $2, updated = $3, mime = $4, storage_key = $5, size = $6, checksum = $7, json = $8,
+		    content_text = $9
+		where id = $1
+		`
+
+		_, err = tx.Exec(ctx, sql, version.DocumentID, version.Version, version.Created, version.Mime,
+			nullable(version.StorageKey), version.Size, nullable(version.Checksum), version.JSON, version.ContentText)
+		return err
+	})
+}
+
+func (r *documentRepository) GetVersions(ctx context.Context, docID string) ([]domain.DocumentVersion, error) {
+	sql := `
+	select document_id, version, coalesce(mime, ''), coalesce(storage_key, ''),
+	       size, coalesce(checksum, ''), author, created
+	from document_versions
+	where document_id = $1
+	order by version
+	`
+
+	rows, err := r.pool.Query(ctx, sql, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []domain.DocumentVersion
+	for rows.Next() {
+		var v domain.DocumentVersion
+		err := rows.Scan(&v.DocumentID, &v.Version, &v.Mime, &v.StorageKey, &v.Size, &v.Checksum, &v.Author, &v.Created)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+func (r *documentRepository) GetVersion(ctx context.Context, docID string, version int) (*domain.DocumentVersion, error) {
+	sql := `
+	select document_id, version, coalesce(mime, ''), coalesce(storage_key, ''),
+	       size, coalesce(checksum, ''), coalesce(json, ''), coalesce(content_text, ''), author, created
+	from document_versions
+	where document_id = $1 and version = $2
+	`
+
+	var v domain.DocumentVersion
+	err := r.pool.QueryRow(ctx, sql, docID, version).Scan(&v.DocumentID, &v.Version, &v.Mime,
+		&v.StorageKey, &v.Size, &v.Checksum, &v.JSON, &v.ContentText, &v.Author, &v.Created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func insertVersion(ctx context.Context, tx pgx.Tx, v *domain.DocumentVersion) error {
+	sql := `
+	insert into document_versions (document_id, version, mime, storage_key, size, checksum, json, content_text, author, created)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := tx.Exec(ctx, sql, v.DocumentID, v.Version, v.Mime, nullable(v.StorageKey), v.Size,
+		nullable(v.Checksum), v.JSON, v.ContentText, v.Author, v.Created)
+	return err
+}
+
+func replaceGrants(ctx context.Context, tx pgx.Tx, docID string, grants []domain.Grant) error {
+	if _, err := tx.Exec(ctx, `delete from document_grants where document_id = $1`, docID); err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		sql := `
+		insert into document_grants (document_id, user_id, group_id, role)
+		values ($1, $2, $3, $4)
+		`
+
+		if _, err := tx.Exec(ctx, sql, docID, nullable(grant.UserID), nullable(grant.GroupID), grant.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mibrgmv/document-service/pkg/filter"
+)
+
+// filterColumns maps the fields of document filters to columns.
+var filterColumns = map[string]string{
+	"name":    "name",
+	"mime":    "coalesce(mime, '')",
+	"file":    "file",
+	"public":  "public",
+	"created": "created",
+	"updated": "updated",
+	"size":    "size",
+	"version": "version",
+}
+
+// compileFilter turns a validated filter into a SQL condition. Values are
+// appended to args and referenced as parameters.
+func compileFilter(expr filter.Expr, args *[]any) (string, error) {
+	switch e := expr.(type) {
+	case *filter.Logical:
+		if e.Op != "and" && e.Op != "or" {
+			return "", fmt.Errorf("unknown logical operator %q", e.Op)
+		}
+		left, err := compileFilter(e.Left, args)
+		if err != nil {
+			return "", err
+		}
+		right, err := compileFilter(e.Right, args)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + e.Op + " " + right + ")", nil
+
+	case *filter.Not:
+		inner, err := compileFilter(e.Expr, args)
+		if err != nil {
+			return "", err
+		}
+		return "not (" + inner + ")", nil
+
+	case *filter.Comparison:
+		return compileComparison(e, args)
+	}
+
+	return "", fmt.Errorf("unknown filter node %T", expr)
+}
+
+func compileComparison(e *filter.Comparison, args *[]any) (string, error) {
+	column, ok := filterColumns[e.Field]
+	if !ok {
+		return "", fmt.Errorf("unknown filter field %q", e.Field)
+	}
+
+	param := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	v := e.Value
+	switch e.Op {
+	case filter.Match:
+		return fmt.Sprintf(`%s like %s escape '\'`, column, param(globToLike(v.Str))), nil
+	case filter.NotMatch:
+		return fmt.Sprintf(`%s not like %s escape '\'`, column, param(globToLike(v.Str))), nil
+	case filter.Eq, filter.NotEq, filter.Less, filter.LessEq, filter.Greater, filter.GreaterEq:
+	default:
+		return "", fmt.Errorf("unknown filter operator %q", e.Op)
+	}
+
+	var value any
+	switch v.Kind {
+	case filter.String:
+		value = v.Str
+	case filter.Number:
+		value = v.Num
+	case filter.Bool:
+		value = v.Bool
+	case filter.Time:
+		value = v.Time
+	}
+
+	// A calendar date stands for the whole day.
+	if v.Kind == filter.Time && v.DateOnly {
+		next := v.Time.AddDate(0, 0, 1)
+		switch e.Op {
+		case filter.Eq:
+			return fmt.Sprintf("(%s >= %s and %s < %s)", column, param(v.Time), column, param(next)), nil
+		case filter.NotEq:
+			return fmt.Sprintf("(%s < %s or %s >= %s)", column, param(v.Time), column, param(next)), nil
+		case filter.Greater:
+			return fmt.Sprintf("%s >= %s", column, param(next)), nil
+		case filter.LessEq:
+			return fmt.Sprintf("%s < %s", column, param(next)), nil
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s", column, e.Op, param(value)), nil
+}
+
+// globToLike converts a glob using * and ? into a LIKE pattern, escaping the
+// characters LIKE treats specially.
+func globToLike(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
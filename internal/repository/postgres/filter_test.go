@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilter(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name  string
+		input string
+		sql   string
+		args  []any
+	}{
+		{"string", `name = "report"`, `name = $1`, []any{"report"}},
+		{"nullable column", `mime != "text/plain"`, `coalesce(mime, '') != $1`, []any{"text/plain"}},
+		{"number", `size >= 1024`, `size >= $1`, []any{int64(1024)}},
+		{"boolean", `public = true`, `public = $1`, []any{true}},
+		{"glob", `mime ~ "image/*"`, `coalesce(mime, '') like $1 escape '\'`, []any{"image/%"}},
+		{"negated glob", `name !~ "draft-?"`, `name not like $1 escape '\'`, []any{"draft-_"}},
+		{"like characters are escaped", `name ~ "100%_a\\b*"`, `name like $1 escape '\'`, []any{`100\%\_a\\b%`}},
+		{"quote in value stays a parameter", `name = "x' or '1'='1"`, `name = $1`, []any{`x' or '1'='1`}},
+		{
+			"precedence and numbering",
+			`size > 10 or public = true and not file = false`,
+			`(size > $1 or (public = $2 and not (file = $3)))`,
+			[]any{int64(10), true, false},
+		},
+		{
+			"parentheses",
+			`(size > 10 or public = true) and version = 2`,
+			`((size > $1 or public = $2) and version = $3)`,
+			[]any{int64(10), true, int64(2)},
+		},
+		{"date equals the whole day", `created = 2026-01-01`, `(created >= $1 and created < $2)`, []any{day, next}},
+		{"date not equal", `created != 2026-01-01`, `(created < $1 or created >= $2)`, []any{day, next}},
+		{"after a date", `updated > 2026-01-01`, `updated >= $1`, []any{next}},
+		{"up to a date", `updated <= 2026-01-01`, `updated < $1`, []any{next}},
+		{"before a date", `updated < 2026-01-01`, `updated < $1`, []any{day}},
+		{"from a date", `updated >= 2026-01-01`, `updated >= $1`, []any{day}},
+		{"time", `created < 2026-01-01T10:00:00Z`, `created < $1`, []any{day.Add(10 * time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := filter.Parse(tt.input)
+			require.NoError(t, err)
+
+			var args []any
+			sql, err := compileFilter(expr, &args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sql, sql)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestCompileFilter_ContinuesParameters(t *testing.T) {
+	expr, err := filter.Parse(`name = "a" and size < 5`)
+	require.NoError(t, err)
+
+	args := []any{"user1", []string{"group1"}}
+	sql, err := compileFilter(expr, &args)
+
+	require.NoError(t, err)
+	assert.Equal(t, `(name = $3 and size < $4)`, sql)
+	assert.Equal(t, []any{"user1", []string{"group1"}, "a", int64(5)}, args)
+}
+
+func TestCompileFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr filter.Expr
+		msg  string
+	}{
+		{"unknown field", &filter.Comparison{Field: "owner", Op: filter.Eq}, `unknown filter field "owner"`},
+		{"unknown operator", &filter.Comparison{Field: "name", Op: "<>"}, `unknown filter operator "<>"`},
+		{
+			"unknown logical operator",
+			&filter.Logical{Op: "xor", Left: &filter.Comparison{Field: "name"}, Right: &filter.Comparison{Field: "name"}},
+			`unknown logical operator "xor"`,
+		},
+		{"unknown field below not", &filter.Not{Expr: &filter.Comparison{Field: "owner", Op: filter.Eq}}, `unknown filter field "owner"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			_, err := compileFilter(tt.expr, &args)
+			assert.ErrorContains(t, err, tt.msg)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type folderRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewFolderRepository(pool *pgxpool.Pool) repository.FolderRepository {
+	return &folderRepository{pool: pool}
+}
+
+// CreateFolder stores a new folder. It fails with repository.ErrConflict when
+// the parent already has a folder with the same name.
+func (r *folderRepository) CreateFolder(ctx context.Context, folder *domain.Folder) error {
+	sql := `
+	insert into folders (id, name, parent_id, owner, created, updated)
+	values ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.pool.Exec(ctx, sql, folder.ID, folder.Name, nullable(folder.ParentID), folder.Owner,
+		folder.Created, folder.Updated)
+	return folderError(err)
+}
+
+func (r *folderRepository) GetFolderByID(ctx context.Context, id string) (*domain.Folder, error) {
+	sql := `
+	select id, name, coalesce(parent_id, ''), owner, created, updated
+	from folders
+	where id = $1
+	`
+
+	return scanFolder(r.pool.QueryRow(ctx, sql, id))
+}
+
+func (r *folderRepository) GetFolderByName(ctx context.Context, owner, parentID, name string) (*domain.Folder, error) {
+	sql := `
+	select id, name, coalesce(parent_id, ''), owner, created, updated
+	from folders
+	where owner = $1 and parent_id is not distinct from $2 and name = $3
+	`
+
+	return scanFolder(r.pool.QueryRow(ctx, sql, owner, nullable(parentID), name))
+}
+
+func (r *folderRepository) GetSubfolders(ctx context.Context, owner, parentID string) ([]domain.Folder, error) {
+	sql := `
+	select id, name, coalesce(parent_id, ''), owner, created, updated
+	from folders
+	where owner = $1 and parent_id is not distinct from $2
+	order by name
+	`
+
+	rows, err := r.pool.Query(ctx, sql, owner, nullable(parentID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []domain.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// UpdateFolder stores the name and parent of the folder. Moves that would
+// place a folder inside itself fail with repository.ErrCycle; moves of one
+// owner's folders are serialized so that two concurrent moves cannot create
+// a cycle together.
+func (r *folderRepository) UpdateFolder(ctx context.Context, folder *domain.Folder) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1))`, folder.Owner); err != nil {
+			return err
+		}
+
+		if folder.ParentID != "" {
+			sql := `
+			with recursive ancestors (id, parent_id) as (
+				select id, parent_id from folders where id = $2
+				union all
+				select f.id, f.parent_id from folders f join ancestors a on f.id = a.parent_id
+			)
+			select exists(select 1 from ancestors where id = $1)
+			`
+
+			var cycle bool
+			if err := tx.QueryRow(ctx, sql, folder.ID, folder.ParentID).Scan(&cycle); err != nil {
+				return err
+			}
+			if cycle {
+				return repository.ErrCycle
+			}
+		}
+
+		sql := `
+		update folders
+		set name = $2, parent_id = $3, updated = $4
+		where id = $1
+		`
+
+		tag, err := tx.Exec(ctx, sql, folder.ID, folder.Name, nullable(folder.ParentID), folder.Updated)
+		if err != nil {
+			return folderError(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteFolder removes an empty folder. It fails with repository.ErrNotEmpty
+// while the folder still contains documents or folders.
+func (r *folderRepository) DeleteFolder(ctx context.Context, id, owner string) error {
+	sql := `
+	delete from folders
+	where id = $1 and owner = $2
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, id, owner)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotEmpty
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func scanFolder(row pgx.Row) (*domain.Folder, error) {
+	var folder domain.Folder
+	err := row.Scan(&folder.ID, &folder.Name, &folder.ParentID, &folder.Owner, &folder.Created, &folder.Updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// folderError reports a name clash between siblings as repository.ErrConflict.
+func folderError(err error) error {
+	if errorCode(err) == uniqueViolation {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+func errorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type groupRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewGroupRepository(pool *pgxpool.Pool) repository.GroupRepository {
+	return &groupRepository{pool: pool}
+}
+
+func (r *groupRepository) CreateGroup(ctx context.Context, group *domain.Group) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `
+		insert into user_groups (id, name, owner, created)
+		values ($1, $2, $3, $4)
+		`
+
+		_, err := tx.Exec(ctx, sql, group.ID, group.Name, group.Owner, group.Created)
+		if errorCode(err) == uniqueViolation {
+			return repository.ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, member := range group.Members {
+			_, err := tx.Exec(ctx, `insert into group_members (group_id, user_id) values ($1, $2)`, group.ID, member.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *groupRepository) GetGroupByName(ctx context.Context, name string) (*domain.Group, error) {
+	sql := `
+	select g.id, g.name, g.owner, g.created,
+	       coalesce(array_agg(u.id order by u.login) filter (where u.id is not null), '{}'),
+	       coalesce(array_agg(u.login order by u.login) filter (where u.id is not null), '{}')
+	from user_groups g
+	left join group_members m on m.group_id = g.id
+	left join users u on u.id = m.user_id
+	where g.name = $1
+	group by g.id
+	`
+
+	var group domain.Group
+	var ids, logins []string
+	err := r.pool.QueryRow(ctx, sql, name).Scan(&group.ID, &group.Name, &group.Owner, &group.Created, &ids, &logins)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	group.Members = make([]domain.GroupMember, len(ids))
+	for i := range ids {
+		group.Members[i] = domain.GroupMember{ID: ids[i], Login: logins[i]}
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) GetUserGroups(ctx context.Context, userID string) ([]domain.Group, error) {
+	sql := `
+	select g.id, g.name, g.owner, g.created
+	from user_groups g
+	join group_members m on m.group_id = g.id
+	where m.user_id = $1
+	order by g.name
+	`
+
+	rows, err := r.pool.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []domain.Group{}
+	for rows.Next() {
+		var group domain.Group
+		if err := rows.Scan(&group.ID, &group.Name, &group.Owner, &group.Created); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// AddMember fails with repository.ErrConflict when the user is a member
+// already.
+func (r *groupRepository) AddMember(ctx context.Context, groupID, userID string) error {
+	_, err := r.pool.Exec(ctx, `insert into group_members (group_id, user_id) values ($1, $2)`, groupID, userID)
+	if errorCode(err) == uniqueViolation {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+func (r *groupRepository) RemoveMember(ctx context.Context, groupID, userID string) error {
+	tag, err := r.pool.Exec(ctx, `delete from group_members where group_id = $1 and user_id = $2`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteGroup also removes the grants to the group, which would go with it
+// anyway, to learn which documents they were on.
+func (r *groupRepository) DeleteGroup(ctx context.Context, id string) ([]string, error) {
+	var docIDs []string
+	err := r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `delete from document_grants where group_id = $1 returning document_id`, id)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var docID string
+			if err := rows.Scan(&docID); err != nil {
+				rows.Close()
+				return err
+			}
+			docIDs = append(docIDs, docID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `delete from user_groups where id = $1`, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return docIDs, nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type signingKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSigningKeyRepository(pool *pgxpool.Pool) repository.SigningKeyRepository {
+	return &signingKeyRepository{pool: pool}
+}
+
+func (r *signingKeyRepository) GetSigningKeys(ctx context.Context, now time.Time) ([]domain.SigningKey, error) {
+	sql := `
+	select id, algorithm, private_key, created, retires
+	from signing_keys
+	where retires is null or retires > $1
+	order by created desc
+	`
+
+	rows, err := r.pool.Query(ctx, sql, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.SigningKey
+	for rows.Next() {
+		var key domain.SigningKey
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.Created, &key.Retires); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *signingKeyRepository) RotateSigningKey(ctx context.Context, key *domain.SigningKey, notBefore, retires time.Time) error {
+	return r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// Every instance checks for due rotations; the lock lets only one of
+		// them create the new key.
+		if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext('signing_keys'))`); err != nil {
+			return err
+		}
+
+		sql := `
+		select exists(
+			select 1 from signing_keys
+			where algorithm = $1 and created > $2 and retires is null
+		)
+		`
+
+		var fresh bool
+		if err := tx.QueryRow(ctx, sql, key.Algorithm, notBefore).Scan(&fresh); err != nil {
+			return err
+		}
+		if fresh {
+			return nil
+		}
+
+		sql = `
+		update signing_keys
+		set retires = $1
+		where retires is null
+		`
+
+		if _, err := tx.Exec(ctx, sql, retires); err != nil {
+			return err
+		}
+
+		sql = `
+		insert into signing_keys (id, algorithm, private_key, created)
+		values ($1, $2, $3, $4)
+		`
+
+		_, err := tx.Exec(ctx, sql, key.ID, key.Algorithm, key.PrivateKey, key.Created)
+		return err
+	})
+}
+
+func (r *signingKeyRepository) DeleteRetiredKeys(ctx context.Context, now time.Time) error {
+	sql := `
+	delete from signing_keys
+	where retires < $1
+	`
+
+	_, err := r.pool.Exec(ctx, sql, now)
+	return err
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+// MoveLegacyData copies file contents that are still stored in the
+// documents.data column into the blob store and clears the column. It is
+// safe to run on every start.
+func MoveLegacyData(ctx context.Context, pool *pgxpool.Pool, blobs repository.BlobStore) error {
+	rows, err := pool.Query(ctx, `select id from documents where data is not null`)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		var data []byte
+		if err := pool.QueryRow(ctx, `select data from documents where id = $1`, id).Scan(&data); err != nil {
+			return err
+		}
+
+		info, err := blobs.Put(ctx, utils.GenerateID(), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		sql := `
+		update documents
+		set storage_key = $2, size = $3, checksum = $4, data = null
+		where id = $1
+		`
+
+		if _, err := pool.Exec(ctx, sql, id, info.Key, info.Size, info.Checksum); err != nil {
+			return err
+		}
+
+		sql = `
+		update document_versions
+		set storage_key = $2, size = $3, checksum = $4
+		where document_id = $1 and storage_key is null
+		`
+
+		if _, err := pool.Exec(ctx, sql, id, info.Key, info.Size, info.Checksum); err != nil {
+			return err
+		}
+	}
+
+	if len(ids) > 0 {
+		log.Printf("moved %d legacy documents to blob storage", len(ids))
+	}
+	return nil
+}
+
+// IndexLegacyText extracts the search text of text/* files stored before
+// content_text existed. It is safe to run on every start.
+func IndexLegacyText(ctx context.Context, pool *pgxpool.Pool, blobs repository.BlobStore) error {
+	sql := `
+	select id, version, storage_key
+	from documents
+	where file and mime like 'text/%' and content_text is null and storage_key is not null
+	`
+
+	rows, err := pool.Query(ctx, sql)
+	if err != nil {
+		return err
+	}
+
+	type legacyText struct {
+		id, key string
+		version int
+	}
+
+	var docs []legacyText
+	for rows.Next() {
+		var doc legacyText
+		if err := rows.Scan(&doc.id, &doc.version, &doc.key); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		// A missing blob is indexed as empty rather than blocking the start.
+		var data []byte
+		content, err := blobs.Get(ctx, doc.key)
+		if err == nil {
+			data, err = io.ReadAll(io.LimitReader(content, utils.MaxIndexedText))
+			content.Close()
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		text := utils.PlainText(data)
+		if _, err := pool.Exec(ctx, `update documents set content_text = $2 where id = $1`, doc.id, text); err != nil {
+			return err
+		}
+
+		sql := `
+		update document_versions
+		set content_text = $3
+		where document_id = $1 and version = $2
+		`
+
+		if _, err := pool.Exec(ctx, sql, doc.id, doc.version, text); err != nil {
+			return err
+		}
+	}
+
+	if len(docs) > 0 {
+		log.Printf("indexed text of %d documents for search", len(docs))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type shareLinkRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewShareLinkRepository(pool *pgxpool.Pool) repository.ShareLinkRepository {
+	return &shareLinkRepository{pool: pool}
+}
+
+const linkColumns = `id, document_id, prefix, coalesce(password, ''), max_downloads, downloads, created_by, created, expires, revoked`
+
+func (r *shareLinkRepository) CreateLink(ctx context.Context, link *domain.ShareLink) error {
+	sql := `
+	insert into share_links (id, document_id, prefix, hash, password, max_downloads, created_by, created, expires)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.pool.Exec(ctx, sql, link.ID, link.DocumentID, link.Prefix, link.Hash, nullable(link.Password),
+		link.MaxDownloads, link.CreatedBy, link.Created, link.Expires)
+	return err
+}
+
+func (r *shareLinkRepository) GetLinkByHash(ctx context.Context, hash string) (*domain.ShareLink, error) {
+	sql := `select ` + linkColumns + ` from share_links where hash = $1`
+
+	link, err := scanLink(r.pool.QueryRow(ctx, sql, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (r *shareLinkRepository) GetDocumentLinks(ctx context.Context, docID string) ([]domain.ShareLink, error) {
+	sql := `select ` + linkColumns + ` from share_links where document_id = $1 order by created desc`
+
+	rows, err := r.pool.Query(ctx, sql, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []domain.ShareLink{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+
+	return links, rows.Err()
+}
+
+func (r *shareLinkRepository) RevokeLink(ctx context.Context, docID, id string, at time.Time) error {
+	sql := `
+	update share_links
+	set revoked = $3
+	where id = $2 and document_id = $1 and revoked is null
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, docID, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// UseLink checks the limits and counts the download in one statement, so
+// concurrent downloads cannot go past max_downloads.
+func (r *shareLinkRepository) UseLink(ctx context.Context, id string, now time.Time) (bool, error) {
+	sql := `
+	update share_links
+	set downloads = downloads + 1
+	where id = $1 and revoked is null and (expires is null or expires > $2)
+	  and (max_downloads is null or downloads < max_downloads)
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, id, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *shareLinkRepository) LogAccess(ctx context.Context, access *domain.LinkAccess) error {
+	sql := `
+	insert into share_link_accesses (link_id, time, ip, user_agent, outcome)
+	values ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, sql, access.LinkID, access.Time, access.IP, access.UserAgent, access.Outcome)
+	return err
+}
+
+func (r *shareLinkRepository) GetAccesses(ctx context.Context, linkID string) ([]domain.LinkAccess, error) {
+	sql := `
+	select link_id, time, ip, user_agent, outcome
+	from share_link_accesses
+	where link_id = $1
+	order by time desc, id desc
+	`
+
+	rows, err := r.pool.Query(ctx, sql, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accesses := []domain.LinkAccess{}
+	for rows.Next() {
+		var access domain.LinkAccess
+		if err := rows.Scan(&access.LinkID, &access.Time, &access.IP, &access.UserAgent, &access.Outcome); err != nil {
+			return nil, err
+		}
+		accesses = append(accesses, access)
+	}
+
+	return accesses, rows.Err()
+}
+
+func scanLink(row pgx.Row) (*domain.ShareLink, error) {
+	var link domain.ShareLink
+	err := row.Scan(&link.ID, &link.DocumentID, &link.Prefix, &link.Password, &link.MaxDownloads, &link.Downloads,
+		&link.CreatedBy, &link.Created, &link.Expires, &link.Revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	link.Protected = link.Password != ""
+	return &link, nil
+}
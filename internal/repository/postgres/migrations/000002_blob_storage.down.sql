@@ -0,0 +1,4 @@
+alter table documents
+    drop column if exists checksum,
+    drop column if exists size,
+    drop column if exists storage_key;
@@ -0,0 +1,4 @@
+alter table documents
+    add column if not exists storage_key varchar(255),
+    add column if not exists size        bigint not null default 0,
+    add column if not exists checksum    varchar(64);
@@ -0,0 +1,3 @@
+update documents
+set checksum = null
+where file = false;
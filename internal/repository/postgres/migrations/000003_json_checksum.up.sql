@@ -0,0 +1,3 @@
+update documents
+set checksum = encode(sha256(convert_to(coalesce(json, ''), 'UTF8')), 'hex')
+where file = false and checksum is null;
@@ -0,0 +1,2 @@
+drop index if exists idx_uploads_expires;
+drop table if exists uploads;
@@ -0,0 +1,14 @@
+create table if not exists uploads
+(
+    id            varchar(36) primary key,
+    owner         varchar(36) not null,
+    meta          text        not null,
+    length        bigint      not null,
+    upload_offset bigint      not null default 0,
+    chunks        text[]      not null default '{}',
+    created       timestamp   not null,
+    expires       timestamp   not null,
+    foreign key (owner) references users (id)
+);
+
+create index if not exists idx_uploads_expires on uploads (expires);
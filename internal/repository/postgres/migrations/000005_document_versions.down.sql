@@ -0,0 +1,5 @@
+drop table if exists document_versions;
+
+alter table documents
+    drop column if exists updated,
+    drop column if exists version;
@@ -0,0 +1,28 @@
+alter table documents
+    add column if not exists version integer   not null default 1,
+    add column if not exists updated timestamp;
+
+update documents set updated = created where updated is null;
+
+alter table documents alter column updated set not null;
+
+create table if not exists document_versions
+(
+    document_id varchar(36) not null,
+    version     integer     not null,
+    mime        varchar(100),
+    storage_key varchar(255),
+    size        bigint      not null default 0,
+    checksum    varchar(64),
+    json        text,
+    author      varchar(36) not null,
+    created     timestamp   not null,
+    primary key (document_id, version),
+    foreign key (document_id) references documents (id) on delete cascade,
+    foreign key (author) references users (id)
+);
+
+insert into document_versions (document_id, version, mime, storage_key, size, checksum, json, author, created)
+select id, version, mime, storage_key, size, checksum, json, owner, created
+from documents
+on conflict do nothing;
@@ -0,0 +1,4 @@
+alter table documents
+    drop column if exists folder_id;
+
+drop table if exists folders;
@@ -0,0 +1,19 @@
+create table if not exists folders
+(
+    id        varchar(36) primary key,
+    name      varchar(255) not null,
+    parent_id varchar(36),
+    owner     varchar(36)  not null,
+    created   timestamp    not null,
+    updated   timestamp    not null,
+    foreign key (parent_id) references folders (id),
+    foreign key (owner) references users (id)
+);
+
+create unique index if not exists idx_folders_name on folders (owner, coalesce(parent_id, ''), name);
+create index if not exists idx_folders_parent on folders (parent_id);
+
+alter table documents
+    add column if not exists folder_id varchar(36) references folders (id);
+
+create index if not exists idx_documents_folder on documents (owner, folder_id, name);
@@ -0,0 +1,8 @@
+drop index if exists idx_documents_search;
+
+alter table documents
+    drop column if exists search,
+    drop column if exists content_text;
+
+alter table document_versions
+    drop column if exists content_text;
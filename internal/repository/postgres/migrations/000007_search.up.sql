@@ -0,0 +1,16 @@
+alter table documents
+    add column if not exists content_text text;
+
+alter table document_versions
+    add column if not exists content_text text;
+
+-- A tsvector cannot exceed 1 MB, so only the leading part of the JSON and of
+-- the extracted text is indexed.
+alter table documents
+    add column if not exists search tsvector generated always as (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', left(coalesce(json, ''), 100000)), 'B') ||
+        setweight(to_tsvector('simple', left(coalesce(content_text, ''), 100000)), 'C')
+    ) stored;
+
+create index if not exists idx_documents_search on documents using gin (search);
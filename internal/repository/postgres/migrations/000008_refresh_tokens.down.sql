@@ -0,0 +1,4 @@
+drop index if exists idx_refresh_tokens_expires;
+drop index if exists idx_refresh_tokens_user;
+drop index if exists idx_refresh_tokens_family;
+drop table if exists refresh_tokens;
@@ -0,0 +1,17 @@
+create table if not exists refresh_tokens
+(
+    hash           varchar(64) primary key,
+    family_id      varchar(36) not null,
+    user_id        varchar(36) not null,
+    access_jti     varchar(36) not null,
+    access_expires timestamp   not null,
+    used           boolean     not null default false,
+    revoked        boolean     not null default false,
+    created        timestamp   not null,
+    expires        timestamp   not null,
+    foreign key (user_id) references users (id)
+);
+
+create index if not exists idx_refresh_tokens_family on refresh_tokens (family_id);
+create index if not exists idx_refresh_tokens_user on refresh_tokens (user_id);
+create index if not exists idx_refresh_tokens_expires on refresh_tokens (expires);
@@ -0,0 +1,8 @@
+create table if not exists signing_keys
+(
+    id          varchar(36) primary key,
+    algorithm   varchar(10) not null,
+    private_key text        not null,
+    created     timestamp   not null,
+    retires     timestamp
+);
@@ -0,0 +1,2 @@
+drop index if exists idx_api_keys_user;
+drop table if exists api_keys;
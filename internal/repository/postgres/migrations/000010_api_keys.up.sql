@@ -0,0 +1,15 @@
+create table if not exists api_keys
+(
+    id        varchar(36)  primary key,
+    user_id   varchar(36)  not null,
+    name      varchar(100) not null,
+    prefix    varchar(16)  not null,
+    hash      varchar(64)  not null unique,
+    scopes    text[]       not null default '{}',
+    created   timestamp    not null,
+    expires   timestamp,
+    last_used timestamp,
+    foreign key (user_id) references users (id)
+);
+
+create index if not exists idx_api_keys_user on api_keys (user_id);
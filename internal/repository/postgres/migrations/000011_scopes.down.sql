@@ -0,0 +1,5 @@
+alter table refresh_tokens
+    drop column if exists scopes;
+
+alter table users
+    drop column if exists admin;
@@ -0,0 +1,6 @@
+alter table users
+    add column if not exists admin boolean not null default false;
+
+-- Sessions started before scopes existed keep full document access.
+alter table refresh_tokens
+    add column if not exists scopes text[] not null default '{docs:read,docs:write,docs:delete}';
@@ -0,0 +1,5 @@
+drop index if exists users_oidc_subject_idx;
+
+alter table users
+    drop column if exists oidc_subject,
+    drop column if exists oidc_issuer;
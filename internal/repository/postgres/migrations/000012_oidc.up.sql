@@ -0,0 +1,7 @@
+alter table users
+    add column if not exists oidc_issuer  text,
+    add column if not exists oidc_subject text;
+
+-- Users signed in through an identity provider are matched by issuer and
+-- subject, not by login.
+create unique index if not exists users_oidc_subject_idx on users (oidc_issuer, oidc_subject);
@@ -0,0 +1,14 @@
+-- totp_secret is set on enrollment and only used for login once confirmed;
+-- totp_last_step keeps a code from being used twice.
+alter table users
+    add column if not exists totp_secret    text,
+    add column if not exists totp_enabled   boolean not null default false,
+    add column if not exists totp_last_step bigint  not null default 0;
+
+create table if not exists recovery_codes
+(
+    user_id varchar(36) not null references users (id) on delete cascade,
+    hash    text        not null,
+    used    timestamp,
+    primary key (user_id, hash)
+);
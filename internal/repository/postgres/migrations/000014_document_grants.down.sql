@@ -0,0 +1,8 @@
+alter table documents
+    add column if not exists grant_list text[];
+
+update documents d
+set grant_list = coalesce((select array_agg(grantee order by grantee) from document_grants g where g.document_id = d.id),
+                          '{}');
+
+drop table if exists document_grants;
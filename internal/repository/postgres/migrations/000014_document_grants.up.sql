@@ -0,0 +1,19 @@
+-- Grants replace the grant_list column; the logins in it become viewers.
+create table if not exists document_grants
+(
+    document_id varchar(36) not null references documents (id) on delete cascade,
+    grantee     text        not null,
+    role        text        not null check (role in ('viewer', 'commenter', 'editor', 'co-owner')),
+    primary key (document_id, grantee)
+);
+
+create index if not exists idx_document_grants_grantee on document_grants (grantee);
+
+insert into document_grants (document_id, grantee, role)
+select distinct id, grantee, 'viewer'
+from documents, unnest(grant_list) grantee
+where grantee <> ''
+on conflict do nothing;
+
+alter table documents
+    drop column if exists grant_list;
@@ -0,0 +1,6 @@
+delete from document_grants
+where grantee like 'group:%';
+
+drop index if exists idx_group_members_user;
+drop table if exists group_members;
+drop table if exists user_groups;
@@ -0,0 +1,17 @@
+create table if not exists user_groups
+(
+    id      varchar(36) primary key,
+    name    varchar(50) not null unique,
+    owner   varchar(36) not null,
+    created timestamp   not null,
+    foreign key (owner) references users (id)
+);
+
+create table if not exists group_members
+(
+    group_id varchar(36) not null references user_groups (id) on delete cascade,
+    user_id  varchar(36) not null references users (id),
+    primary key (group_id, user_id)
+);
+
+create index if not exists idx_group_members_user on group_members (user_id);
@@ -0,0 +1,5 @@
+drop index if exists idx_share_link_accesses_link;
+drop table if exists share_link_accesses;
+
+drop index if exists idx_share_links_document;
+drop table if exists share_links;
@@ -0,0 +1,28 @@
+create table if not exists share_links
+(
+    id            varchar(36)  primary key,
+    document_id   varchar(36)  not null references documents (id) on delete cascade,
+    prefix        varchar(16)  not null,
+    hash          varchar(64)  not null unique,
+    password      varchar(255),
+    max_downloads integer,
+    downloads     integer      not null default 0,
+    created_by    varchar(36)  not null references users (id),
+    created       timestamp    not null,
+    expires       timestamp,
+    revoked       timestamp
+);
+
+create index if not exists idx_share_links_document on share_links (document_id);
+
+create table if not exists share_link_accesses
+(
+    id         bigserial   primary key,
+    link_id    varchar(36) not null references share_links (id) on delete cascade,
+    time       timestamp   not null,
+    ip         varchar(45) not null,
+    user_agent text        not null,
+    outcome    varchar(20) not null
+);
+
+create index if not exists idx_share_link_accesses_link on share_link_accesses (link_id, time);
@@ -0,0 +1,20 @@
+drop index if exists idx_document_grants_group_id;
+drop index if exists idx_document_grants_user_id;
+drop index if exists idx_document_grants_group;
+drop index if exists idx_document_grants_user;
+
+alter table document_grants
+    drop constraint if exists document_grants_grantee,
+    add column if not exists grantee text;
+
+update document_grants g
+set grantee = coalesce((select login from users u where u.id = g.user_id),
+                       (select 'group:' || name from user_groups ug where ug.id = g.group_id));
+
+alter table document_grants
+    alter column grantee set not null,
+    drop column if exists user_id,
+    drop column if exists group_id,
+    add primary key (document_id, grantee);
+
+create index if not exists idx_document_grants_grantee on document_grants (grantee);
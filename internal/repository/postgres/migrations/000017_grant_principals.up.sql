@@ -0,0 +1,43 @@
+-- Grants named their grantee by login, or group:<name> for groups, while
+-- owners are user IDs. They now reference the user or the group by ID.
+alter table document_grants
+    add column if not exists user_id  varchar(36) references users (id) on delete cascade,
+    add column if not exists group_id varchar(36) references user_groups (id) on delete cascade;
+
+update document_grants g
+set user_id = u.id
+from users u
+where g.grantee in (u.login, u.id);
+
+update document_grants g
+set group_id = ug.id
+from user_groups ug
+where g.grantee = 'group:' || ug.name;
+
+-- Grants to logins that never existed gave no access and cannot be kept.
+delete from document_grants
+where user_id is null and group_id is null;
+
+-- A user granted a document both by login and by ID now has two grants of it.
+-- Only the one with the highest role is kept.
+delete from document_grants g
+using document_grants other
+where g.document_id = other.document_id
+  and g.user_id = other.user_id
+  and g.ctid <> other.ctid
+  and (array_position(array ['viewer', 'commenter', 'editor', 'co-owner'], g.role), g.ctid)
+    < (array_position(array ['viewer', 'commenter', 'editor', 'co-owner'], other.role), other.ctid);
+
+alter table document_grants
+    drop constraint if exists document_grants_pkey;
+
+drop index if exists idx_document_grants_grantee;
+
+alter table document_grants
+    drop column if exists grantee,
+    add constraint document_grants_grantee check (num_nonnulls(user_id, group_id) = 1);
+
+create unique index if not exists idx_document_grants_user on document_grants (document_id, user_id);
+create unique index if not exists idx_document_grants_group on document_grants (document_id, group_id);
+create index if not exists idx_document_grants_user_id on document_grants (user_id);
+create index if not exists idx_document_grants_group_id on document_grants (group_id);
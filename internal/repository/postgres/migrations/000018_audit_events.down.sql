@@ -0,0 +1,8 @@
+drop trigger if exists audit_events_append_only on audit_events;
+drop function if exists audit_events_append_only();
+
+drop index if exists idx_audit_events_document;
+drop index if exists idx_audit_events_actor;
+drop index if exists idx_audit_events_actor_id;
+drop index if exists idx_audit_events_time;
+drop table if exists audit_events;
@@ -0,0 +1,34 @@
+-- Events outlive the users and documents they mention, so there are no
+-- foreign keys.
+create table if not exists audit_events
+(
+    id          bigserial    primary key,
+    time        timestamp    not null,
+    actor_id    varchar(36),
+    actor       varchar(255),
+    action      varchar(20)  not null,
+    document_id varchar(36),
+    detail      text,
+    ip          varchar(45)  not null,
+    user_agent  text         not null,
+    outcome     varchar(20)  not null
+);
+
+create index if not exists idx_audit_events_time on audit_events (time);
+create index if not exists idx_audit_events_actor_id on audit_events (actor_id, time);
+create index if not exists idx_audit_events_actor on audit_events (actor, time);
+create index if not exists idx_audit_events_document on audit_events (document_id, time);
+
+-- The log is append-only.
+create or replace function audit_events_append_only() returns trigger as
+$$
+begin
+    raise exception 'audit_events is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_events_append_only
+    before update or delete or truncate
+    on audit_events
+    for each statement
+execute function audit_events_append_only();
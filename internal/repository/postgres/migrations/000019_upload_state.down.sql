@@ -0,0 +1,2 @@
+alter table uploads
+    drop column if exists state;
@@ -0,0 +1,4 @@
+-- Completing an upload moves it from uploading to finished first, so only one
+-- of two concurrent requests turns it into a document.
+alter table uploads
+    add column if not exists state varchar(16) not null default 'uploading';
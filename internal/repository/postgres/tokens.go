@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type refreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(pool *pgxpool.Pool) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{pool: pool}
+}
+
+func (r *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	sql := `
+	insert into refresh_tokens (hash, family_id, user_id, access_jti, access_expires, scopes, created, expires)
+	values ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, sql, token.Hash, token.FamilyID, token.UserID, token.AccessJTI,
+		token.AccessExpires, token.Scopes, token.Created, token.Expires)
+	return err
+}
+
+func (r *refreshTokenRepository) UseRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	// The row lock makes concurrent refreshes with the same token see each
+	// other: only the first finds it unused.
+	sql := `
+	update refresh_tokens r
+	set used = true
+	from (
+		select t.hash, t.used, u.login
+		from refresh_tokens t
+		join users u on u.id = t.user_id
+		where t.hash = $1
+		for update of t
+	) old
+	where r.hash = old.hash
+	returning r.hash, r.family_id, r.user_id, old.login, r.access_jti, r.access_expires, r.scopes,
+		old.used, r.revoked, r.created, r.expires
+	`
+
+	var token domain.RefreshToken
+	err := r.pool.QueryRow(ctx, sql, hash).Scan(&token.Hash, &token.FamilyID, &token.UserID, &token.Login,
+		&token.AccessJTI, &token.AccessExpires, &token.Scopes, &token.Used, &token.Revoked, &token.Created, &token.Expires)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error) {
+	sql := `
+	update refresh_tokens
+	set revoked = true
+	where family_id = $1
+	returning hash, family_id, user_id, access_jti, access_expires, used, revoked, created, expires
+	`
+
+	rows, err := r.pool.Query(ctx, sql, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []domain.RefreshToken
+	for rows.Next() {
+		var token domain.RefreshToken
+		err := rows.Scan(&token.Hash, &token.FamilyID, &token.UserID, &token.AccessJTI, &token.AccessExpires,
+			&token.Used, &token.Revoked, &token.Created, &token.Expires)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *refreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	sql := `
+	update refresh_tokens
+	set revoked = true
+	where user_id = $1 and not revoked
+	`
+
+	_, err := r.pool.Exec(ctx, sql, userID)
+	return err
+}
+
+func (r *refreshTokenRepository) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error) {
+	sql := `
+	delete from refresh_tokens
+	where expires < $1
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type uploadRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUploadRepository(pool *pgxpool.Pool) repository.UploadRepository {
+	return &uploadRepository{pool: pool}
+}
+
+func (r *uploadRepository) CreateUpload(ctx context.Context, upload *domain.Upload) error {
+	sql := `
+	insert into uploads (id, owner, meta, length, upload_offset, chunks, state, created, expires)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	meta, err := json.Marshal(upload.Meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pool.Exec(ctx, sql, upload.ID, upload.Owner, string(meta), upload.Length,
+		upload.Offset, upload.Chunks, upload.State, upload.Created, upload.Expires)
+	return err
+}
+
+func (r *uploadRepository) GetUpload(ctx context.Context, id string) (*domain.Upload, error) {
+	sql := `
+	select id, owner, meta, length, upload_offset, chunks, state, created, expires
+	from uploads
+	where id = $1
+	`
+
+	upload, err := scanUpload(r.pool.QueryRow(ctx, sql, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return upload, err
+}
+
+// AppendChunk records a chunk written at offset. It fails with
+// repository.ErrConflict when the stored offset has moved in the meantime.
+func (r *uploadRepository) AppendChunk(ctx context.Context, id string, offset int64, key string, size int64, expires time.Time) error {
+	sql := `
+	update uploads
+	set upload_offset = upload_offset + $3, chunks = array_append(chunks, $4), expires = $5
+	where id = $1 and upload_offset = $2
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, id, offset, size, key, expires)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+// SetUploadState moves the upload from one state to another. It fails with
+// repository.ErrConflict when the upload is not in the from state.
+func (r *uploadRepository) SetUploadState(ctx context.Context, id, from, to string) error {
+	sql := `
+	update uploads
+	set state = $3
+	where id = $1 and state = $2
+	`
+
+	tag, err := r.pool.Exec(ctx, sql, id, from, to)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r *uploadRepository) DeleteUpload(ctx context.Context, id string) error {
+	sql := `
+	delete from uploads
+	where id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, sql, id)
+	return err
+}
+
+func (r *uploadRepository) GetExpiredUploads(ctx context.Context, now time.Time) ([]domain.Upload, error) {
+	sql := `
+	select id, owner, meta, length, upload_offset, chunks, state, created, expires
+	from uploads
+	where expires < $1
+	`
+
+	rows, err := r.pool.Query(ctx, sql, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []domain.Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, *upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+func scanUpload(row pgx.Row) (*domain.Upload, error) {
+	var upload domain.Upload
+	var meta string
+	err := row.Scan(&upload.ID, &upload.Owner, &meta, &upload.Length, &upload.Offset,
+		&upload.Chunks, &upload.State, &upload.Created, &upload.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(meta), &upload.Meta); err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type loginAttemptRepository struct {
+	client *redis.Client
+}
+
+func NewLoginAttemptRepository(client *redis.Client) repository.LoginAttemptRepository {
+	return &loginAttemptRepository{client: client}
+}
+
+func failuresKey(key string) string { return "login_failures:" + key }
+func lockKey(key string) string     { return "login_lock:" + key }
+
+func (r *loginAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL is negative when the key does not exist.
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// FailLogin counts failures in a fixed window that starts with the first one.
+func (r *loginAttemptRepository) FailLogin(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failures, err := r.client.Incr(ctx, failuresKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if failures == 1 {
+		if err := r.client.Expire(ctx, failuresKey(key), window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return failures, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	return r.client.Set(ctx, lockKey(key), 1, duration).Err()
+}
+
+func (r *loginAttemptRepository) ResetLogin(ctx context.Context, key string) error {
+	return r.client.Del(ctx, failuresKey(key), lockKey(key)).Err()
+}
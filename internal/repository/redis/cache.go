@@ -18,27 +18,82 @@ func NewCacheRepository(client *redis.Client) repository.CacheRepository {
 	return &cacheRepository{client: client}
 }
 
-func (r *cacheRepository) SetDocuments(ctx context.Context, key string, docs []domain.Document, expiration time.Duration) error {
-	data, err := json.Marshal(docs)
+// cachedDocument mirrors domain.Document including the fields hidden from API
+// responses, so a cache hit carries everything needed to serve the content.
+type cachedDocument struct {
+	domain.Document
+	Owner      string `json:"owner"`
+	StorageKey string `json:"storage_key"`
+	Checksum   string `json:"checksum"`
+	JSON       string `json:"json"`
+}
+
+func toCached(doc domain.Document) cachedDocument {
+	return cachedDocument{
+		Document:   doc,
+		Owner:      doc.Owner,
+		StorageKey: doc.StorageKey,
+		Checksum:   doc.Checksum,
+		JSON:       doc.JSON,
+	}
+}
+
+func (c cachedDocument) toDomain() domain.Document {
+	doc := c.Document
+	doc.Owner = c.Owner
+	doc.StorageKey = c.StorageKey
+	doc.Checksum = c.Checksum
+	doc.JSON = c.JSON
+	return doc
+}
+
+type cachedPage struct {
+	Docs       []cachedDocument `json:"docs"`
+	NextCursor string           `json:"next_cursor"`
+	Total      *int             `json:"total"`
+}
+
+func (r *cacheRepository) SetDocuments(ctx context.Context, key string, page *domain.DocumentPage, expiration time.Duration) error {
+	cached := cachedPage{
+		Docs:       make([]cachedDocument, 0, len(page.Docs)),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	for _, doc := range page.Docs {
+		cached.Docs = append(cached.Docs, toCached(doc))
+	}
+
+	data, err := json.Marshal(cached)
 	if err != nil {
 		return err
 	}
 	return r.client.Set(ctx, key, data, expiration).Err()
 }
 
-func (r *cacheRepository) GetDocuments(ctx context.Context, key string) ([]domain.Document, error) {
+func (r *cacheRepository) GetDocuments(ctx context.Context, key string) (*domain.DocumentPage, error) {
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, err
 	}
 
-	var docs []domain.Document
-	err = json.Unmarshal(data, &docs)
-	return docs, err
+	var cached cachedPage
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+
+	page := &domain.DocumentPage{
+		Docs:       make([]domain.Document, 0, len(cached.Docs)),
+		NextCursor: cached.NextCursor,
+		Total:      cached.Total,
+	}
+	for _, c := range cached.Docs {
+		page.Docs = append(page.Docs, c.toDomain())
+	}
+	return page, nil
 }
 
 func (r *cacheRepository) SetDocument(ctx context.Context, key string, doc *domain.Document, expiration time.Duration) error {
-	data, err := json.Marshal(doc)
+	data, err := json.Marshal(toCached(*doc))
 	if err != nil {
 		return err
 	}
@@ -51,9 +106,13 @@ func (r *cacheRepository) GetDocument(ctx context.Context, key string) (*domain.
 		return nil, err
 	}
 
-	var doc domain.Document
-	err = json.Unmarshal(data, &doc)
-	return &doc, err
+	var cached cachedDocument
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+
+	doc := cached.toDomain()
+	return &doc, nil
 }
 
 func (r *cacheRepository) Delete(ctx context.Context, key string) error {
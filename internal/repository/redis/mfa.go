@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type mfaChallengeRepository struct {
+	client *redis.Client
+}
+
+func NewMFAChallengeRepository(client *redis.Client) repository.MFAChallengeRepository {
+	return &mfaChallengeRepository{client: client}
+}
+
+func challengeKey(hash string) string         { return "mfa:" + hash }
+func challengeFailuresKey(hash string) string { return "mfa:" + hash + ":failures" }
+
+func (r *mfaChallengeRepository) SaveChallenge(ctx context.Context, hash string, login *domain.PendingLogin, ttl time.Duration) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, challengeKey(hash), data, ttl).Err()
+}
+
+func (r *mfaChallengeRepository) GetChallenge(ctx context.Context, hash string) (*domain.PendingLogin, error) {
+	data, err := r.client.Get(ctx, challengeKey(hash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var login domain.PendingLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (r *mfaChallengeRepository) FailChallenge(ctx context.Context, hash string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, challengeFailuresKey(hash))
+		pipe.Expire(ctx, challengeFailuresKey(hash), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *mfaChallengeRepository) DeleteChallenge(ctx context.Context, hash string) error {
+	return r.client.Del(ctx, challengeKey(hash), challengeFailuresKey(hash)).Err()
+}
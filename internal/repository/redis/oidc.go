@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type oidcStateRepository struct {
+	client *redis.Client
+}
+
+func NewOIDCStateRepository(client *redis.Client) repository.OIDCStateRepository {
+	return &oidcStateRepository{client: client}
+}
+
+func oidcStateKey(state string) string { return "oidc:" + state }
+
+func (r *oidcStateRepository) SaveOIDCState(ctx context.Context, state string, oidcState *domain.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(oidcState)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, oidcStateKey(state), data, ttl).Err()
+}
+
+func (r *oidcStateRepository) TakeOIDCState(ctx context.Context, state string) (*domain.OIDCState, error) {
+	data, err := r.client.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var oidcState domain.OIDCState
+	if err := json.Unmarshal(data, &oidcState); err != nil {
+		return nil, err
+	}
+	return &oidcState, nil
+}
@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type passwordResetRepository struct {
+	client *redis.Client
+}
+
+func NewPasswordResetRepository(client *redis.Client) repository.PasswordResetRepository {
+	return &passwordResetRepository{client: client}
+}
+
+func resetTokenKey(hash string) string { return "password_reset:" + hash }
+
+func (r *passwordResetRepository) SaveResetToken(ctx context.Context, hash, userID string, ttl time.Duration) error {
+	return r.client.Set(ctx, resetTokenKey(hash), userID, ttl).Err()
+}
+
+func (r *passwordResetRepository) TakeResetToken(ctx context.Context, hash string) (string, error) {
+	userID, err := r.client.GetDel(ctx, resetTokenKey(hash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", repository.ErrNotFound
+	}
+	return userID, err
+}
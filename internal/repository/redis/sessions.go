@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+type sessionRepository struct {
+	client *redis.Client
+}
+
+func NewSessionRepository(client *redis.Client) repository.SessionRepository {
+	return &sessionRepository{client: client}
+}
+
+// The sessions of a user are kept in a sorted set scored by expiry; a revoked
+// token has its own key that lives until the token would have expired.
+func sessionsKey(userID string) string { return "sessions:" + userID }
+func revokedKey(jti string) string     { return "revoked:" + jti }
+
+func (r *sessionRepository) AddSession(ctx context.Context, userID, jti string, expiresAt time.Time) error {
+	key := sessionsKey(userID)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(expiresAt.Unix()), Member: jti})
+		pipe.ExpireAt(ctx, key, expiresAt)
+		return nil
+	})
+	return err
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, revokedKey(jti), 1, ttl)
+		pipe.ZRem(ctx, sessionsKey(userID), jti)
+		return nil
+	})
+	return err
+}
+
+func (r *sessionRepository) RevokeSessions(ctx context.Context, userID string) error {
+	key := sessionsKey(userID)
+	now := time.Now()
+
+	sessions, err := r.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	// Only the sessions read above are removed from the set, so a login
+	// racing with this call stays tracked.
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		members := make([]interface{}, 0, len(sessions))
+		for _, session := range sessions {
+			jti := session.Member.(string)
+			pipe.Set(ctx, revokedKey(jti), 1, time.Unix(int64(session.Score), 0).Sub(now))
+			members = append(members, jti)
+		}
+		pipe.ZRem(ctx, key, members...)
+		return nil
+	})
+	return err
+}
+
+func (r *sessionRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, revokedKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/minio/minio-go/v7"
+)
+
+// partSize is the size of the parts an object of unknown length is uploaded
+// in. The client buffers a whole part in memory, and without it picks parts
+// large enough for the biggest object S3 accepts, about 560 MiB each. With at
+// most 10000 parts this still allows objects of 160 GiB.
+const partSize = 16 << 20
+
+type blobStore struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewBlobStore(client *minio.Client, bucket string) repository.BlobStore {
+	return &blobStore{client: client, bucket: bucket}
+}
+
+func (s *blobStore) Put(ctx context.Context, key string, r io.Reader) (*repository.BlobInfo, error) {
+	hash := sha256.New()
+
+	info, err := s.client.PutObject(ctx, s.bucket, key, io.TeeReader(r, hash), -1, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    partSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.BlobInfo{
+		Key:      key,
+		Size:     info.Size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+		Modified: info.LastModified,
+	}, nil
+}
+
+// Get opens the object. The client sends no request until the object is first
+// used, so it is stat'ed here to report a missing key; later reads are pinned
+// to the version seen then.
+func (s *blobStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, objectError(err)
+	}
+
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, objectError(err)
+	}
+	return object, nil
+}
+
+func (s *blobStore) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *blobStore) Stat(ctx context.Context, key string) (*repository.BlobInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, objectError(err)
+	}
+
+	return &repository.BlobInfo{
+		Key:      key,
+		Size:     info.Size,
+		Modified: info.LastModified,
+	}, nil
+}
+
+func objectError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return repository.ErrNotFound
+	}
+	return err
+}
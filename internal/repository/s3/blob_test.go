@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/mibrgmv/document-service/internal/repository/blobtest"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlobStore runs against the MinIO server at TEST_S3_ENDPOINT, e.g. the
+// one from docker-compose with TEST_S3_ENDPOINT=localhost:9000.
+func TestBlobStore(t *testing.T) {
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(envOr("TEST_S3_ACCESS_KEY", "minioadmin"), envOr("TEST_S3_SECRET_KEY", "minioadmin"), ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	bucket := envOr("TEST_S3_BUCKET", "blobtest")
+	exists, err := client.BucketExists(ctx, bucket)
+	require.NoError(t, err)
+	if !exists {
+		require.NoError(t, client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}))
+	}
+
+	// More than two parts, with a short last one.
+	blobtest.Run(t, NewBlobStore(client, bucket), "test-"+utils.GenerateID()+"/", 2*partSize+1<<20)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
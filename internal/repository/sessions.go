@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// SessionRepository tracks the tokens issued to each user so they can be
+// revoked before they expire. Sessions are identified by the token's jti.
+type SessionRepository interface {
+	AddSession(ctx context.Context, userID, jti string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, userID, jti string, expiresAt time.Time) error
+	RevokeSessions(ctx context.Context, userID string) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
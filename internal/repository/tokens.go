@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	// UseRefreshToken marks the token used and returns it as it was before.
+	UseRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	// RevokeFamily revokes every token of the family and returns them.
+	RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error)
+	RevokeUserTokens(ctx context.Context, userID string) error
+	DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error)
+}
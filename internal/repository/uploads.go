@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+type UploadRepository interface {
+	CreateUpload(ctx context.Context, upload *domain.Upload) error
+	GetUpload(ctx context.Context, id string) (*domain.Upload, error)
+	AppendChunk(ctx context.Context, id string, offset int64, key string, size int64, expires time.Time) error
+	SetUploadState(ctx context.Context, id, from, to string) error
+	DeleteUpload(ctx context.Context, id string) error
+	GetExpiredUploads(ctx context.Context, now time.Time) ([]domain.Upload, error)
+}
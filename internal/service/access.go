@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// AccessControl decides what users may do with documents. Owners and grants
+// refer to users and groups by ID; the logins and group names clients use are
+// resolved to IDs when grants are stored.
+type AccessControl struct {
+	userRepo  repository.UserRepository
+	groupRepo repository.GroupRepository
+}
+
+func NewAccessControl(userRepo repository.UserRepository, groupRepo repository.GroupRepository) *AccessControl {
+	return &AccessControl{
+		userRepo:  userRepo,
+		groupRepo: groupRepo,
+	}
+}
+
+// principal is the user a request is made for. Its groups are looked up once,
+// when they are first needed.
+type principal struct {
+	id     string
+	login  string
+	groups []string
+	loaded bool
+}
+
+func (a *AccessControl) principal(userID, login string) *principal {
+	return &principal{id: userID, login: login}
+}
+
+// groups returns the IDs of the groups the principal is a member of.
+func (a *AccessControl) groups(ctx context.Context, p *principal) ([]string, error) {
+	if p.loaded {
+		return p.groups, nil
+	}
+
+	groups, err := a.groupRepo.GetUserGroups(ctx, p.id)
+	if err != nil {
+		return nil, err
+	}
+
+	p.groups = make([]string, len(groups))
+	for i, group := range groups {
+		p.groups[i] = group.ID
+	}
+	p.loaded = true
+	return p.groups, nil
+}
+
+// role returns the role the principal has on the document, or "" if the
+// principal has no access. A user granted access both directly and through
+// groups gets the highest of those roles. Public documents can be read by
+// everyone.
+func (a *AccessControl) role(ctx context.Context, doc *domain.Document, p *principal) (string, error) {
+	if doc.Owner == p.id {
+		return domain.RoleOwner, nil
+	}
+
+	var groups []string
+	if sharedWithGroup(doc.Grant) {
+		var err error
+		if groups, err = a.groups(ctx, p); err != nil {
+			return "", err
+		}
+	}
+
+	role := ""
+	for _, grant := range doc.Grant {
+		granted := grant.UserID != "" && grant.UserID == p.id ||
+			grant.GroupID != "" && contains(groups, grant.GroupID)
+		if granted && (role == "" || domain.RoleAtLeast(grant.Role, role)) {
+			role = grant.Role
+		}
+	}
+
+	if role == "" && doc.Public {
+		return domain.RoleViewer, nil
+	}
+	return role, nil
+}
+
+// authorize fails with ErrAccessDenied unless the principal has at least the
+// role on the document.
+func (a *AccessControl) authorize(ctx context.Context, doc *domain.Document, p *principal, role string) error {
+	actual, err := a.role(ctx, doc, p)
+	if err != nil {
+		return err
+	}
+
+	if !domain.RoleAtLeast(actual, role) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// resolveGrants normalizes the grants and looks up the users and groups they
+// name. Grants to logins or groups that do not exist are rejected.
+func (a *AccessControl) resolveGrants(ctx context.Context, grants []domain.Grant) ([]domain.Grant, error) {
+	grants, err := normalizeGrants(grants)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range grants {
+		grant := &grants[i]
+		grant.UserID, grant.GroupID = "", ""
+
+		if name, ok := domain.GroupName(grant.Grantee); ok {
+			group, err := a.groupRepo.GetGroupByName(ctx, name)
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownGroup, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+			grant.GroupID = group.ID
+			continue
+		}
+
+		user, err := a.userRepo.GetUserByLogin(ctx, grant.Grantee)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownUser, grant.Grantee)
+		}
+		if err != nil {
+			return nil, err
+		}
+		grant.UserID = user.ID
+	}
+	return grants, nil
+}
+
+// audience returns the IDs of the owners of the documents and of the users
+// they are shared with, including the members of the groups they are shared
+// with. It is used to invalidate caches, so groups that cannot be looked up
+// are skipped.
+func (a *AccessControl) audience(ctx context.Context, docs ...*domain.Document) []string {
+	var users []string
+	for _, doc := range docs {
+		users = append(users, doc.Owner)
+		for _, grant := range doc.Grant {
+			if grant.UserID != "" {
+				users = append(users, grant.UserID)
+				continue
+			}
+			name, ok := domain.GroupName(grant.Grantee)
+			if !ok || grant.GroupID == "" {
+				continue
+			}
+			if group, err := a.groupRepo.GetGroupByName(ctx, name); err == nil {
+				for _, member := range group.Members {
+					users = append(users, member.ID)
+				}
+			}
+		}
+	}
+	return users
+}
+
+// normalizeGrants drops grants without a grantee, makes viewer the default
+// role and keeps one grant per grantee, the last one given.
+func normalizeGrants(grants []domain.Grant) ([]domain.Grant, error) {
+	result := []domain.Grant{}
+	index := map[string]int{}
+
+	for _, grant := range grants {
+		if grant.Grantee == "" {
+			continue
+		}
+		if grant.Role == "" {
+			grant.Role = domain.RoleViewer
+		}
+		if !domain.ValidRole(grant.Role) {
+			return nil, ErrInvalidRole
+		}
+
+		if i, ok := index[grant.Grantee]; ok {
+			result[i] = grant
+			continue
+		}
+		index[grant.Grantee] = len(result)
+		result = append(result, grant)
+	}
+	return result, nil
+}
+
+func sharedWithGroup(grants []domain.Grant) bool {
+	for _, grant := range grants {
+		if grant.GroupID != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrAPIKeyExpired = fmt.Errorf("%w: expired", ErrInvalidAPIKey)
+	ErrInvalidExpiry = errors.New("expiry must be in the future")
+)
+
+// apiKeyPrefix marks API keys so they are recognizable in configs and by
+// secret scanners.
+const (
+	apiKeyPrefix      = "dsk_"
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID, name string, scopes, held []string, expires *time.Time) (*domain.APIKey, string, error)
+	GetAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, userID string) error
+	ValidateAPIKey(ctx context.Context, key string) (*domain.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+	auditor    *Auditor
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, auditor *Auditor) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		auditor:    auditor,
+	}
+}
+
+// CreateAPIKey stores a new key and returns it together with the key itself,
+// which is not kept and cannot be shown again. The key cannot have scopes
+// beyond held, the scopes of the token it is created with.
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID, name string, scopes, held []string, expires *time.Time) (_ *domain.APIKey, _ string, err error) {
+	name = strings.TrimSpace(name)
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditAccount,
+			Detail: fmt.Sprintf("api key create %s (%s)", name, strings.Join(scopes, " "))}, err)
+	}()
+
+	if name == "" || len(name) > 100 {
+		return nil, "", ErrInvalidName
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one of %s is required", ErrInvalidScope, strings.Join(domain.Scopes, ", "))
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	scopes, err = grantScopes(user, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkHeldScopes(scopes, held); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if expires != nil && !expires.After(now) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+
+	key := &domain.APIKey{
+		ID:      utils.GenerateID(),
+		Name:    name,
+		Prefix:  secret[:apiKeyShownLength],
+		Scopes:  scopes,
+		Created: now,
+		Expires: expires,
+		UserID:  userID,
+		Hash:    utils.Checksum([]byte(secret)),
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+func (s *apiKeyService) GetAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	return s.apiKeyRepo.GetUserAPIKeys(ctx, userID)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id, userID string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditAccount, Detail: "api key revoke " + id}, err)
+	}()
+
+	return s.apiKeyRepo.DeleteAPIKey(ctx, id, userID)
+}
+
+// ValidateAPIKey returns the stored key for a key presented by a client and
+// records that it was used.
+func (s *apiKeyService) ValidateAPIKey(ctx context.Context, key string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	stored, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, utils.Checksum([]byte(key)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.Expires != nil && !stored.Expires.After(now) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	// Failing to record the use is no reason to refuse the request.
+	s.apiKeyRepo.TouchAPIKey(ctx, stored.ID, now)
+
+	stored.Scopes = dropAdmin(stored.Scopes, stored.UserAdmin)
+
+	return stored, nil
+}
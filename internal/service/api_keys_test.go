@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAPIKeyService_CreateAPIKey_Success(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, testAuditor())
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123"}, nil)
+	mockAPIKeyRepo.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("*domain.APIKey")).Return(nil)
+
+	expires := time.Now().Add(24 * time.Hour)
+	key, secret, err := apiKeyService.CreateAPIKey(context.Background(), "user123", " ci ",
+		[]string{domain.ScopeDocsWrite, domain.ScopeDocsRead, domain.ScopeDocsWrite}, domain.DefaultScopes, &expires)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "dsk_"))
+	assert.Equal(t, "ci", key.Name)
+	assert.Equal(t, []string{domain.ScopeDocsWrite, domain.ScopeDocsRead}, key.Scopes)
+	assert.Equal(t, secret[:len(key.Prefix)], key.Prefix)
+	assert.Equal(t, utils.Checksum([]byte(secret)), key.Hash)
+	mockAPIKeyRepo.AssertExpectations(t)
+}
+
+func TestAPIKeyService_CreateAPIKey_Invalid(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, testAuditor())
+
+	past := time.Now().Add(-time.Hour)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123"}, nil)
+
+	_, _, err := apiKeyService.CreateAPIKey(context.Background(), "user123", "", []string{domain.ScopeDocsRead}, domain.Scopes, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidName)
+
+	_, _, err = apiKeyService.CreateAPIKey(context.Background(), "user123", "ci", nil, domain.Scopes, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	_, _, err = apiKeyService.CreateAPIKey(context.Background(), "user123", "ci", []string{"docs:everything"}, domain.Scopes, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	_, _, err = apiKeyService.CreateAPIKey(context.Background(), "user123", "ci", []string{domain.ScopeDocsRead}, domain.Scopes, &past)
+	assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+
+	_, _, err = apiKeyService.CreateAPIKey(context.Background(), "user123", "ci", []string{domain.ScopeAdmin}, domain.Scopes, nil)
+	assert.ErrorIs(t, err, service.ErrScopeNotAllowed)
+
+	mockAPIKeyRepo.AssertNotCalled(t, "CreateAPIKey")
+}
+
+func TestAPIKeyService_CreateAPIKey_BeyondTokenScopes(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, testAuditor())
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123"}, nil)
+
+	_, _, err := apiKeyService.CreateAPIKey(context.Background(), "user123", "ci",
+		[]string{domain.ScopeDocsRead, domain.ScopeDocsWrite}, []string{domain.ScopeDocsRead}, nil)
+
+	assert.ErrorIs(t, err, service.ErrScopeNotAllowed)
+	mockAPIKeyRepo.AssertNotCalled(t, "CreateAPIKey")
+}
+
+func TestAPIKeyService_ValidateAPIKey_Success(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, testAuditor())
+
+	secret := "dsk_abcdefgh"
+	mockAPIKeyRepo.On("GetAPIKeyByHash", mock.Anything, utils.Checksum([]byte(secret))).Return(&domain.APIKey{
+		ID:     "key1",
+		UserID: "user123",
+		Login:  "testuser",
+		Scopes: []string{domain.ScopeDocsWrite, domain.ScopeAdmin},
+	}, nil)
+	mockAPIKeyRepo.On("TouchAPIKey", mock.Anything, "key1", mock.AnythingOfType("time.Time")).Return(nil)
+
+	key, err := apiKeyService.ValidateAPIKey(context.Background(), secret)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", key.UserID)
+	// The user is no longer an admin.
+	assert.Equal(t, []string{domain.ScopeDocsWrite}, key.Scopes)
+	mockAPIKeyRepo.AssertExpectations(t)
+}
+
+func TestAPIKeyService_ValidateAPIKey_Rejected(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, testAuditor())
+
+	expired := time.Now().Add(-time.Minute)
+	mockAPIKeyRepo.On("GetAPIKeyByHash", mock.Anything, utils.Checksum([]byte("dsk_unknown"))).Return(nil, repository.ErrNotFound)
+	mockAPIKeyRepo.On("GetAPIKeyByHash", mock.Anything, utils.Checksum([]byte("dsk_expired"))).Return(&domain.APIKey{
+		ID:      "key1",
+		Expires: &expired,
+	}, nil)
+
+	_, err := apiKeyService.ValidateAPIKey(context.Background(), "not-a-key")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	_, err = apiKeyService.ValidateAPIKey(context.Background(), "dsk_unknown")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	_, err = apiKeyService.ValidateAPIKey(context.Background(), "dsk_expired")
+	assert.ErrorIs(t, err, service.ErrAPIKeyExpired)
+
+	mockAPIKeyRepo.AssertNotCalled(t, "TouchAPIKey")
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+)
+
+var ErrInvalidRange = errors.New("the end of the range must be after its start")
+
+type clientKey struct{}
+
+// WithClient attaches the client a request comes from to its context, so the
+// audit events recorded while serving it say who made it and from where.
+func WithClient(ctx context.Context, client domain.Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFrom returns the client attached to the context by WithClient.
+func ClientFrom(ctx context.Context) domain.Client {
+	client, _ := ctx.Value(clientKey{}).(domain.Client)
+	return client
+}
+
+// Auditor records audit events on behalf of the other services.
+type Auditor struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditor(auditRepo repository.AuditRepository) *Auditor {
+	return &Auditor{auditRepo: auditRepo}
+}
+
+// record stores the event with the client of the request and the outcome of
+// err. Unless the event names an actor, the actor is the user the request is
+// authenticated as.
+func (a *Auditor) record(ctx context.Context, event domain.AuditEvent, err error) {
+	client := ClientFrom(ctx)
+	if event.ActorID == "" && event.Actor == "" {
+		event.ActorID = client.UserID
+	}
+	if event.Actor == "" && event.ActorID == client.UserID {
+		event.Actor = client.Login
+	}
+	event.Time = time.Now()
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+	event.Outcome = auditOutcome(err)
+
+	// The action has happened even if the client has gone away meanwhile, and
+	// failing to record it is no reason to undo it.
+	if err := a.auditRepo.AddEvent(context.WithoutCancel(ctx), &event); err != nil {
+		log.Printf("failed to record audit event %s %q: %v", event.Action, event.Detail, err)
+	}
+}
+
+func auditOutcome(err error) string {
+	switch {
+	case err == nil:
+		return domain.AuditSuccess
+	case errors.Is(err, ErrAccessDenied), errors.Is(err, ErrInvalidCredentials),
+		errors.Is(err, ErrTooManyAttempts), errors.Is(err, ErrInvalidMFAToken),
+		errors.Is(err, ErrInvalidMFACode), errors.Is(err, ErrInvalidLink),
+		errors.Is(err, ErrLinkExpired), errors.Is(err, ErrLinkPassword):
+		return domain.AuditDenied
+	default:
+		return domain.AuditFailed
+	}
+}
+
+type AuditService interface {
+	GetEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error)
+	ExportEvents(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEvent) error) error
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// GetEvents returns a page of events matching the filter, newest first. Pass
+// the ID of the last one as Before to get the following page.
+func (s *auditService) GetEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	if err := checkAuditRange(filter); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	filter.Limit = min(filter.Limit, maxPageSize)
+
+	events := []domain.AuditEvent{}
+	err := s.auditRepo.GetEvents(ctx, filter, func(event *domain.AuditEvent) error {
+		events = append(events, *event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ExportEvents calls fn for every event matching the filter, newest first,
+// without a page limit.
+func (s *auditService) ExportEvents(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEvent) error) error {
+	if err := checkAuditRange(filter); err != nil {
+		return err
+	}
+
+	filter.Limit = 0
+	return s.auditRepo.GetEvents(ctx, filter, fn)
+}
+
+func checkAuditRange(filter domain.AuditFilter) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return ErrInvalidRange
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testAuditor returns an auditor that accepts and drops every event.
+func testAuditor() *service.Auditor {
+	auditRepo := new(mocks.MockAuditRepository)
+	auditRepo.On("AddEvent", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return service.NewAuditor(auditRepo)
+}
+
+// recordedEvents collects the events stored through the repository.
+func recordedEvents(auditRepo *mocks.MockAuditRepository) *[]domain.AuditEvent {
+	events := &[]domain.AuditEvent{}
+	auditRepo.On("AddEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*events = append(*events, *args.Get(1).(*domain.AuditEvent))
+	}).Return(nil)
+	return events
+}
+
+func TestDocumentService_DeleteDocument_Audited(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), testAccess(),
+		service.NewAuditor(mockAuditRepo), mockCacheRepo, new(mocks.MockBlobStore))
+	events := recordedEvents(mockAuditRepo)
+
+	doc := &domain.Document{ID: "123", Owner: "user1"}
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(doc, nil)
+	mockDocRepo.On("GetVersions", mock.Anything, "123").Return([]domain.DocumentVersion{}, nil)
+	mockDocRepo.On("DeleteDocument", mock.Anything, "123").Return(doc, nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := service.WithClient(context.Background(), domain.Client{IP: "10.0.0.1", UserAgent: "curl/8.0"})
+
+	err := docService.DeleteDocument(ctx, "123", "user2", "mallory")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	err = docService.DeleteDocument(ctx, "123", "user1", "alice")
+	assert.NoError(t, err)
+
+	if assert.Len(t, *events, 2) {
+		denied, deleted := (*events)[0], (*events)[1]
+		assert.Equal(t, "mallory", denied.Actor)
+		assert.Equal(t, domain.AuditDenied, denied.Outcome)
+
+		assert.Equal(t, "user1", deleted.ActorID)
+		assert.Equal(t, "alice", deleted.Actor)
+		assert.Equal(t, domain.AuditDelete, deleted.Action)
+		assert.Equal(t, "123", deleted.DocumentID)
+		assert.Equal(t, "10.0.0.1", deleted.IP)
+		assert.Equal(t, "curl/8.0", deleted.UserAgent)
+		assert.Equal(t, domain.AuditSuccess, deleted.Outcome)
+		assert.False(t, deleted.Time.IsZero())
+	}
+}
+
+func TestDocumentService_UploadDocument_AuditedAsRequestUser(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), testAccess(),
+		service.NewAuditor(mockAuditRepo), mockCacheRepo, new(mocks.MockBlobStore))
+	events := recordedEvents(mockAuditRepo)
+
+	mockDocRepo.On("CreateDocument", mock.Anything, mock.Anything).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := service.WithClient(context.Background(), domain.Client{UserID: "user1", Login: "alice"})
+	doc, err := docService.UploadDocument(ctx, &domain.DocumentMeta{Name: "data.json"}, nil, `{}`, "user1")
+	assert.NoError(t, err)
+
+	if assert.Len(t, *events, 1) {
+		assert.Equal(t, "alice", (*events)[0].Actor)
+		assert.Equal(t, domain.AuditUpload, (*events)[0].Action)
+		assert.Equal(t, doc.ID, (*events)[0].DocumentID)
+	}
+}
+
+func TestAuthService_Authenticate_AuditsFailedLogin(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockAttemptRepo := newAttemptRepo()
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	authService := service.NewAuthService(mockUserRepo, nil, nil, nil, nil, mockAttemptRepo, nil,
+		jwt.NewManager(jwtConfig(jwt.HS256, "test-secret")), service.NewAuditor(mockAuditRepo),
+		service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+	events := recordedEvents(mockAuditRepo)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "ghost").Return(nil, service.ErrNotFound)
+	mockAttemptRepo.On("FailLogin", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+
+	_, err := authService.Authenticate(context.Background(), "10.0.0.1", "ghost", "wrong", nil)
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	if assert.Len(t, *events, 1) {
+		assert.Equal(t, "ghost", (*events)[0].Actor)
+		assert.Equal(t, domain.AuditFailedLogin, (*events)[0].Action)
+		assert.Equal(t, domain.AuditDenied, (*events)[0].Outcome)
+	}
+}
+
+func TestGroupService_AddMember_AuditedAsShare(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	groupService := service.NewGroupService(mockGroupRepo, mockUserRepo, service.NewAuditor(mockAuditRepo), mockCacheRepo)
+	events := recordedEvents(mockAuditRepo)
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "newbie").Return(&domain.User{ID: "user3", Login: "newbie"}, nil)
+	mockGroupRepo.On("AddMember", mock.Anything, "g1", "user3").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := service.WithClient(context.Background(), domain.Client{UserID: "owner1", Login: "owner"})
+	_, err := groupService.AddMember(ctx, "finance", "owner1", "newbie")
+	assert.NoError(t, err)
+
+	_, err = groupService.AddMember(ctx, "finance", "user2", "newbie")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	if assert.Len(t, *events, 2) {
+		added, denied := (*events)[0], (*events)[1]
+		assert.Equal(t, "owner", added.Actor)
+		assert.Equal(t, domain.AuditShare, added.Action)
+		assert.Equal(t, "group finance add newbie", added.Detail)
+		assert.Equal(t, domain.AuditSuccess, added.Outcome)
+
+		assert.Equal(t, "user2", denied.ActorID)
+		assert.Equal(t, domain.AuditDenied, denied.Outcome)
+	}
+}
+
+func TestGroupService_DeleteGroup_Audited(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	groupService := service.NewGroupService(mockGroupRepo, new(mocks.MockUserRepository), service.NewAuditor(mockAuditRepo), mockCacheRepo)
+	events := recordedEvents(mockAuditRepo)
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+	mockGroupRepo.On("DeleteGroup", mock.Anything, "g1").Return([]string{}, nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := service.WithClient(context.Background(), domain.Client{UserID: "owner1", Login: "owner"})
+	assert.ErrorIs(t, groupService.DeleteGroup(ctx, "finance", "user2"), service.ErrAccessDenied)
+	assert.NoError(t, groupService.DeleteGroup(ctx, "finance", "owner1"))
+
+	if assert.Len(t, *events, 2) {
+		denied, deleted := (*events)[0], (*events)[1]
+		assert.Equal(t, domain.AuditDenied, denied.Outcome)
+		assert.Equal(t, "owner", deleted.Actor)
+		assert.Equal(t, domain.AuditShare, deleted.Action)
+		assert.Equal(t, "group finance delete", deleted.Detail)
+		assert.Equal(t, domain.AuditSuccess, deleted.Outcome)
+	}
+}
+
+func TestAuditor_RecordsAfterCancel(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, new(mocks.MockUserRepository), service.NewAuditor(mockAuditRepo))
+
+	mockAPIKeyRepo.On("DeleteAPIKey", mock.Anything, "key1", "user1").Return(nil)
+	mockAuditRepo.On("AddEvent", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Err() == nil
+	}), mock.Anything).Return(errors.New("connection refused"))
+
+	// The client hanging up after the key is revoked still gets the event
+	// stored, and a failure to store it does not fail the request.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := apiKeyService.RevokeAPIKey(ctx, "key1", "user1")
+
+	assert.NoError(t, err)
+	mockAuditRepo.AssertNumberOfCalls(t, "AddEvent", 1)
+}
+
+func TestAPIKeyService_RevokeAPIKey_Audited(t *testing.T) {
+	mockAPIKeyRepo := new(mocks.MockAPIKeyRepository)
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	apiKeyService := service.NewAPIKeyService(mockAPIKeyRepo, new(mocks.MockUserRepository), service.NewAuditor(mockAuditRepo))
+	events := recordedEvents(mockAuditRepo)
+
+	mockAPIKeyRepo.On("DeleteAPIKey", mock.Anything, "key1", "user1").Return(nil)
+
+	ctx := service.WithClient(context.Background(), domain.Client{UserID: "user1", Login: "alice"})
+	err := apiKeyService.RevokeAPIKey(ctx, "key1", "user1")
+	assert.NoError(t, err)
+
+	if assert.Len(t, *events, 1) {
+		assert.Equal(t, "alice", (*events)[0].Actor)
+		assert.Equal(t, domain.AuditAccount, (*events)[0].Action)
+		assert.Equal(t, "api key revoke key1", (*events)[0].Detail)
+	}
+}
+
+func TestAuditService_GetEvents(t *testing.T) {
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	auditService := service.NewAuditService(mockAuditRepo)
+
+	mockAuditRepo.On("GetEvents", mock.Anything, domain.AuditFilter{Actor: "alice", Limit: 100}, mock.Anything).
+		Return([]domain.AuditEvent{{ID: 2}, {ID: 1}}, nil)
+
+	events, err := auditService.GetEvents(context.Background(), domain.AuditFilter{Actor: "alice"})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	now := time.Now()
+	_, err = auditService.GetEvents(context.Background(), domain.AuditFilter{From: now, To: now.Add(-time.Hour)})
+	assert.ErrorIs(t, err, service.ErrInvalidRange)
+}
+
+func TestAuditService_ExportEvents_NotPaged(t *testing.T) {
+	mockAuditRepo := new(mocks.MockAuditRepository)
+	auditService := service.NewAuditService(mockAuditRepo)
+
+	mockAuditRepo.On("GetEvents", mock.Anything, domain.AuditFilter{DocumentID: "123"}, mock.Anything).
+		Return([]domain.AuditEvent{{ID: 3}, {ID: 2}, {ID: 1}}, nil)
+
+	var ids []int64
+	err := auditService.ExportEvents(context.Background(), domain.AuditFilter{DocumentID: "123", Limit: 10},
+		func(event *domain.AuditEvent) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2, 1}, ids)
+}
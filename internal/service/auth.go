@@ -13,33 +13,78 @@ import (
 )
 
 type AuthService interface {
-	Register(ctx context.Context, token, login, password string) error
-	Authenticate(ctx context.Context, login, password string) (string, error)
+	Register(ctx context.Context, token, login, password string, admin bool) error
+	Authenticate(ctx context.Context, ip, login, password string, scopes []string) (*domain.AuthResult, error)
+	VerifyMFA(ctx context.Context, ip, mfaToken, code string) (*domain.TokenPair, error)
+	OIDCLoginURL(ctx context.Context, scopes []string) (string, error)
+	OIDCCallback(ctx context.Context, code, state string) (*domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error)
+	ValidateToken(ctx context.Context, token string) (*jwt.Claims, error)
 	Logout(ctx context.Context, token string) error
+	LogoutAll(ctx context.Context, userID string) error
+	UnlockLogin(ctx context.Context, login string) error
+	ChangePassword(ctx context.Context, ip, userID, current, password string, scopes []string) (*domain.TokenPair, error)
+	IssuePasswordReset(ctx context.Context, login string) (*domain.PasswordReset, error)
+	ResetPassword(ctx context.Context, token, password string) error
+	EnrollTOTP(ctx context.Context, userID string) (*domain.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, code string) error
+	ExpireRefreshTokens(ctx context.Context) (int64, error)
 }
 
 type authService struct {
-	userRepo   repository.UserRepository
-	cacheRepo  repository.CacheRepository
-	jwtManager *jwt.Manager
-	adminToken string
+	userRepo      repository.UserRepository
+	sessionRepo   repository.SessionRepository
+	refreshRepo   repository.RefreshTokenRepository
+	oidcStateRepo repository.OIDCStateRepository
+	challengeRepo repository.MFAChallengeRepository
+	attemptRepo   repository.LoginAttemptRepository
+	resetRepo     repository.PasswordResetRepository
+	jwtManager    *jwt.Manager
+	auditor       *Auditor
+	oidc          OIDCConfig
+	throttle      LoginThrottle
+	passwords     PasswordPolicy
+	adminToken    string
 }
 
 func NewAuthService(
 	userRepo repository.UserRepository,
-	cacheRepo repository.CacheRepository,
+	sessionRepo repository.SessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	oidcStateRepo repository.OIDCStateRepository,
+	challengeRepo repository.MFAChallengeRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	resetRepo repository.PasswordResetRepository,
 	jwtManager *jwt.Manager,
+	auditor *Auditor,
+	oidc OIDCConfig,
+	throttle LoginThrottle,
+	passwords PasswordPolicy,
 	adminToken string,
 ) AuthService {
 	return &authService{
-		userRepo:   userRepo,
-		cacheRepo:  cacheRepo,
-		jwtManager: jwtManager,
-		adminToken: adminToken,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		refreshRepo:   refreshRepo,
+		oidcStateRepo: oidcStateRepo,
+		challengeRepo: challengeRepo,
+		attemptRepo:   attemptRepo,
+		resetRepo:     resetRepo,
+		jwtManager:    jwtManager,
+		auditor:       auditor,
+		oidc:          oidc,
+		throttle:      throttle,
+		passwords:     passwords,
+		adminToken:    adminToken,
 	}
 }
 
-func (s *authService) Register(ctx context.Context, token, login, password string) error {
+func (s *authService) Register(ctx context.Context, token, login, password string, admin bool) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{Action: domain.AuditAdmin, Detail: "register " + login}, err)
+	}()
+
 	if token != s.adminToken {
 		return errors.New("invalid admin token")
 	}
@@ -48,8 +93,8 @@ func (s *authService) Register(ctx context.Context, token, login, password strin
 		return errors.New("login must be at least 4 characters long and contain only letters and numbers")
 	}
 
-	if !isValidPassword(password) {
-		return errors.New("password must be at least 4 characters long, contain uppercase and lowercase letter, digit and special character")
+	if err := s.passwords.isValidPassword(password); err != nil {
+		return err
 	}
 
 	exists, err := s.userRepo.UserExists(ctx, login)
@@ -69,32 +114,217 @@ func (s *authService) Register(ctx context.Context, token, login, password strin
 		ID:       utils.GenerateID(),
 		Login:    login,
 		Password: hashedPassword,
+		Admin:    admin,
 		Created:  time.Now(),
 	}
 
 	return s.userRepo.CreateUser(ctx, user)
 }
 
-func (s *authService) Authenticate(ctx context.Context, login, password string) (string, error) {
+// Authenticate issues a token pair limited to the requested scopes, or to
+// domain.DefaultScopes if none are requested. Users with TOTP enabled get an
+// MFA challenge to pass to VerifyMFA instead. Failed attempts are throttled
+// per login and per client IP.
+func (s *authService) Authenticate(ctx context.Context, ip, login, password string, scopes []string) (result *domain.AuthResult, err error) {
+	defer func() {
+		event := domain.AuditEvent{Actor: login, Action: domain.AuditLogin}
+		switch {
+		case err != nil:
+			event.Action = domain.AuditFailedLogin
+		case result.MFA != nil:
+			// VerifyMFA records the login once the second factor is checked.
+			return
+		}
+		s.auditor.record(ctx, event, err)
+	}()
+
+	if err := s.checkThrottle(ctx, ip, login); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetUserByLogin(ctx, login)
+	if errors.Is(err, repository.ErrNotFound) {
+		user = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !checkPassword(user, password) {
+		if err := s.failLogin(ctx, ip, login); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if len(scopes) == 0 {
+		scopes = domain.DefaultScopes
+	}
+	scopes, err = grantScopes(user, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// With TOTP the failures are only reset by VerifyMFA, so a known password
+	// does not lift the throttle on guessing the second factor.
+	if user.TOTPEnabled {
+		challenge, err := s.challenge(ctx, user, scopes)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuthResult{MFA: challenge}, nil
+	}
+
+	if err := s.attemptRepo.ResetLogin(ctx, loginKey(login)); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user.ID, user.Login, utils.GenerateID(), scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AuthResult{TokenPair: tokens}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// can be used once; presenting a used one means it has leaked, so the whole
+// family descending from the same login is revoked.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	stored, err := s.refreshRepo.UseRefreshToken(ctx, jwt.HashRefreshToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.Used {
+		if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+
+	if stored.Revoked || time.Now().After(stored.Expires) {
+		return nil, ErrInvalidToken
+	}
+
+	// The session keeps the scopes it was started with, except admin for a
+	// user who has lost it since.
+	scopes := stored.Scopes
+	if contains(scopes, domain.ScopeAdmin) {
+		user, err := s.userRepo.GetUserByID(ctx, stored.UserID)
+		if err != nil {
+			return nil, err
+		}
+		scopes = dropAdmin(scopes, user.Admin)
+	}
+
+	return s.issueTokens(ctx, stored.UserID, stored.Login, stored.FamilyID, scopes)
+}
+
+func (s *authService) issueTokens(ctx context.Context, userID, login, familyID string, scopes []string) (*domain.TokenPair, error) {
+	token, claims, err := s.jwtManager.GenerateToken(userID, login, familyID, scopes)
+	if err != nil {
+		return nil, err
+	}
+	accessExpires := claims.ExpiresAt.Time
+
+	// A token that is not tracked could not be revoked by LogoutAll.
+	if err := s.sessionRepo.AddSession(ctx, userID, claims.ID, accessExpires); err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, expires := s.jwtManager.GenerateRefreshToken()
+	err = s.refreshRepo.CreateRefreshToken(ctx, &domain.RefreshToken{
+		Hash:          hash,
+		FamilyID:      familyID,
+		UserID:        userID,
+		AccessJTI:     claims.ID,
+		AccessExpires: accessExpires,
+		Scopes:        scopes,
+		Created:       time.Now(),
+		Expires:       expires,
+	})
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(claims.ExpiresAt.Sub(claims.IssuedAt.Time).Seconds()),
+		Scope:        claims.Scope,
+	}, nil
+}
+
+// revokeFamily revokes the refresh tokens of the family together with the
+// access tokens issued alongside them.
+func (s *authService) revokeFamily(ctx context.Context, familyID string) error {
+	tokens, err := s.refreshRepo.RevokeFamily(ctx, familyID)
+	if err != nil {
+		return err
 	}
 
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", errors.New("invalid credentials")
+	for _, token := range tokens {
+		if err := s.sessionRepo.RevokeSession(ctx, token.UserID, token.AccessJTI, token.AccessExpires); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Login)
+// ValidateToken checks the signature and expiry of the token and that it has
+// not been revoked.
+func (s *authService) ValidateToken(ctx context.Context, token string) (*jwt.Claims, error) {
+	claims, err := s.jwtManager.ValidateToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	revoked, err := s.sessionRepo.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
 	}
 
-	return token, nil
+	return claims, nil
 }
 
+// Logout revokes the token until it expires, along with the refresh token
+// family it belongs to.
 func (s *authService) Logout(ctx context.Context, token string) error {
-	return s.cacheRepo.DeletePattern(ctx, "*"+token+"*")
+	claims, err := s.ValidateToken(ctx, token)
+	if errors.Is(err, ErrTokenRevoked) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeSession(ctx, claims.UserID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if claims.SessionID == "" {
+		return nil
+	}
+	return s.revokeFamily(ctx, claims.SessionID)
+}
+
+// LogoutAll revokes every token issued to the user that has not expired yet.
+func (s *authService) LogoutAll(ctx context.Context, userID string) error {
+	if err := s.refreshRepo.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeSessions(ctx, userID)
+}
+
+// ExpireRefreshTokens removes the records of expired refresh tokens and
+// returns how many were removed.
+func (s *authService) ExpireRefreshTokens(ctx context.Context) (int64, error) {
+	return s.refreshRepo.DeleteExpiredRefreshTokens(ctx, time.Now())
 }
 
 func isValidLogin(login string) bool {
@@ -104,16 +334,3 @@ func isValidLogin(login string) bool {
 	matched, _ := regexp.MatchString("^[a-zA-Z0-9]+$", login)
 	return matched
 }
-
-func isValidPassword(password string) bool {
-	if len(password) < 4 {
-		return false
-	}
-
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
-	hasSpecial := regexp.MustCompile(`[^a-zA-Z0-9]`).MatchString(password)
-
-	return hasUpper && hasLower && hasDigit && hasSpecial
-}
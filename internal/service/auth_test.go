@@ -5,19 +5,35 @@ import (
 	"testing"
 	"time"
 
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/service"
 	"github.com/mibrgmv/document-service/internal/service/mocks"
 	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+func jwtConfig(algorithm, secret string) jwt.Config {
+	return jwt.Config{
+		Algorithm:         algorithm,
+		Secret:            secret,
+		Issuer:            "document-service",
+		Audience:          "document-service",
+		Expiration:        15 * time.Minute,
+		RefreshExpiration: 24 * time.Hour,
+		Leeway:            30 * time.Second,
+	}
+}
+
 func TestAuthService_Register_Success(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
-	mockCacheRepo := new(mocks.MockCacheRepository)
-	jwtManager := jwt.NewManager("test-secret", 24*time.Hour)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
 
-	authService := service.NewAuthService(mockUserRepo, mockCacheRepo, jwtManager, "admin-token")
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
 
 	mockUserRepo.On("UserExists", mock.Anything, "testuser").Return(false, nil)
 	mockUserRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*domain.User")).Return(nil)
@@ -26,6 +42,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 		"admin-token",
 		"testuser",
 		"Password123!",
+		false,
 	)
 
 	assert.NoError(t, err)
@@ -34,15 +51,17 @@ func TestAuthService_Register_Success(t *testing.T) {
 
 func TestAuthService_Register_InvalidAdminToken(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
-	mockCacheRepo := new(mocks.MockCacheRepository)
-	jwtManager := jwt.NewManager("test-secret", 24*time.Hour)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
 
-	authService := service.NewAuthService(mockUserRepo, mockCacheRepo, jwtManager, "admin-token")
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
 
 	err := authService.Register(context.Background(),
 		"wrong-token",
 		"testuser",
 		"Password123!",
+		false,
 	)
 
 	assert.Error(t, err)
@@ -51,10 +70,11 @@ func TestAuthService_Register_InvalidAdminToken(t *testing.T) {
 
 func TestAuthService_Register_UserExists(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
-	mockCacheRepo := new(mocks.MockCacheRepository)
-	jwtManager := jwt.NewManager("test-secret", 24*time.Hour)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
 
-	authService := service.NewAuthService(mockUserRepo, mockCacheRepo, jwtManager, "admin-token")
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
 
 	mockUserRepo.On("UserExists", mock.Anything, "existinguser").Return(true, nil)
 
@@ -62,9 +82,315 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 		"admin-token",
 		"existinguser",
 		"Password123!",
+		false,
 	)
 
 	assert.Error(t, err)
 	assert.Equal(t, "user already exists", err.Error())
 	mockUserRepo.AssertExpectations(t)
 }
+
+func TestAuthService_Authenticate_TracksSession(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, newAttemptRepo(), nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(&domain.User{ID: "user123", Login: "testuser", Password: hash}, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	tokens, err := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15*60), tokens.ExpiresIn)
+	claims, err := jwtManager.ValidateToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+	assert.Equal(t, domain.DefaultScopes, claims.Scopes())
+	mockSessionRepo.AssertCalled(t, "AddSession", mock.Anything, "user123", claims.ID, claims.ExpiresAt.Time)
+
+	stored := mockRefreshRepo.Calls[0].Arguments.Get(1).(*domain.RefreshToken)
+	assert.Equal(t, jwt.HashRefreshToken(tokens.RefreshToken), stored.Hash)
+	assert.Equal(t, claims.SessionID, stored.FamilyID)
+	assert.Equal(t, claims.ID, stored.AccessJTI)
+	assert.Equal(t, domain.DefaultScopes, stored.Scopes)
+}
+
+func TestAuthService_Authenticate_Scopes(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, newAttemptRepo(), nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(&domain.User{ID: "user123", Login: "testuser", Password: hash}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "root").Return(&domain.User{ID: "user1", Login: "root", Password: hash, Admin: true}, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	tokens, err := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", []string{domain.ScopeDocsRead, domain.ScopeDocsRead})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ScopeDocsRead, tokens.Scope)
+
+	_, err = authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", []string{"docs:everything"})
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	_, err = authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", []string{domain.ScopeAdmin})
+	assert.ErrorIs(t, err, service.ErrScopeNotAllowed)
+
+	tokens, err = authService.Authenticate(context.Background(), "127.0.0.1", "root", "Password123!", []string{domain.ScopeAdmin})
+	assert.NoError(t, err)
+	claims, err := jwtManager.ValidateToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{domain.ScopeAdmin}, claims.Scopes())
+}
+
+func TestAuthService_Refresh_Rotates(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	mockRefreshRepo.On("UseRefreshToken", mock.Anything, jwt.HashRefreshToken("old-token")).Return(&domain.RefreshToken{
+		FamilyID: "family1",
+		UserID:   "user123",
+		Login:    "testuser",
+		Scopes:   []string{domain.ScopeDocsRead},
+		Expires:  time.Now().Add(time.Hour),
+	}, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token *domain.RefreshToken) bool {
+		return token.FamilyID == "family1" && token.UserID == "user123" && token.Scopes[0] == domain.ScopeDocsRead
+	})).Return(nil)
+
+	tokens, err := authService.Refresh(context.Background(), "old-token")
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, "old-token", tokens.RefreshToken)
+	claims, err := jwtManager.ValidateToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Login)
+	assert.Equal(t, "family1", claims.SessionID)
+	assert.Equal(t, []string{domain.ScopeDocsRead}, claims.Scopes())
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_DropsRevokedAdmin(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	mockRefreshRepo.On("UseRefreshToken", mock.Anything, jwt.HashRefreshToken("old-token")).Return(&domain.RefreshToken{
+		FamilyID: "family1",
+		UserID:   "user123",
+		Login:    "testuser",
+		Scopes:   []string{domain.ScopeDocsRead, domain.ScopeAdmin},
+		Expires:  time.Now().Add(time.Hour),
+	}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123", Login: "testuser"}, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	tokens, err := authService.Refresh(context.Background(), "old-token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ScopeDocsRead, tokens.Scope)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	accessExpires := time.Now().Add(10 * time.Minute)
+	mockRefreshRepo.On("UseRefreshToken", mock.Anything, jwt.HashRefreshToken("stolen")).Return(&domain.RefreshToken{
+		FamilyID: "family1",
+		UserID:   "user123",
+		Used:     true,
+		Expires:  time.Now().Add(time.Hour),
+	}, nil)
+	mockRefreshRepo.On("RevokeFamily", mock.Anything, "family1").Return([]domain.RefreshToken{
+		{FamilyID: "family1", UserID: "user123", AccessJTI: "jti1", AccessExpires: accessExpires},
+		{FamilyID: "family1", UserID: "user123", AccessJTI: "jti2", AccessExpires: accessExpires},
+	}, nil)
+	mockSessionRepo.On("RevokeSession", mock.Anything, "user123", "jti1", accessExpires).Return(nil)
+	mockSessionRepo.On("RevokeSession", mock.Anything, "user123", "jti2", accessExpires).Return(nil)
+
+	_, err := authService.Refresh(context.Background(), "stolen")
+
+	assert.ErrorIs(t, err, service.ErrTokenReused)
+	mockRefreshRepo.AssertExpectations(t)
+	mockSessionRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertNotCalled(t, "CreateRefreshToken")
+}
+
+func TestAuthService_Refresh_Expired(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	mockRefreshRepo.On("UseRefreshToken", mock.Anything, jwt.HashRefreshToken("old-token")).Return(&domain.RefreshToken{
+		FamilyID: "family1",
+		UserID:   "user123",
+		Expires:  time.Now().Add(-time.Minute),
+	}, nil)
+
+	_, err := authService.Refresh(context.Background(), "old-token")
+
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+	mockRefreshRepo.AssertNotCalled(t, "CreateRefreshToken")
+}
+
+func TestAuthService_ValidateToken_Revoked(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	token, claims, _ := jwtManager.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	mockSessionRepo.On("IsRevoked", mock.Anything, claims.ID).Return(true, nil)
+
+	_, err := authService.ValidateToken(context.Background(), token)
+
+	assert.ErrorIs(t, err, service.ErrTokenRevoked)
+}
+
+func TestAuthService_ValidateToken_Invalid(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	token, _, _ := jwt.NewManager(jwtConfig(jwt.HS256, "other-secret")).GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+
+	_, err := authService.ValidateToken(context.Background(), token)
+
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+	mockSessionRepo.AssertNotCalled(t, "IsRevoked")
+}
+
+func TestAuthService_Logout_RevokesToken(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	token, claims, _ := jwtManager.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	mockSessionRepo.On("IsRevoked", mock.Anything, claims.ID).Return(false, nil)
+	mockSessionRepo.On("RevokeSession", mock.Anything, "user123", claims.ID, claims.ExpiresAt.Time).Return(nil)
+	mockRefreshRepo.On("RevokeFamily", mock.Anything, "family1").Return([]domain.RefreshToken{}, nil)
+
+	err := authService.Logout(context.Background(), token)
+
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	mockSessionRepo.On("RevokeSessions", mock.Anything, "user123").Return(nil)
+	mockRefreshRepo.On("RevokeUserTokens", mock.Anything, "user123").Return(nil)
+
+	err := authService.LogoutAll(context.Background(), "user123")
+
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ValidateToken_TypedErrors(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, nil, nil, nil, nil, jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	now := time.Now()
+	sign := func(method gojwt.SigningMethod, claims gojwt.RegisteredClaims) string {
+		token, err := gojwt.NewWithClaims(method, &jwt.Claims{UserID: "user123", RegisteredClaims: claims}).
+			SignedString([]byte("test-secret"))
+		assert.NoError(t, err)
+		return token
+	}
+	valid := func() gojwt.RegisteredClaims {
+		return gojwt.RegisteredClaims{
+			ID:        "jti1",
+			Issuer:    "document-service",
+			Audience:  gojwt.ClaimStrings{"document-service"},
+			ExpiresAt: gojwt.NewNumericDate(now.Add(time.Minute)),
+			NotBefore: gojwt.NewNumericDate(now),
+			IssuedAt:  gojwt.NewNumericDate(now),
+		}
+	}
+
+	expired := valid()
+	expired.ExpiresAt = gojwt.NewNumericDate(now.Add(-time.Minute))
+	skewed := valid()
+	skewed.ExpiresAt = gojwt.NewNumericDate(now.Add(-10 * time.Second))
+	audience := valid()
+	audience.Audience = gojwt.ClaimStrings{"other-service"}
+	issuer := valid()
+	issuer.Issuer = "someone"
+	early := valid()
+	early.NotBefore = gojwt.NewNumericDate(now.Add(time.Hour))
+	noNotBefore := valid()
+	noNotBefore.NotBefore = nil
+	noID := valid()
+	noID.ID = ""
+
+	cases := map[string]struct {
+		token string
+		err   error
+	}{
+		"malformed":     {"not-a-token", jwt.ErrTokenMalformed},
+		"expired":       {sign(gojwt.SigningMethodHS256, expired), jwt.ErrTokenExpired},
+		"wrong aud":     {sign(gojwt.SigningMethodHS256, audience), jwt.ErrTokenAudience},
+		"wrong iss":     {sign(gojwt.SigningMethodHS256, issuer), jwt.ErrTokenIssuer},
+		"not yet valid": {sign(gojwt.SigningMethodHS256, early), jwt.ErrTokenNotValidYet},
+		"no nbf":        {sign(gojwt.SigningMethodHS256, noNotBefore), jwt.ErrTokenClaims},
+		"no jti":        {sign(gojwt.SigningMethodHS256, noID), jwt.ErrTokenClaims},
+		"other alg":     {sign(gojwt.SigningMethodHS512, valid()), jwt.ErrTokenSignature},
+	}
+	for name, tc := range cases {
+		_, err := authService.ValidateToken(context.Background(), tc.token)
+		assert.ErrorIs(t, err, tc.err, name)
+		assert.ErrorIs(t, err, service.ErrInvalidToken, name)
+	}
+
+	// Expired within the leeway still passes.
+	mockSessionRepo.On("IsRevoked", mock.Anything, "jti1").Return(false, nil)
+	_, err := authService.ValidateToken(context.Background(), sign(gojwt.SigningMethodHS256, skewed))
+	assert.NoError(t, err)
+}
@@ -1,135 +1,459 @@
 package service
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/mibrgmv/document-service/internal/domain"
 	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/filter"
 	"github.com/mibrgmv/document-service/pkg/utils"
 )
 
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+
+	// Search results are ranked and get a snippet each, which costs more
+	// than listing, so fewer are returned at once.
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// documentFields are the fields a document list can be filtered by.
+var documentFields = filter.Fields{
+	"name":    filter.String,
+	"mime":    filter.String,
+	"file":    filter.Bool,
+	"public":  filter.Bool,
+	"created": filter.Time,
+	"updated": filter.Time,
+	"size":    filter.Number,
+	"version": filter.Number,
+}
+
 type DocumentService interface {
-	UploadDocument(ctx context.Context, meta *domain.DocumentMeta, data []byte, jsonData, owner string) (*domain.Document, error)
-	GetDocuments(ctx context.Context, login, filterKey, filterValue string, limit int) ([]domain.Document, error)
-	GetDocument(ctx context.Context, docID, userID, login string) (*domain.Document, error)
-	DeleteDocument(ctx context.Context, id, owner string) error
-	FilterDocuments(docs []domain.Document, key, value string) []domain.Document
+	UploadDocument(ctx context.Context, meta *domain.DocumentMeta, content io.Reader, jsonData, owner string) (*domain.Document, error)
+	GetDocuments(ctx context.Context, userID, filterExpr string, opts domain.ListOptions) (*domain.DocumentPage, error)
+	SearchDocuments(ctx context.Context, userID, query string, limit int) ([]domain.SearchResult, error)
+	GetDocument(ctx context.Context, docID, userID, login string) (*domain.Document, io.ReadSeekCloser, error)
+	UpdateDocumentMeta(ctx context.Context, docID, userID, login string, patch *domain.DocumentMetaPatch) (*domain.Document, error)
+	DeleteDocument(ctx context.Context, id, userID, login string) error
+	UpdateDocument(ctx context.Context, docID, userID, login string, content io.Reader, jsonData string) (*domain.Document, error)
+	GetVersions(ctx context.Context, docID, userID, login string) ([]domain.DocumentVersion, error)
+	GetVersion(ctx context.Context, docID, userID, login string, version int) (*domain.Document, io.ReadSeekCloser, error)
+	RestoreVersion(ctx context.Context, docID, userID, login string, version int) (*domain.Document, error)
+	DiffVersions(ctx context.Context, docID, userID, login string, from, to int) (*domain.VersionDiff, error)
 }
 
 type documentService struct {
-	docRepo   repository.DocumentRepository
-	cacheRepo repository.CacheRepository
+	docRepo    repository.DocumentRepository
+	folderRepo repository.FolderRepository
+	access     *AccessControl
+	auditor    *Auditor
+	cacheRepo  repository.CacheRepository
+	blobStore  repository.BlobStore
 }
 
 func NewDocumentService(
 	docRepo repository.DocumentRepository,
+	folderRepo repository.FolderRepository,
+	access *AccessControl,
+	auditor *Auditor,
 	cacheRepo repository.CacheRepository,
+	blobStore repository.BlobStore,
 ) DocumentService {
 	return &documentService{
-		docRepo:   docRepo,
-		cacheRepo: cacheRepo,
+		docRepo:    docRepo,
+		folderRepo: folderRepo,
+		access:     access,
+		auditor:    auditor,
+		cacheRepo:  cacheRepo,
+		blobStore:  blobStore,
 	}
 }
 
-func (s *documentService) UploadDocument(ctx context.Context, meta *domain.DocumentMeta, data []byte, jsonData, owner string) (*domain.Document, error) {
+func (s *documentService) UploadDocument(ctx context.Context, meta *domain.DocumentMeta, content io.Reader, jsonData, owner string) (*domain.Document, error) {
+	doc, err := s.uploadDocument(ctx, meta, content, jsonData, owner)
+
+	event := domain.AuditEvent{ActorID: owner, Action: domain.AuditUpload, Detail: meta.Name}
+	if doc != nil {
+		event.DocumentID = doc.ID
+	}
+	s.auditor.record(ctx, event, err)
+
+	return doc, err
+}
+
+func (s *documentService) uploadDocument(ctx context.Context, meta *domain.DocumentMeta, content io.Reader, jsonData, owner string) (*domain.Document, error) {
+	if err := checkFolder(ctx, s.folderRepo, meta.FolderID, owner); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.access.resolveGrants(ctx, meta.Grant)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	doc := &domain.Document{
-		ID:      utils.GenerateID(),
-		Name:    meta.Name,
-		Mime:    meta.Mime,
-		File:    meta.File,
-		Public:  meta.Public,
-		Created: time.Now(),
-		Grant:   meta.Grant,
-		Owner:   owner,
+		ID:       utils.GenerateID(),
+		Name:     meta.Name,
+		Mime:     meta.Mime,
+		File:     meta.File,
+		Public:   meta.Public,
+		Created:  now,
+		Updated:  now,
+		Version:  1,
+		Grant:    grants,
+		FolderID: meta.FolderID,
+		Owner:    owner,
 	}
 
 	if meta.File {
-		doc.Data = data
+		content, text := captureText(doc.Mime, content)
+		info, err := s.blobStore.Put(ctx, utils.GenerateID(), content)
+		if err != nil {
+			return nil, err
+		}
+		doc.StorageKey = info.Key
+		doc.Size = info.Size
+		doc.Checksum = info.Checksum
+		doc.ContentText = text()
 	} else {
 		doc.JSON = jsonData
+		doc.Checksum = utils.Checksum([]byte(jsonData))
 	}
 
-	err := s.docRepo.CreateDocument(ctx, doc)
+	err = s.docRepo.CreateDocument(ctx, doc)
 	if err != nil {
+		if doc.StorageKey != "" {
+			s.blobStore.Delete(ctx, doc.StorageKey)
+		}
 		return nil, err
 	}
 
-	s.cacheRepo.DeletePattern(ctx, "docs:*"+owner+"*")
+	s.invalidateListings(ctx, doc)
 	return doc, nil
 }
 
-func (s *documentService) GetDocuments(ctx context.Context, userID, filterKey, filterValue string, limit int) ([]domain.Document, error) {
-	cacheKey := "docs:" + userID + ":" + filterKey + ":" + filterValue
+// GetDocuments returns a page of the documents visible to the user that match
+// the optional filter expression.
+func (s *documentService) GetDocuments(ctx context.Context, userID, filterExpr string, opts domain.ListOptions) (*domain.DocumentPage, error) {
+	opts.Filter = nil
+	if strings.TrimSpace(filterExpr) != "" {
+		expr, err := filter.Parse(filterExpr)
+		if err == nil {
+			err = filter.Validate(expr, documentFields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		opts.Filter = expr
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = "name"
+	}
+	switch opts.Sort {
+	case "name", "created", "size", "mime":
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	opts.Limit = min(opts.Limit, maxPageSize)
+
+	var normalized string
+	if opts.Filter != nil {
+		normalized = opts.Filter.String()
+	}
+	cacheKey := fmt.Sprintf("docs:%s:%s:%s:%t:%d:%t:%s", userID, normalized,
+		opts.Sort, opts.Desc, opts.Limit, opts.Total, opts.Cursor)
 
 	if cached, err := s.cacheRepo.GetDocuments(ctx, cacheKey); cached != nil && err == nil {
 		return cached, nil
 	}
 
-	docs, err := s.docRepo.GetUserDocuments(ctx, userID, limit)
+	groups, err := s.access.groups(ctx, s.access.principal(userID, ""))
 	if err != nil {
 		return nil, err
 	}
 
-	if filterKey != "" && filterValue != "" {
-		docs = s.FilterDocuments(docs, filterKey, filterValue)
+	page, err := s.docRepo.GetUserDocuments(ctx, userID, groups, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	s.cacheRepo.SetDocuments(ctx, cacheKey, docs, 5*time.Minute)
-	return docs, nil
+	s.cacheRepo.SetDocuments(ctx, cacheKey, page, 5*time.Minute)
+	return page, nil
 }
 
-func (s *documentService) GetDocument(ctx context.Context, docID, userID, login string) (*domain.Document, error) {
+// SearchDocuments finds the documents visible to the user whose name or
+// content matches the query.
+func (s *documentService) SearchDocuments(ctx context.Context, userID, query string, limit int) ([]domain.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	groups, err := s.access.groups(ctx, s.access.principal(userID, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.docRepo.SearchDocuments(ctx, userID, groups, query, limit)
+}
+
+// GetDocument returns the document and, for file documents, a seekable reader
+// over its content that the caller must close.
+func (s *documentService) GetDocument(ctx context.Context, docID, userID, login string) (_ *domain.Document, _ io.ReadSeekCloser, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditRead, DocumentID: docID}, err)
+	}()
+
+	doc, err := s.getDocument(ctx, docID, userID, login)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !doc.File {
+		return doc, nil, nil
+	}
+
+	content, err := s.blobStore.Get(ctx, doc.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, content, nil
+}
+
+func (s *documentService) getDocument(ctx context.Context, docID, userID, login string) (*domain.Document, error) {
 	cacheKey := "doc:" + docID + ":" + userID
 
 	if cached, err := s.cacheRepo.GetDocument(ctx, cacheKey); err == nil {
 		return cached, nil
 	}
 
+	doc, err := s.documentFor(ctx, docID, userID, login, domain.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheRepo.SetDocument(ctx, cacheKey, doc, 10*time.Minute)
+	return doc, nil
+}
+
+// documentFor loads the document and checks that the user has at least the
+// role on it.
+func (s *documentService) documentFor(ctx context.Context, docID, userID, login, role string) (*domain.Document, error) {
 	doc, err := s.docRepo.GetDocumentByID(ctx, docID)
 	if err != nil {
 		return nil, err
 	}
 
-	if doc.Owner != userID && !doc.Public && !contains(doc.Grant, login) {
-		return nil, errors.New("access denied")
+	if err := s.access.authorize(ctx, doc, s.access.principal(userID, login), role); err != nil {
+		return nil, err
 	}
+	return doc, nil
+}
 
-	s.cacheRepo.SetDocument(ctx, cacheKey, doc, 10*time.Minute)
+// DeleteDocument deletes the document with all its versions. The owner and
+// co-owners may do so.
+func (s *documentService) DeleteDocument(ctx context.Context, id, userID, login string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditDelete, DocumentID: id}, err)
+	}()
+
+	doc, err := s.documentFor(ctx, id, userID, login, domain.RoleCoOwner)
+	if err != nil {
+		return err
+	}
+
+	versions, err := s.docRepo.GetVersions(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.docRepo.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+
+	// Restored versions share blobs with the version they were restored
+	// from, so every key is deleted once.
+	keys := map[string]bool{doc.StorageKey: true}
+	for _, v := range versions {
+		keys[v.StorageKey] = true
+	}
+	for key := range keys {
+		if key != "" {
+			s.blobStore.Delete(ctx, key)
+		}
+	}
+
+	s.invalidateDocument(ctx, doc)
+	return nil
+}
+
+// UpdateDocumentMeta changes the metadata fields set in patch. The owner and
+// co-owners may do so, but only the owner can move the document to another
+// folder.
+func (s *documentService) UpdateDocumentMeta(ctx context.Context, docID, userID, login string, patch *domain.DocumentMetaPatch) (_ *domain.Document, err error) {
+	// Only changes of who can access the document are audited.
+	if patch.Public != nil || patch.Grant != nil {
+		defer func() {
+			s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditShare, DocumentID: docID,
+				Detail: shareDetail(patch)}, err)
+		}()
+	}
+
+	doc, err := s.documentFor(ctx, docID, userID, login, domain.RoleCoOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *doc
+
+	if patch.Name != nil {
+		if *patch.Name == "" {
+			return nil, ErrInvalidMeta
+		}
+		doc.Name = *patch.Name
+	}
+	if patch.Mime != nil {
+		doc.Mime = *patch.Mime
+	}
+	if patch.Public != nil {
+		doc.Public = *patch.Public
+	}
+	if patch.Grant != nil {
+		doc.Grant, err = s.access.resolveGrants(ctx, *patch.Grant)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if patch.FolderID != nil {
+		if doc.Owner != userID {
+			return nil, ErrAccessDenied
+		}
+		if err := checkFolder(ctx, s.folderRepo, *patch.FolderID, doc.Owner); err != nil {
+			return nil, err
+		}
+		doc.FolderID = *patch.FolderID
+	}
+
+	if err := s.docRepo.UpdateDocumentMeta(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	// Users that lost access must not keep seeing the document in their
+	// cached listings, so both the old and the new audience are invalidated.
+	s.invalidateDocument(ctx, &before, doc)
 	return doc, nil
 }
 
-func (s *documentService) DeleteDocument(ctx context.Context, id, owner string) error {
-	err := s.docRepo.DeleteDocument(ctx, id, owner)
+// shareDetail describes the access a metadata patch gives, as in
+// "public=false grant=alice:viewer,group:finance:editor".
+func shareDetail(patch *domain.DocumentMetaPatch) string {
+	var parts []string
+	if patch.Public != nil {
+		parts = append(parts, fmt.Sprintf("public=%t", *patch.Public))
+	}
+	if patch.Grant != nil {
+		grants := make([]string, len(*patch.Grant))
+		for i, grant := range *patch.Grant {
+			grants[i] = grant.Grantee + ":" + grant.Role
+		}
+		parts = append(parts, "grant="+strings.Join(grants, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// captureText passes content through and, for text/* files, keeps its
+// beginning for the search index. The returned function yields the captured
+// text once content has been read.
+func captureText(mimeType string, content io.Reader) (io.Reader, func() string) {
+	if !strings.HasPrefix(mimeType, "text/") {
+		return content, func() string { return "" }
+	}
+
+	capture := &textCapture{}
+	return io.TeeReader(content, capture), func() string {
+		return utils.PlainText(capture.buf.Bytes())
+	}
+}
+
+type textCapture struct {
+	buf bytes.Buffer
+}
+
+func (t *textCapture) Write(p []byte) (int, error) {
+	if room := utils.MaxIndexedText - t.buf.Len(); room > 0 {
+		t.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// checkFolder makes sure a document can be placed in the folder: it must be
+// the root or one of the owner's folders.
+func checkFolder(ctx context.Context, folderRepo repository.FolderRepository, folderID, owner string) error {
+	if folderID == "" {
+		return nil
+	}
+
+	folder, err := folderRepo.GetFolderByID(ctx, folderID)
 	if err != nil {
 		return err
 	}
 
-	s.cacheRepo.DeletePattern(ctx, "doc:"+id+"*")
-	s.cacheRepo.DeletePattern(ctx, "docs:*"+owner+"*")
+	if folder.Owner != owner {
+		return ErrAccessDenied
+	}
 	return nil
 }
 
-func (s *documentService) FilterDocuments(docs []domain.Document, key, value string) []domain.Document {
-	var filtered []domain.Document
+// invalidateDocument drops the cached copies of the documents together with
+// the cached listings they appear in.
+func (s *documentService) invalidateDocument(ctx context.Context, docs ...*domain.Document) {
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		if !seen[doc.ID] {
+			seen[doc.ID] = true
+			s.cacheRepo.DeletePattern(ctx, "doc:"+doc.ID+"*")
+		}
+	}
+
+	s.invalidateListings(ctx, docs...)
+}
+
+// invalidateListings drops the cached document lists of the users who can
+// see the documents, or of every user if one of them is public.
+func (s *documentService) invalidateListings(ctx context.Context, docs ...*domain.Document) {
 	for _, doc := range docs {
-		switch key {
-		case "name":
-			if doc.Name == value {
-				filtered = append(filtered, doc)
-			}
-		case "mime":
-			if doc.Mime == value {
-				filtered = append(filtered, doc)
-			}
-		case "public":
-			if doc.Public == (value == "true") {
-				filtered = append(filtered, doc)
-			}
+		if doc.Public {
+			s.cacheRepo.DeletePattern(ctx, "docs:*")
+			return
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, user := range s.access.audience(ctx, docs...) {
+		if !seen[user] {
+			seen[user] = true
+			s.cacheRepo.DeletePattern(ctx, "docs:*"+user+"*")
 		}
 	}
-	return filtered
 }
 
 func contains(slice []string, item string) bool {
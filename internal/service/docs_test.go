@@ -2,42 +2,73 @@ package service_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
 	"github.com/mibrgmv/document-service/internal/service"
 	"github.com/mibrgmv/document-service/internal/service/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// testAccess returns access control for tests that do not resolve grantees,
+// with every user outside of any group.
+func testAccess() *service.AccessControl {
+	groupRepo := new(mocks.MockGroupRepository)
+	groupRepo.On("GetUserGroups", mock.Anything, mock.Anything).Return([]domain.Group{}, nil).Maybe()
+	return service.NewAccessControl(new(mocks.MockUserRepository), groupRepo)
+}
+
 func TestDocumentService_UploadDocument_Success_File(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	meta := &domain.DocumentMeta{
 		Name:   "test.txt",
 		File:   true,
 		Public: false,
 		Mime:   "text/plain",
-		Grant:  []string{},
+		Grant:  []domain.Grant{},
 	}
-	data := []byte("test file content")
+	data := "test file content"
 	owner := "testuser"
 
+	mockBlobStore.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		io.ReadAll(args.Get(2).(io.Reader))
+	}).Return(&repository.BlobInfo{
+		Key:      "blob123",
+		Size:     int64(len(data)),
+		Checksum: "checksum",
+	}, nil)
+
 	mockDocRepo.On("CreateDocument", mock.Anything, mock.MatchedBy(func(doc *domain.Document) bool {
 		return doc.Name == "test.txt" &&
 			doc.File == true &&
 			doc.Owner == "testuser" &&
-			string(doc.Data) == "test file content"
+			doc.StorageKey == "blob123" &&
+			doc.Size == int64(len("test file content")) &&
+			doc.Checksum == "checksum" &&
+			doc.ContentText == "test file content"
 	})).Return(nil)
 
 	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*testuser*").Return(nil)
 
-	doc, err := docService.UploadDocument(context.Background(), meta, data, "", owner)
+	doc, err := docService.UploadDocument(context.Background(), meta, strings.NewReader(data), "", owner)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, doc)
@@ -46,19 +77,111 @@ func TestDocumentService_UploadDocument_Success_File(t *testing.T) {
 	assert.Equal(t, "testuser", doc.Owner)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
+}
+
+func TestDocumentService_UploadDocument_CreateFails_RemovesBlob(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	meta := &domain.DocumentMeta{Name: "test.txt", File: true, Mime: "text/plain"}
+
+	mockBlobStore.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(&repository.BlobInfo{Key: "blob123"}, nil)
+	mockDocRepo.On("CreateDocument", mock.Anything, mock.Anything).Return(errors.New("database error"))
+	mockBlobStore.On("Delete", mock.Anything, "blob123").Return(nil)
+
+	doc, err := docService.UploadDocument(context.Background(), meta, strings.NewReader("content"), "", "testuser")
+
+	assert.Error(t, err)
+	assert.Nil(t, doc)
+	mockBlobStore.AssertExpectations(t)
+	mockCacheRepo.AssertNotCalled(t, "DeletePattern")
+}
+
+func TestDocumentService_UploadDocument_BinaryNotIndexed(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockBlobStore.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		io.ReadAll(args.Get(2).(io.Reader))
+	}).Return(&repository.BlobInfo{Key: "blob123"}, nil)
+	mockDocRepo.On("CreateDocument", mock.Anything, mock.MatchedBy(func(doc *domain.Document) bool {
+		return doc.ContentText == ""
+	})).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	meta := &domain.DocumentMeta{Name: "photo.jpg", File: true, Mime: "image/jpeg", Grant: []domain.Grant{}}
+	_, err := docService.UploadDocument(context.Background(), meta, strings.NewReader("\xff\xd8\xff"), "", "testuser")
+
+	assert.NoError(t, err)
+	mockDocRepo.AssertExpectations(t)
+}
+
+func TestDocumentService_SearchDocuments(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	expected := []domain.SearchResult{{Document: domain.Document{ID: "123"}, Rank: 0.5, Snippet: "<b>report</b>"}}
+	mockDocRepo.On("SearchDocuments", mock.Anything, "user123", []string{}, "report -draft", 20).Return(expected, nil)
+
+	results, err := docService.SearchDocuments(context.Background(), "user123", "report -draft", 20)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	mockDocRepo.On("SearchDocuments", mock.Anything, "user123", []string{}, "report", 100).Return(expected, nil)
+	_, err = docService.SearchDocuments(context.Background(), "user123", "report", 5000)
+	assert.NoError(t, err)
+
+	_, err = docService.SearchDocuments(context.Background(), "user123", "  ", 20)
+	assert.ErrorIs(t, err, service.ErrEmptyQuery)
+	mockDocRepo.AssertNumberOfCalls(t, "SearchDocuments", 2)
+}
+
+func TestDocumentService_UploadDocument_ForeignFolder(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "f1").Return(&domain.Folder{ID: "f1", Owner: "owner1"}, nil)
+
+	meta := &domain.DocumentMeta{Name: "test.txt", File: true, Grant: []domain.Grant{}, FolderID: "f1"}
+	_, err := docService.UploadDocument(context.Background(), meta, strings.NewReader("data"), "", "testuser")
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockBlobStore.AssertNotCalled(t, "Put")
+	mockDocRepo.AssertNotCalled(t, "CreateDocument")
 }
 
 func TestDocumentService_UploadDocument_Success_JSON(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockUserRepo := new(mocks.MockUserRepository)
+	access := service.NewAccessControl(mockUserRepo, new(mocks.MockGroupRepository))
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, access, testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "user1").Return(&domain.User{ID: "id1", Login: "user1"}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "user2").Return(&domain.User{ID: "id2", Login: "user2"}, nil)
 
 	meta := &domain.DocumentMeta{
 		Name:   "data.json",
 		File:   false,
 		Public: true,
 		Mime:   "application/json",
-		Grant:  []string{"user1", "user2"},
+		Grant:  []domain.Grant{{Grantee: "user1"}, {Grantee: "user2", Role: domain.RoleEditor}},
 	}
 	jsonData := `{"key": "value"}`
 	owner := "testuser"
@@ -68,10 +191,13 @@ func TestDocumentService_UploadDocument_Success_JSON(t *testing.T) {
 			doc.File == false &&
 			doc.Public == true &&
 			doc.JSON == `{"key": "value"}` &&
-			len(doc.Grant) == 2
+			assert.ObjectsAreEqual([]domain.Grant{
+				{Grantee: "user1", Role: domain.RoleViewer, UserID: "id1"},
+				{Grantee: "user2", Role: domain.RoleEditor, UserID: "id2"},
+			}, doc.Grant)
 	})).Return(nil)
 
-	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*testuser*").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*").Return(nil)
 
 	doc, err := docService.UploadDocument(context.Background(), meta, nil, jsonData, owner)
 
@@ -81,14 +207,17 @@ func TestDocumentService_UploadDocument_Success_JSON(t *testing.T) {
 	assert.False(t, doc.File)
 	assert.True(t, doc.Public)
 	assert.Equal(t, `{"key": "value"}`, doc.JSON)
+	assert.NotEmpty(t, doc.Checksum)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
 }
 
 func TestDocumentService_GetDocuments_Success_FromCache(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	expectedDocs := []domain.Document{
 		{
@@ -98,24 +227,26 @@ func TestDocumentService_GetDocuments_Success_FromCache(t *testing.T) {
 			File:    true,
 			Public:  false,
 			Created: time.Now(),
-			Grant:   []string{},
+			Grant:   []domain.Grant{},
 		},
 	}
 
-	mockCacheRepo.On("GetDocuments", mock.Anything, "docs:testuser::").Return(expectedDocs, nil)
+	mockCacheRepo.On("GetDocuments", mock.Anything, "docs:testuser::name:false:100:false:").Return(&domain.DocumentPage{Docs: expectedDocs}, nil)
 
-	docs, err := docService.GetDocuments(context.Background(), "testuser", "", "", 100)
+	page, err := docService.GetDocuments(context.Background(), "testuser", "", domain.ListOptions{Limit: 100})
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedDocs, docs)
+	assert.Equal(t, expectedDocs, page.Docs)
 	mockCacheRepo.AssertExpectations(t)
 	mockDocRepo.AssertNotCalled(t, "GetUserDocuments")
 }
 
 func TestDocumentService_GetDocuments_Success_FromDatabase(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	expectedDocs := []domain.Document{
 		{
@@ -125,37 +256,34 @@ func TestDocumentService_GetDocuments_Success_FromDatabase(t *testing.T) {
 			File:    true,
 			Public:  false,
 			Created: time.Now(),
-			Grant:   []string{},
+			Grant:   []domain.Grant{},
 		},
 	}
 
-	mockCacheRepo.On("GetDocuments", mock.Anything, "docs:testuser::").Return(nil, errors.New("cache miss"))
-	mockDocRepo.On("GetUserDocuments", mock.Anything, "testuser", 100).Return(expectedDocs, nil)
-	mockCacheRepo.On("SetDocuments", mock.Anything, "docs:testuser::", expectedDocs, 5*time.Minute).Return(nil)
+	expectedPage := &domain.DocumentPage{Docs: expectedDocs, NextCursor: "next"}
+	opts := domain.ListOptions{Limit: 100, Sort: "name"}
 
-	docs, err := docService.GetDocuments(context.Background(), "testuser", "", "", 100)
+	mockCacheRepo.On("GetDocuments", mock.Anything, "docs:testuser::name:false:100:false:").Return(nil, errors.New("cache miss"))
+	mockDocRepo.On("GetUserDocuments", mock.Anything, "testuser", []string{}, opts).Return(expectedPage, nil)
+	mockCacheRepo.On("SetDocuments", mock.Anything, "docs:testuser::name:false:100:false:", expectedPage, 5*time.Minute).Return(nil)
+
+	page, err := docService.GetDocuments(context.Background(), "testuser", "", domain.ListOptions{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedDocs, docs)
+	assert.Equal(t, expectedDocs, page.Docs)
+	assert.Equal(t, "next", page.NextCursor)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
 }
 
 func TestDocumentService_GetDocuments_WithFilter(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
-	allDocs := []domain.Document{
-		{
-			ID:      "1",
-			Name:    "doc1.txt",
-			Mime:    "text/plain",
-			File:    true,
-			Public:  false,
-			Created: time.Now(),
-			Grant:   []string{},
-		},
+	matching := []domain.Document{
 		{
 			ID:      "2",
 			Name:    "image.jpg",
@@ -163,228 +291,544 @@ func TestDocumentService_GetDocuments_WithFilter(t *testing.T) {
 			File:    true,
 			Public:  true,
 			Created: time.Now(),
-			Grant:   []string{},
+			Grant:   []domain.Grant{},
 		},
 	}
 
-	mockCacheRepo.On("GetDocuments", mock.Anything, "docs:testuser:mime:image/jpeg").Return(nil, errors.New("cache miss"))
-	mockDocRepo.On("GetUserDocuments", mock.Anything, "testuser", 100).Return(allDocs, nil)
-	mockCacheRepo.On("SetDocuments", mock.Anything, "docs:testuser:mime:image/jpeg", mock.Anything, 5*time.Minute).Return(nil)
+	cacheKey := `docs:testuser:(mime ~ "image/*" and not public = false):name:false:100:false:`
+	mockCacheRepo.On("GetDocuments", mock.Anything, cacheKey).Return(nil, errors.New("cache miss"))
+	mockDocRepo.On("GetUserDocuments", mock.Anything, "testuser", []string{}, mock.MatchedBy(func(opts domain.ListOptions) bool {
+		return opts.Filter != nil && opts.Filter.String() == `(mime ~ "image/*" and not public = false)`
+	})).Return(&domain.DocumentPage{Docs: matching}, nil)
+	mockCacheRepo.On("SetDocuments", mock.Anything, cacheKey, mock.Anything, 5*time.Minute).Return(nil)
 
-	docs, err := docService.GetDocuments(context.Background(), "testuser", "mime", "image/jpeg", 100)
+	page, err := docService.GetDocuments(context.Background(), "testuser", `MIME ~ "image/*" AND NOT public = false`, domain.ListOptions{Limit: 100})
 
 	assert.NoError(t, err)
-	assert.Len(t, docs, 1)
-	assert.Equal(t, "image.jpg", docs[0].Name)
-	assert.Equal(t, "image/jpeg", docs[0].Mime)
+	assert.Len(t, page.Docs, 1)
+	assert.Equal(t, "image.jpg", page.Docs[0].Name)
 	mockDocRepo.AssertExpectations(t)
 }
 
+func TestDocumentService_GetDocuments_InvalidFilter(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	for _, expr := range []string{
+		`owner = "alice"`,
+		`size > "big"`,
+		`public < true`,
+		`created ~ "2026*"`,
+		`name = "a" and`,
+		`(name = "a"`,
+	} {
+		_, err := docService.GetDocuments(context.Background(), "testuser", expr, domain.ListOptions{})
+		assert.ErrorIs(t, err, service.ErrInvalidFilter, expr)
+	}
+	mockDocRepo.AssertNotCalled(t, "GetUserDocuments")
+	mockCacheRepo.AssertNotCalled(t, "GetDocuments")
+}
+
+func TestDocumentService_GetDocuments_SortAndCursor(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	total := 3
+	opts := domain.ListOptions{Limit: 1000, Sort: "size", Desc: true, Cursor: "abc", Total: true}
+	cacheKey := "docs:testuser::size:true:1000:true:abc"
+
+	mockCacheRepo.On("GetDocuments", mock.Anything, cacheKey).Return(nil, errors.New("cache miss"))
+	mockDocRepo.On("GetUserDocuments", mock.Anything, "testuser", []string{}, opts).Return(&domain.DocumentPage{
+		Docs:  []domain.Document{{ID: "1"}},
+		Total: &total,
+	}, nil)
+	mockCacheRepo.On("SetDocuments", mock.Anything, cacheKey, mock.Anything, 5*time.Minute).Return(nil)
+
+	page, err := docService.GetDocuments(context.Background(), "testuser", "", domain.ListOptions{
+		Limit: 5000, Sort: "size", Desc: true, Cursor: "abc", Total: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *page.Total)
+	mockDocRepo.AssertExpectations(t)
+}
+
+func TestDocumentService_GetDocuments_InvalidSort(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	_, err := docService.GetDocuments(context.Background(), "testuser", "", domain.ListOptions{Sort: "owner"})
+
+	assert.ErrorIs(t, err, service.ErrInvalidSort)
+	mockDocRepo.AssertNotCalled(t, "GetUserDocuments")
+}
+
 func TestDocumentService_GetDocument_Success_FromCache(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	expectedDoc := &domain.Document{
-		ID:      "123",
-		Name:    "test.txt",
-		Mime:    "text/plain",
-		File:    true,
-		Public:  false,
-		Created: time.Now(),
-		Grant:   []string{},
-		Owner:   "owner1",
-		Data:    []byte("content"),
+		ID:         "123",
+		Name:       "test.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Public:     false,
+		Created:    time.Now(),
+		Grant:      []domain.Grant{},
+		Owner:      "owner1",
+		StorageKey: "blob123",
 	}
 
 	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(expectedDoc, nil)
+	mockBlobStore.On("Get", mock.Anything, "blob123").Return(nopSeekCloser{strings.NewReader("content")}, nil)
 
-	doc, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
+	doc, content, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedDoc, doc)
+	data, _ := io.ReadAll(content)
+	assert.Equal(t, "content", string(data))
 	mockCacheRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
 	mockDocRepo.AssertNotCalled(t, "GetDocumentByID")
 }
 
 func TestDocumentService_GetDocument_Success_FromDatabase(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	expectedDoc := &domain.Document{
-		ID:      "123",
-		Name:    "test.txt",
-		Mime:    "text/plain",
-		File:    true,
-		Public:  true,
-		Created: time.Now(),
-		Grant:   []string{},
-		Owner:   "owner1",
-		Data:    []byte("content"),
+		ID:         "123",
+		Name:       "test.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Public:     true,
+		Created:    time.Now(),
+		Grant:      []domain.Grant{},
+		Owner:      "owner1",
+		StorageKey: "blob123",
 	}
 
 	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(nil, errors.New("cache miss"))
 	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(expectedDoc, nil)
 	mockCacheRepo.On("SetDocument", mock.Anything, "doc:123:user123", expectedDoc, 10*time.Minute).Return(nil)
+	mockBlobStore.On("Get", mock.Anything, "blob123").Return(nopSeekCloser{strings.NewReader("content")}, nil)
 
-	doc, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
+	doc, content, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedDoc, doc)
+	assert.NotNil(t, content)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
 }
 
 func TestDocumentService_GetDocument_AccessDenied(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	docFromDB := &domain.Document{
-		ID:      "123",
-		Name:    "private.txt",
-		Mime:    "text/plain",
-		File:    true,
-		Public:  false,
-		Created: time.Now(),
-		Grant:   []string{},
-		Owner:   "otheruser",
-		Data:    []byte("content"),
+		ID:         "123",
+		Name:       "private.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Public:     false,
+		Created:    time.Now(),
+		Grant:      []domain.Grant{},
+		Owner:      "otheruser",
+		StorageKey: "blob123",
 	}
 
 	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(nil, errors.New("cache miss"))
 	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(docFromDB, nil)
 
-	doc, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
+	doc, content, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
 	assert.Nil(t, doc)
-	assert.Equal(t, "access denied", err.Error())
+	assert.Nil(t, content)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertNotCalled(t, "SetDocument")
+	mockBlobStore.AssertNotCalled(t, "Get")
 }
 
 func TestDocumentService_GetDocument_AccessGranted_ByGrant(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	docFromDB := &domain.Document{
-		ID:      "123",
-		Name:    "shared.txt",
-		Mime:    "text/plain",
-		File:    true,
-		Public:  false,
-		Created: time.Now(),
-		Grant:   []string{"testuser"},
-		Owner:   "otheruser",
-		Data:    []byte("content"),
+		ID:         "123",
+		Name:       "shared.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Public:     false,
+		Created:    time.Now(),
+		Grant:      []domain.Grant{{Grantee: "testuser", Role: domain.RoleViewer, UserID: "user123"}},
+		Owner:      "otheruser",
+		StorageKey: "blob123",
 	}
 
 	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(nil, errors.New("cache miss"))
 	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(docFromDB, nil)
 	mockCacheRepo.On("SetDocument", mock.Anything, "doc:123:user123", docFromDB, 10*time.Minute).Return(nil)
+	mockBlobStore.On("Get", mock.Anything, "blob123").Return(nopSeekCloser{strings.NewReader("content")}, nil)
 
-	doc, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
+	doc, content, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
 
 	assert.NoError(t, err)
 	assert.Equal(t, docFromDB, doc)
+	assert.NotNil(t, content)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
 }
 
 func TestDocumentService_GetDocument_AccessGranted_ByOwner(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
 	docFromDB := &domain.Document{
-		ID:      "123",
-		Name:    "myfile.txt",
-		Mime:    "text/plain",
-		File:    true,
-		Public:  false,
-		Created: time.Now(),
-		Grant:   []string{},
-		Owner:   "user123",
-		Data:    []byte("content"),
+		ID:         "123",
+		Name:       "myfile.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Public:     false,
+		Created:    time.Now(),
+		Grant:      []domain.Grant{},
+		Owner:      "user123",
+		StorageKey: "blob123",
 	}
 
 	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(nil, errors.New("cache miss"))
 	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(docFromDB, nil)
 	mockCacheRepo.On("SetDocument", mock.Anything, "doc:123:user123", docFromDB, 10*time.Minute).Return(nil)
+	mockBlobStore.On("Get", mock.Anything, "blob123").Return(nopSeekCloser{strings.NewReader("content")}, nil)
 
-	doc, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
+	doc, content, err := docService.GetDocument(context.Background(), "123", "user123", "testuser")
 
 	assert.NoError(t, err)
 	assert.Equal(t, docFromDB, doc)
+	assert.NotNil(t, content)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
 }
 
-func TestDocumentService_DeleteDocument_Success(t *testing.T) {
+func TestDocumentService_UpdateDocumentMeta_Success(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockUserRepo := new(mocks.MockUserRepository)
+	access := service.NewAccessControl(mockUserRepo, new(mocks.MockGroupRepository))
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, access, testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "user2").Return(&domain.User{ID: "id2", Login: "user2"}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "user3").Return(&domain.User{ID: "id3", Login: "user3"}, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Name:  "old.txt",
+		Mime:  "text/plain",
+		File:  true,
+		Owner: "owner1",
+		Grant: []domain.Grant{
+			{Grantee: "user1", Role: domain.RoleViewer, UserID: "id1"},
+			{Grantee: "user2", Role: domain.RoleViewer, UserID: "id2"},
+		},
+	}, nil)
+	mockDocRepo.On("UpdateDocumentMeta", mock.Anything, mock.MatchedBy(func(doc *domain.Document) bool {
+		return doc.Name == "new.txt" &&
+			doc.Mime == "text/plain" &&
+			!doc.Public &&
+			assert.ObjectsAreEqual([]domain.Grant{
+				{Grantee: "user2", Role: domain.RoleViewer, UserID: "id2"},
+				{Grantee: "user3", Role: domain.RoleCoOwner, UserID: "id3"},
+			}, doc.Grant)
+	})).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "doc:123*").Return(nil).Once()
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*owner1*").Return(nil).Once()
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*id1*").Return(nil).Once()
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*id2*").Return(nil).Once()
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*id3*").Return(nil).Once()
+
+	name := "new.txt"
+	grant := []domain.Grant{{Grantee: "user2"}, {Grantee: "user3"}, {Grantee: "user3", Role: domain.RoleCoOwner}}
+	doc, err := docService.UpdateDocumentMeta(context.Background(), "123", "owner1", "owner", &domain.DocumentMetaPatch{
+		Name:  &name,
+		Grant: &grant,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new.txt", doc.Name)
+	assert.Len(t, doc.Grant, 2)
+	mockDocRepo.AssertExpectations(t)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestDocumentService_UpdateDocumentMeta_MadePrivate(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:     "123",
+		Public: true,
+		Owner:  "owner1",
+		Grant:  []domain.Grant{},
+	}, nil)
+	mockDocRepo.On("UpdateDocumentMeta", mock.Anything, mock.MatchedBy(func(doc *domain.Document) bool {
+		return !doc.Public
+	})).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "doc:123*").Return(nil).Once()
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*").Return(nil).Once()
+
+	public := false
+	doc, err := docService.UpdateDocumentMeta(context.Background(), "123", "owner1", "owner", &domain.DocumentMetaPatch{
+		Public: &public,
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, doc.Public)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestDocumentService_UpdateDocumentMeta_NotOwner(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{{Grantee: "testuser", Role: domain.RoleEditor}},
+	}, nil)
+
+	public := true
+	_, err := docService.UpdateDocumentMeta(context.Background(), "123", "user123", "testuser", &domain.DocumentMetaPatch{
+		Public: &public,
+	})
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockDocRepo.AssertNotCalled(t, "UpdateDocumentMeta")
+	mockCacheRepo.AssertNotCalled(t, "DeletePattern")
+}
 
-	mockDocRepo.On("DeleteDocument", mock.Anything, "123", "testuser").Return(nil)
+func TestDocumentService_UpdateDocumentMeta_EmptyName(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Name:  "test.txt",
+		Owner: "owner1",
+	}, nil)
+
+	name := ""
+	_, err := docService.UpdateDocumentMeta(context.Background(), "123", "owner1", "owner", &domain.DocumentMetaPatch{
+		Name: &name,
+	})
+
+	assert.ErrorIs(t, err, service.ErrInvalidMeta)
+	mockDocRepo.AssertNotCalled(t, "UpdateDocumentMeta")
+}
+
+func TestDocumentService_DeleteDocument_Success(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	doc := &domain.Document{
+		ID:         "123",
+		File:       true,
+		Owner:      "user123",
+		Grant:      []domain.Grant{{Grantee: "colleague", Role: domain.RoleViewer, UserID: "user456"}},
+		StorageKey: "blob123",
+	}
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(doc, nil)
+	mockDocRepo.On("GetVersions", mock.Anything, "123").Return([]domain.DocumentVersion{
+		{DocumentID: "123", Version: 1, StorageKey: "blob100"},
+		{DocumentID: "123", Version: 2, StorageKey: "blob123"},
+		{DocumentID: "123", Version: 3, StorageKey: "blob100"},
+	}, nil)
+	mockDocRepo.On("DeleteDocument", mock.Anything, "123").Return(doc, nil)
+	mockBlobStore.On("Delete", mock.Anything, "blob100").Return(nil).Once()
+	mockBlobStore.On("Delete", mock.Anything, "blob123").Return(nil).Once()
 	mockCacheRepo.On("DeletePattern", mock.Anything, "doc:123*").Return(nil)
-	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*testuser*").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*user123*").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*user456*").Return(nil)
 
-	err := docService.DeleteDocument(context.Background(), "123", "testuser")
+	err := docService.DeleteDocument(context.Background(), "123", "user123", "testuser")
 
 	assert.NoError(t, err)
 	mockDocRepo.AssertExpectations(t)
 	mockCacheRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
 }
 
-func TestDocumentService_DeleteDocument_Error(t *testing.T) {
+func TestDocumentService_DeleteDocument_Roles(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	doc := &domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{
+			{Grantee: "editor", Role: domain.RoleEditor, UserID: "user1"},
+			{Grantee: "coowner", Role: domain.RoleCoOwner, UserID: "user2"},
+		},
+	}
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(doc, nil)
+	mockDocRepo.On("GetVersions", mock.Anything, "123").Return([]domain.DocumentVersion{}, nil)
+	mockDocRepo.On("DeleteDocument", mock.Anything, "123").Return(doc, nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
 
-	mockDocRepo.On("DeleteDocument", mock.Anything, "123", "testuser").Return(errors.New("database error"))
+	err := docService.DeleteDocument(context.Background(), "123", "user1", "editor")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockDocRepo.AssertNotCalled(t, "DeleteDocument", mock.Anything, "123")
 
-	err := docService.DeleteDocument(context.Background(), "123", "testuser")
+	err = docService.DeleteDocument(context.Background(), "123", "user2", "coowner")
+	assert.NoError(t, err)
+	mockDocRepo.AssertCalled(t, "DeleteDocument", mock.Anything, "123")
+}
 
-	assert.Error(t, err)
-	assert.Equal(t, "database error", err.Error())
-	mockDocRepo.AssertExpectations(t)
-	mockCacheRepo.AssertNotCalled(t, "DeletePattern")
+func TestDocumentService_UpdateDocumentMeta_CoOwnerReshares(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockUserRepo := new(mocks.MockUserRepository)
+	access := service.NewAccessControl(mockUserRepo, new(mocks.MockGroupRepository))
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, access, testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "coowner").Return(&domain.User{ID: "user2", Login: "coowner"}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "viewer").Return(&domain.User{ID: "user3", Login: "viewer"}, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{{Grantee: "coowner", Role: domain.RoleCoOwner, UserID: "user2"}},
+	}, nil)
+	mockDocRepo.On("UpdateDocumentMeta", mock.Anything, mock.AnythingOfType("*domain.Document")).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	grant := []domain.Grant{{Grantee: "coowner", Role: domain.RoleCoOwner}, {Grantee: "viewer"}}
+	doc, err := docService.UpdateDocumentMeta(context.Background(), "123", "user2", "coowner", &domain.DocumentMetaPatch{
+		Grant: &grant,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RoleViewer, doc.Grant[1].Role)
+
+	folderID := "f1"
+	_, err = docService.UpdateDocumentMeta(context.Background(), "123", "user2", "coowner", &domain.DocumentMetaPatch{
+		FolderID: &folderID,
+	})
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	invalid := []domain.Grant{{Grantee: "viewer", Role: domain.RoleOwner}}
+	_, err = docService.UpdateDocumentMeta(context.Background(), "123", "owner1", "owner", &domain.DocumentMetaPatch{
+		Grant: &invalid,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidRole)
 }
 
-func TestDocumentService_FilterDocuments(t *testing.T) {
+func TestDocumentService_UpdateDocumentMeta_UnknownUser(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	access := service.NewAccessControl(mockUserRepo, new(mocks.MockGroupRepository))
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), access, testAuditor(),
+		new(mocks.MockCacheRepository), new(mocks.MockBlobStore))
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{ID: "123", Owner: "owner1"}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "nobody").Return(nil, repository.ErrNotFound)
+
+	grant := []domain.Grant{{Grantee: "nobody"}}
+	_, err := docService.UpdateDocumentMeta(context.Background(), "123", "owner1", "owner", &domain.DocumentMetaPatch{
+		Grant: &grant,
+	})
+
+	assert.ErrorIs(t, err, service.ErrUnknownUser)
+	mockDocRepo.AssertNotCalled(t, "UpdateDocumentMeta")
+}
+
+func TestDocumentService_GetDocument_GrantMatchesUserID(t *testing.T) {
 	mockDocRepo := new(mocks.MockDocumentRepository)
 	mockCacheRepo := new(mocks.MockCacheRepository)
-	docService := service.NewDocumentService(mockDocRepo, mockCacheRepo)
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), testAccess(), testAuditor(), mockCacheRepo,
+		new(mocks.MockBlobStore))
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{{Grantee: "alice", Role: domain.RoleViewer, UserID: "user1"}},
+	}, nil)
+	mockCacheRepo.On("GetDocument", mock.Anything, mock.Anything).Return(nil, errors.New("cache miss"))
+
+	// A login alone does not grant access; the grant belongs to the user
+	// it was resolved to.
+	_, _, err := docService.GetDocument(context.Background(), "123", "user2", "alice")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+}
 
-	docs := []domain.Document{
-		{Name: "doc1.txt", Mime: "text/plain", Public: false},
-		{Name: "doc2.pdf", Mime: "application/pdf", Public: true},
-		{Name: "image.jpg", Mime: "image/jpeg", Public: true},
-	}
+func TestDocumentMeta_LegacyGrantList(t *testing.T) {
+	var meta domain.DocumentMeta
+	err := json.Unmarshal([]byte(`{"grant": ["alice", {"grantee": "bob", "role": "editor"}]}`), &meta)
 
-	// Filter by name
-	filtered := docService.FilterDocuments(docs, "name", "doc1.txt")
-	assert.Len(t, filtered, 1)
-	assert.Equal(t, "doc1.txt", filtered[0].Name)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Grant{
+		{Grantee: "alice", Role: domain.RoleViewer},
+		{Grantee: "bob", Role: domain.RoleEditor},
+	}, meta.Grant)
+}
 
-	// Filter by mime
-	filtered = docService.FilterDocuments(docs, "mime", "image/jpeg")
-	assert.Len(t, filtered, 1)
-	assert.Equal(t, "image.jpg", filtered[0].Name)
+func TestDocumentService_DeleteDocument_Error(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
 
-	// Filter by public=true
-	filtered = docService.FilterDocuments(docs, "public", "true")
-	assert.Len(t, filtered, 2)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{ID: "123", Owner: "user123"}, nil)
+	mockDocRepo.On("GetVersions", mock.Anything, "123").Return([]domain.DocumentVersion{}, nil)
+	mockDocRepo.On("DeleteDocument", mock.Anything, "123").Return(nil, errors.New("database error"))
 
-	// Filter by public=false
-	filtered = docService.FilterDocuments(docs, "public", "false")
-	assert.Len(t, filtered, 1)
-	assert.Equal(t, "doc1.txt", filtered[0].Name)
+	err := docService.DeleteDocument(context.Background(), "123", "user123", "testuser")
 
-	// Unknown filter key - returns empty
-	filtered = docService.FilterDocuments(docs, "unknown", "value")
-	assert.Len(t, filtered, 0)
+	assert.Error(t, err)
+	assert.Equal(t, "database error", err.Error())
+	mockDocRepo.AssertExpectations(t)
+	mockCacheRepo.AssertNotCalled(t, "DeletePattern")
+	mockBlobStore.AssertNotCalled(t, "Delete")
 }
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+)
+
+var (
+	ErrAccessDenied  = errors.New("access denied")
+	ErrNotFound      = repository.ErrNotFound
+	ErrInvalidMeta   = errors.New("invalid document metadata")
+	ErrEmptyQuery    = errors.New("search query is empty")
+	ErrInvalidSort   = errors.New("sort must be one of name, created, size, mime")
+	ErrInvalidCursor = repository.ErrInvalidCursor
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidToken  = jwt.ErrInvalidToken
+	ErrTokenRevoked  = errors.New("token revoked")
+	ErrTokenReused   = errors.New("refresh token reused")
+
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrInvalidName    = errors.New("invalid name")
+	ErrFolderExists   = errors.New("folder with this name already exists")
+	ErrFolderCycle    = errors.New("folder cannot be moved into itself")
+	ErrFolderNotEmpty = errors.New("folder is not empty")
+)
+
+type FolderService interface {
+	CreateFolder(ctx context.Context, name, parentID, owner string) (*domain.Folder, error)
+	GetFolder(ctx context.Context, id, owner string) (*domain.FolderContents, error)
+	UpdateFolder(ctx context.Context, id, owner string, patch *domain.FolderPatch) (*domain.Folder, error)
+	DeleteFolder(ctx context.Context, id, owner string) error
+	ResolvePath(ctx context.Context, owner, path string) (*domain.Document, error)
+}
+
+type folderService struct {
+	folderRepo repository.FolderRepository
+	docRepo    repository.DocumentRepository
+}
+
+func NewFolderService(folderRepo repository.FolderRepository, docRepo repository.DocumentRepository) FolderService {
+	return &folderService{
+		folderRepo: folderRepo,
+		docRepo:    docRepo,
+	}
+}
+
+func (s *folderService) CreateFolder(ctx context.Context, name, parentID, owner string) (*domain.Folder, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+
+	if parentID != "" {
+		if _, err := s.ownFolder(ctx, parentID, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	folder := &domain.Folder{
+		ID:       utils.GenerateID(),
+		Name:     name,
+		ParentID: parentID,
+		Created:  now,
+		Updated:  now,
+		Owner:    owner,
+	}
+
+	if err := s.folderRepo.CreateFolder(ctx, folder); err != nil {
+		return nil, folderError(err)
+	}
+
+	return folder, nil
+}
+
+// GetFolder lists the folders and documents directly inside the folder. An
+// empty id lists the owner's root.
+func (s *folderService) GetFolder(ctx context.Context, id, owner string) (*domain.FolderContents, error) {
+	contents := &domain.FolderContents{}
+
+	if id != "" {
+		folder, err := s.ownFolder(ctx, id, owner)
+		if err != nil {
+			return nil, err
+		}
+		contents.Folder = folder
+	}
+
+	folders, err := s.folderRepo.GetSubfolders(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.docRepo.GetFolderDocuments(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	contents.Folders = folders
+	contents.Documents = docs
+	return contents, nil
+}
+
+// UpdateFolder renames and/or moves a folder. Moving a folder into itself or
+// one of its descendants fails with ErrFolderCycle.
+func (s *folderService) UpdateFolder(ctx context.Context, id, owner string, patch *domain.FolderPatch) (*domain.Folder, error) {
+	folder, err := s.ownFolder(ctx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if !validName(*patch.Name) {
+			return nil, ErrInvalidName
+		}
+		folder.Name = *patch.Name
+	}
+
+	if patch.ParentID != nil {
+		if *patch.ParentID != "" {
+			if _, err := s.ownFolder(ctx, *patch.ParentID, owner); err != nil {
+				return nil, err
+			}
+		}
+		folder.ParentID = *patch.ParentID
+	}
+
+	folder.Updated = time.Now()
+	if err := s.folderRepo.UpdateFolder(ctx, folder); err != nil {
+		return nil, folderError(err)
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) DeleteFolder(ctx context.Context, id, owner string) error {
+	if err := s.folderRepo.DeleteFolder(ctx, id, owner); err != nil {
+		return folderError(err)
+	}
+	return nil
+}
+
+// ResolvePath finds the owner's document at a slash separated path such as
+// /reports/2026/q3.pdf, where every element but the last names a folder.
+func (s *folderService) ResolvePath(ctx context.Context, owner, path string) (*domain.Document, error) {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var parentID string
+	for _, name := range names[:len(names)-1] {
+		folder, err := s.folderRepo.GetFolderByName(ctx, owner, parentID, name)
+		if err != nil {
+			return nil, err
+		}
+		parentID = folder.ID
+	}
+
+	return s.docRepo.GetDocumentByName(ctx, owner, parentID, names[len(names)-1])
+}
+
+func (s *folderService) ownFolder(ctx context.Context, id, owner string) (*domain.Folder, error) {
+	folder, err := s.folderRepo.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder.Owner != owner {
+		return nil, ErrAccessDenied
+	}
+	return folder, nil
+}
+
+func validName(name string) bool {
+	return strings.TrimSpace(name) != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+func folderError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		return ErrFolderExists
+	case errors.Is(err, repository.ErrCycle):
+		return ErrFolderCycle
+	case errors.Is(err, repository.ErrNotEmpty):
+		return ErrFolderNotEmpty
+	default:
+		return err
+	}
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFolderService_CreateFolder_Success(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "parent").Return(&domain.Folder{ID: "parent", Owner: "user123"}, nil)
+	mockFolderRepo.On("CreateFolder", mock.Anything, mock.MatchedBy(func(f *domain.Folder) bool {
+		return f.Name == "2026" && f.ParentID == "parent" && f.Owner == "user123" && f.ID != ""
+	})).Return(nil)
+
+	folder, err := folderService.CreateFolder(context.Background(), "2026", "parent", "user123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2026", folder.Name)
+	mockFolderRepo.AssertExpectations(t)
+}
+
+func TestFolderService_CreateFolder_InvalidName(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	for _, name := range []string{"", "  ", "..", "a/b"} {
+		_, err := folderService.CreateFolder(context.Background(), name, "", "user123")
+		assert.ErrorIs(t, err, service.ErrInvalidName, name)
+	}
+	mockFolderRepo.AssertNotCalled(t, "CreateFolder")
+}
+
+func TestFolderService_CreateFolder_ForeignParent(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "parent").Return(&domain.Folder{ID: "parent", Owner: "owner1"}, nil)
+
+	_, err := folderService.CreateFolder(context.Background(), "reports", "parent", "user123")
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockFolderRepo.AssertNotCalled(t, "CreateFolder")
+}
+
+func TestFolderService_CreateFolder_Exists(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("CreateFolder", mock.Anything, mock.Anything).Return(repository.ErrConflict)
+
+	_, err := folderService.CreateFolder(context.Background(), "reports", "", "user123")
+
+	assert.ErrorIs(t, err, service.ErrFolderExists)
+}
+
+func TestFolderService_UpdateFolder_Move(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "f1").Return(&domain.Folder{ID: "f1", Name: "q3", Owner: "user123"}, nil)
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "f2").Return(&domain.Folder{ID: "f2", Owner: "user123"}, nil)
+	mockFolderRepo.On("UpdateFolder", mock.Anything, mock.MatchedBy(func(f *domain.Folder) bool {
+		return f.ID == "f1" && f.Name == "Q3" && f.ParentID == "f2"
+	})).Return(nil)
+
+	name, parent := "Q3", "f2"
+	folder, err := folderService.UpdateFolder(context.Background(), "f1", "user123", &domain.FolderPatch{
+		Name:     &name,
+		ParentID: &parent,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "f2", folder.ParentID)
+	mockFolderRepo.AssertExpectations(t)
+}
+
+func TestFolderService_UpdateFolder_Cycle(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "f1").Return(&domain.Folder{ID: "f1", Owner: "user123"}, nil)
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "child").Return(&domain.Folder{ID: "child", ParentID: "f1", Owner: "user123"}, nil)
+	mockFolderRepo.On("UpdateFolder", mock.Anything, mock.Anything).Return(repository.ErrCycle)
+
+	parent := "child"
+	_, err := folderService.UpdateFolder(context.Background(), "f1", "user123", &domain.FolderPatch{ParentID: &parent})
+
+	assert.ErrorIs(t, err, service.ErrFolderCycle)
+}
+
+func TestFolderService_DeleteFolder_NotEmpty(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("DeleteFolder", mock.Anything, "f1", "user123").Return(repository.ErrNotEmpty)
+
+	err := folderService.DeleteFolder(context.Background(), "f1", "user123")
+
+	assert.ErrorIs(t, err, service.ErrFolderNotEmpty)
+}
+
+func TestFolderService_GetFolder_Root(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetSubfolders", mock.Anything, "user123", "").Return([]domain.Folder{{ID: "f1", Name: "reports"}}, nil)
+	mockDocRepo.On("GetFolderDocuments", mock.Anything, "user123", "").Return([]domain.Document{{ID: "d1", Name: "notes.txt"}}, nil)
+
+	contents, err := folderService.GetFolder(context.Background(), "", "user123")
+
+	assert.NoError(t, err)
+	assert.Nil(t, contents.Folder)
+	assert.Len(t, contents.Folders, 1)
+	assert.Len(t, contents.Documents, 1)
+	mockFolderRepo.AssertNotCalled(t, "GetFolderByID")
+}
+
+func TestFolderService_ResolvePath_Success(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByName", mock.Anything, "user123", "", "reports").Return(&domain.Folder{ID: "f1"}, nil)
+	mockFolderRepo.On("GetFolderByName", mock.Anything, "user123", "f1", "2026").Return(&domain.Folder{ID: "f2"}, nil)
+	mockDocRepo.On("GetDocumentByName", mock.Anything, "user123", "f2", "q3.pdf").Return(&domain.Document{ID: "d1"}, nil)
+
+	doc, err := folderService.ResolvePath(context.Background(), "user123", "/reports/2026/q3.pdf")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "d1", doc.ID)
+	mockFolderRepo.AssertExpectations(t)
+	mockDocRepo.AssertExpectations(t)
+}
+
+func TestFolderService_ResolvePath_MissingFolder(t *testing.T) {
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	folderService := service.NewFolderService(mockFolderRepo, mockDocRepo)
+
+	mockFolderRepo.On("GetFolderByName", mock.Anything, "user123", "", "reports").Return(nil, repository.ErrNotFound)
+
+	_, err := folderService.ResolvePath(context.Background(), "user123", "/reports/2026/q3.pdf")
+
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	mockDocRepo.AssertNotCalled(t, "GetDocumentByName")
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrGroupExists   = errors.New("group with this name already exists")
+	ErrUnknownGroup  = errors.New("unknown group")
+	ErrAlreadyMember = errors.New("user is already a member of the group")
+	ErrGroupOwner    = errors.New("group owner cannot leave the group")
+)
+
+var groupNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{2,50}$`)
+
+type GroupService interface {
+	CreateGroup(ctx context.Context, name, userID, login string) (*domain.Group, error)
+	GetGroups(ctx context.Context, userID string) ([]domain.Group, error)
+	GetGroup(ctx context.Context, name, userID string) (*domain.Group, error)
+	AddMember(ctx context.Context, name, userID, login string) (*domain.Group, error)
+	RemoveMember(ctx context.Context, name, userID, login string) error
+	DeleteGroup(ctx context.Context, name, userID string) error
+}
+
+type groupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+	auditor   *Auditor
+	cacheRepo repository.CacheRepository
+}
+
+func NewGroupService(
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
+	auditor *Auditor,
+	cacheRepo repository.CacheRepository,
+) GroupService {
+	return &groupService{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		auditor:   auditor,
+		cacheRepo: cacheRepo,
+	}
+}
+
+// CreateGroup creates a group owned by the user, who becomes its first
+// member. Group names are unique across all users.
+func (s *groupService) CreateGroup(ctx context.Context, name, userID, login string) (*domain.Group, error) {
+	if !groupNamePattern.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	group := &domain.Group{
+		ID:      utils.GenerateID(),
+		Name:    name,
+		Created: time.Now(),
+		Members: []domain.GroupMember{{ID: userID, Login: login}},
+		Owner:   userID,
+	}
+
+	err := s.groupRepo.CreateGroup(ctx, group)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, ErrGroupExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetGroups lists the groups the user is a member of.
+func (s *groupService) GetGroups(ctx context.Context, userID string) ([]domain.Group, error) {
+	return s.groupRepo.GetUserGroups(ctx, userID)
+}
+
+// GetGroup returns the group with its members; only members can see it.
+func (s *groupService) GetGroup(ctx context.Context, name, userID string) (*domain.Group, error) {
+	group, err := s.groupRepo.GetGroupByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember(group, userID) {
+		return nil, ErrAccessDenied
+	}
+	return group, nil
+}
+
+// AddMember adds the user with the login to the group. Only the owner can
+// add members. Since it shares the group's documents with the user, it is
+// audited as sharing.
+func (s *groupService) AddMember(ctx context.Context, name, userID, login string) (_ *domain.Group, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditShare,
+			Detail: "group " + name + " add " + login}, err)
+	}()
+
+	group, err := s.ownGroup(ctx, name, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.groupRepo.AddMember(ctx, group.ID, user.ID)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, ErrAlreadyMember
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateMember(ctx, user.ID)
+	group.Members = append(group.Members, domain.GroupMember{ID: user.ID, Login: user.Login})
+	return group, nil
+}
+
+// RemoveMember removes the member with the login from the group. The owner
+// can remove anyone but themselves; other members can only leave.
+func (s *groupService) RemoveMember(ctx context.Context, name, userID, login string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditShare,
+			Detail: "group " + name + " remove " + login}, err)
+	}()
+
+	group, err := s.groupRepo.GetGroupByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var member *domain.GroupMember
+	for i := range group.Members {
+		if group.Members[i].Login == login {
+			member = &group.Members[i]
+		}
+	}
+
+	if group.Owner != userID && (member == nil || member.ID != userID) {
+		return ErrAccessDenied
+	}
+	if member == nil {
+		return ErrNotFound
+	}
+	if member.ID == group.Owner {
+		return ErrGroupOwner
+	}
+
+	if err := s.groupRepo.RemoveMember(ctx, group.ID, member.ID); err != nil {
+		return err
+	}
+
+	s.invalidateMember(ctx, member.ID)
+	return nil
+}
+
+// DeleteGroup deletes the group together with every grant made to it. Only
+// the owner can delete a group. Since it unshares the group's documents, it is
+// audited as sharing.
+func (s *groupService) DeleteGroup(ctx context.Context, name, userID string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditShare,
+			Detail: "group " + name + " delete"}, err)
+	}()
+
+	group, err := s.ownGroup(ctx, name, userID)
+	if err != nil {
+		return err
+	}
+
+	docIDs, err := s.groupRepo.DeleteGroup(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range group.Members {
+		s.invalidateMember(ctx, member.ID)
+	}
+	for _, id := range docIDs {
+		s.cacheRepo.DeletePattern(ctx, "doc:"+id+"*")
+	}
+	// The grant lists shown in the listings of everyone else the documents
+	// are shared with are outdated as well.
+	if len(docIDs) > 0 {
+		s.cacheRepo.DeletePattern(ctx, "docs:*")
+	}
+	return nil
+}
+
+func (s *groupService) ownGroup(ctx context.Context, name, userID string) (*domain.Group, error) {
+	group, err := s.groupRepo.GetGroupByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.Owner != userID {
+		return nil, ErrAccessDenied
+	}
+	return group, nil
+}
+
+// invalidateMember drops the cached documents and listings of a user whose
+// group membership changed, as they were cached with the old access.
+func (s *groupService) invalidateMember(ctx context.Context, userID string) {
+	s.cacheRepo.DeletePattern(ctx, "doc:*:"+userID)
+	s.cacheRepo.DeletePattern(ctx, "docs:"+userID+":*")
+}
+
+func isMember(group *domain.Group, userID string) bool {
+	for _, member := range group.Members {
+		if member.ID == userID {
+			return true
+		}
+	}
+	return false
+}
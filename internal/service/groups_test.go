@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testGroup() *domain.Group {
+	return &domain.Group{
+		ID:    "g1",
+		Name:  "finance",
+		Owner: "owner1",
+		Members: []domain.GroupMember{
+			{ID: "owner1", Login: "owner"},
+			{ID: "user2", Login: "member"},
+		},
+	}
+}
+
+func TestGroupService_CreateGroup(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	groupService := service.NewGroupService(mockGroupRepo, new(mocks.MockUserRepository), testAuditor(), new(mocks.MockCacheRepository))
+
+	mockGroupRepo.On("CreateGroup", mock.Anything, mock.MatchedBy(func(g *domain.Group) bool {
+		return g.Name == "finance" && g.Owner == "owner1" && len(g.Members) == 1 && g.Members[0].ID == "owner1"
+	})).Return(nil)
+
+	group, err := groupService.CreateGroup(context.Background(), "finance", "owner1", "owner")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", group.Members[0].Login)
+	mockGroupRepo.AssertExpectations(t)
+}
+
+func TestGroupService_CreateGroup_InvalidOrTaken(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	groupService := service.NewGroupService(mockGroupRepo, new(mocks.MockUserRepository), testAuditor(), new(mocks.MockCacheRepository))
+
+	mockGroupRepo.On("CreateGroup", mock.Anything, mock.Anything).Return(repository.ErrConflict)
+
+	_, err := groupService.CreateGroup(context.Background(), "group:x", "owner1", "owner")
+	assert.ErrorIs(t, err, service.ErrInvalidName)
+
+	_, err = groupService.CreateGroup(context.Background(), "finance", "owner1", "owner")
+	assert.ErrorIs(t, err, service.ErrGroupExists)
+}
+
+func TestGroupService_AddMember_InvalidatesCache(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	groupService := service.NewGroupService(mockGroupRepo, mockUserRepo, testAuditor(), mockCacheRepo)
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "newbie").Return(&domain.User{ID: "user3", Login: "newbie"}, nil)
+	mockGroupRepo.On("AddMember", mock.Anything, "g1", "user3").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "doc:*:user3").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:user3:*").Return(nil)
+
+	group, err := groupService.AddMember(context.Background(), "finance", "owner1", "newbie")
+
+	assert.NoError(t, err)
+	assert.Len(t, group.Members, 3)
+	mockGroupRepo.AssertExpectations(t)
+	mockCacheRepo.AssertExpectations(t)
+}
+
+func TestGroupService_AddMember_NotOwner(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	groupService := service.NewGroupService(mockGroupRepo, mockUserRepo, testAuditor(), new(mocks.MockCacheRepository))
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+
+	_, err := groupService.AddMember(context.Background(), "finance", "user2", "newbie")
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockGroupRepo.AssertNotCalled(t, "AddMember")
+}
+
+func TestGroupService_RemoveMember(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	groupService := service.NewGroupService(mockGroupRepo, new(mocks.MockUserRepository), testAuditor(), mockCacheRepo)
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+	mockGroupRepo.On("RemoveMember", mock.Anything, "g1", "user2").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	// The owner cannot leave, and members can only remove themselves.
+	err := groupService.RemoveMember(context.Background(), "finance", "owner1", "owner")
+	assert.ErrorIs(t, err, service.ErrGroupOwner)
+
+	err = groupService.RemoveMember(context.Background(), "finance", "user2", "owner")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	err = groupService.RemoveMember(context.Background(), "finance", "owner1", "stranger")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	err = groupService.RemoveMember(context.Background(), "finance", "user2", "member")
+	assert.NoError(t, err)
+	mockGroupRepo.AssertNumberOfCalls(t, "RemoveMember", 1)
+	mockCacheRepo.AssertCalled(t, "DeletePattern", mock.Anything, "doc:*:user2")
+}
+
+func TestGroupService_DeleteGroup(t *testing.T) {
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	groupService := service.NewGroupService(mockGroupRepo, new(mocks.MockUserRepository), testAuditor(), mockCacheRepo)
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+	mockGroupRepo.On("DeleteGroup", mock.Anything, "g1").Return([]string{"doc1"}, nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	err := groupService.DeleteGroup(context.Background(), "finance", "owner1")
+
+	assert.NoError(t, err)
+	mockCacheRepo.AssertCalled(t, "DeletePattern", mock.Anything, "doc:*:user2")
+	mockCacheRepo.AssertCalled(t, "DeletePattern", mock.Anything, "doc:doc1*")
+	mockCacheRepo.AssertCalled(t, "DeletePattern", mock.Anything, "docs:*")
+}
+
+func TestDocumentService_GetDocument_AccessGranted_ByGroup(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	access := service.NewAccessControl(new(mocks.MockUserRepository), mockGroupRepo)
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), access, testAuditor(), mockCacheRepo,
+		new(mocks.MockBlobStore))
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{
+			{Grantee: "member", Role: domain.RoleViewer, UserID: "user2"},
+			{Grantee: "group:finance", Role: domain.RoleEditor, GroupID: "g1"},
+		},
+	}, nil)
+	mockCacheRepo.On("GetDocument", mock.Anything, mock.Anything).Return(nil, errors.New("cache miss"))
+	mockCacheRepo.On("SetDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockGroupRepo.On("GetUserGroups", mock.Anything, "user2").Return([]domain.Group{{ID: "g1", Name: "finance"}}, nil)
+	mockGroupRepo.On("GetUserGroups", mock.Anything, "user3").Return([]domain.Group{{ID: "g2", Name: "sales"}}, nil)
+	mockDocRepo.On("AddVersion", mock.Anything, mock.Anything).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "finance").Return(testGroup(), nil)
+
+	doc, _, err := docService.GetDocument(context.Background(), "123", "user2", "member")
+	assert.NoError(t, err)
+	assert.Equal(t, "123", doc.ID)
+
+	_, _, err = docService.GetDocument(context.Background(), "123", "user3", "stranger")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	// The group grant outranks the direct one.
+	_, err = docService.UpdateDocument(context.Background(), "123", "user2", "member", nil, `{"a":1}`)
+	assert.NoError(t, err)
+	mockCacheRepo.AssertCalled(t, "DeletePattern", mock.Anything, "docs:*user2*")
+}
+
+func TestDocumentService_UploadDocument_UnknownGroup(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockGroupRepo := new(mocks.MockGroupRepository)
+	access := service.NewAccessControl(new(mocks.MockUserRepository), mockGroupRepo)
+	docService := service.NewDocumentService(mockDocRepo, new(mocks.MockFolderRepository), access, testAuditor(),
+		new(mocks.MockCacheRepository), new(mocks.MockBlobStore))
+
+	mockGroupRepo.On("GetGroupByName", mock.Anything, "nobody").Return(nil, repository.ErrNotFound)
+
+	meta := &domain.DocumentMeta{
+		Name:  "doc.json",
+		Grant: []domain.Grant{{Grantee: "group:nobody"}},
+	}
+	_, err := docService.UploadDocument(context.Background(), meta, nil, `{}`, "owner1")
+
+	assert.ErrorIs(t, err, service.ErrUnknownGroup)
+	mockDocRepo.AssertNotCalled(t, "CreateDocument")
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+)
+
+type KeyService interface {
+	RefreshKeys(ctx context.Context) error
+	JWKS() jwt.JWKS
+}
+
+type keyService struct {
+	keyRepo    repository.SigningKeyRepository
+	jwtManager *jwt.Manager
+	algorithm  string
+	rotation   time.Duration
+	retention  time.Duration
+}
+
+// NewKeyService creates a service that rotates the signing key every
+// rotation period. A replaced key stays published and keeps validating tokens
+// for the retention period, which must cover the time it may still sign
+// while its successor is published and the lifetime of the tokens it signed.
+func NewKeyService(
+	keyRepo repository.SigningKeyRepository,
+	jwtManager *jwt.Manager,
+	algorithm string,
+	rotation time.Duration,
+	retention time.Duration,
+) KeyService {
+	return &keyService{
+		keyRepo:    keyRepo,
+		jwtManager: jwtManager,
+		algorithm:  algorithm,
+		rotation:   rotation,
+		retention:  retention,
+	}
+}
+
+// RefreshKeys rotates the signing key when it is due and loads the current
+// keys into the token manager.
+func (s *keyService) RefreshKeys(ctx context.Context) error {
+	now := time.Now()
+
+	stored, err := s.keyRepo.GetSigningKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if s.rotationDue(stored, now) {
+		key, err := jwt.GenerateKey(s.algorithm)
+		if err != nil {
+			return err
+		}
+
+		privatePEM, err := key.MarshalPrivate()
+		if err != nil {
+			return err
+		}
+
+		err = s.keyRepo.RotateSigningKey(ctx, &domain.SigningKey{
+			ID:         key.ID,
+			Algorithm:  key.Algorithm,
+			PrivateKey: privatePEM,
+			Created:    key.Created,
+		}, now.Add(-s.rotation), now.Add(s.retention))
+		if err != nil {
+			return err
+		}
+
+		// Another instance may have rotated first, so the new key is read
+		// back rather than used directly.
+		stored, err = s.keyRepo.GetSigningKeys(ctx, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(stored) == 0 {
+		return errors.New("no signing keys")
+	}
+
+	keys := make([]*jwt.Key, 0, len(stored))
+	for _, k := range stored {
+		key, err := jwt.ParseKey(k.ID, k.Algorithm, k.PrivateKey, k.Created)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	s.jwtManager.SetKeys(keys)
+
+	return s.keyRepo.DeleteRetiredKeys(ctx, now)
+}
+
+// rotationDue reports whether the newest key is too old or was made for
+// another algorithm. Keys come newest first.
+func (s *keyService) rotationDue(keys []domain.SigningKey, now time.Time) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	newest := keys[0]
+	return newest.Algorithm != s.algorithm || !newest.Created.After(now.Add(-s.rotation))
+}
+
+func (s *keyService) JWKS() jwt.JWKS {
+	return s.jwtManager.JWKS()
+}
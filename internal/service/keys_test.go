@@ -0,0 +1,187 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func storedKey(t *testing.T, algorithm string, created time.Time) domain.SigningKey {
+	key, err := jwt.GenerateKey(algorithm)
+	require.NoError(t, err)
+	privatePEM, err := key.MarshalPrivate()
+	require.NoError(t, err)
+	return domain.SigningKey{ID: key.ID, Algorithm: algorithm, PrivateKey: privatePEM, Created: created}
+}
+
+func TestKeyService_RefreshKeys_CreatesFirstKey(t *testing.T) {
+	mockKeyRepo := new(mocks.MockSigningKeyRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.EdDSA, ""))
+	keyService := service.NewKeyService(mockKeyRepo, jwtManager, jwt.EdDSA, 720*time.Hour, 16*time.Minute)
+
+	var rotated domain.SigningKey
+	mockKeyRepo.On("GetSigningKeys", mock.Anything, mock.Anything).Return([]domain.SigningKey{}, nil).Once()
+	mockKeyRepo.On("RotateSigningKey", mock.Anything, mock.AnythingOfType("*domain.SigningKey"), mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			rotated = *args.Get(1).(*domain.SigningKey)
+			mockKeyRepo.On("GetSigningKeys", mock.Anything, mock.Anything).Return([]domain.SigningKey{rotated}, nil)
+		}).Return(nil)
+	mockKeyRepo.On("DeleteRetiredKeys", mock.Anything, mock.Anything).Return(nil)
+
+	err := keyService.RefreshKeys(context.Background())
+
+	require.NoError(t, err)
+	jwks := keyService.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, rotated.ID, jwks.Keys[0].ID)
+	assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+
+	token, _, err := jwtManager.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	require.NoError(t, err)
+	claims, err := jwtManager.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", claims.UserID)
+}
+
+func TestKeyService_RefreshKeys_OldKeyStillValidates(t *testing.T) {
+	mockKeyRepo := new(mocks.MockSigningKeyRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.RS256, ""))
+	keyService := service.NewKeyService(mockKeyRepo, jwtManager, jwt.RS256, 720*time.Hour, 16*time.Minute)
+
+	oldKey := storedKey(t, jwt.RS256, time.Now().Add(-time.Hour))
+	mockKeyRepo.On("GetSigningKeys", mock.Anything, mock.Anything).Return([]domain.SigningKey{oldKey}, nil).Once()
+	mockKeyRepo.On("DeleteRetiredKeys", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, keyService.RefreshKeys(context.Background()))
+
+	oldToken, _, err := jwtManager.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	require.NoError(t, err)
+
+	newKey := storedKey(t, jwt.RS256, time.Now())
+	retires := time.Now().Add(16 * time.Minute)
+	oldKey.Retires = &retires
+	mockKeyRepo.On("GetSigningKeys", mock.Anything, mock.Anything).Return([]domain.SigningKey{newKey, oldKey}, nil).Once()
+	require.NoError(t, keyService.RefreshKeys(context.Background()))
+
+	_, err = jwtManager.ValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Len(t, keyService.JWKS().Keys, 2)
+	assert.Equal(t, newKey.ID, keyService.JWKS().Keys[0].ID)
+	mockKeyRepo.AssertNotCalled(t, "RotateSigningKey")
+}
+
+func TestKeyService_RefreshKeys_RotatesExpiredKey(t *testing.T) {
+	mockKeyRepo := new(mocks.MockSigningKeyRepository)
+	jwtManager := jwt.NewManager(jwtConfig(jwt.RS256, ""))
+	keyService := service.NewKeyService(mockKeyRepo, jwtManager, jwt.RS256, 720*time.Hour, 16*time.Minute)
+
+	oldKey := storedKey(t, jwt.RS256, time.Now().Add(-721*time.Hour))
+	mockKeyRepo.On("GetSigningKeys", mock.Anything, mock.Anything).Return([]domain.SigningKey{oldKey}, nil)
+	mockKeyRepo.On("RotateSigningKey", mock.Anything, mock.MatchedBy(func(key *domain.SigningKey) bool {
+		return key.Algorithm == jwt.RS256 && key.ID != oldKey.ID
+	}), mock.Anything, mock.Anything).Return(nil)
+	mockKeyRepo.On("DeleteRetiredKeys", mock.Anything, mock.Anything).Return(nil)
+
+	err := keyService.RefreshKeys(context.Background())
+
+	assert.NoError(t, err)
+	mockKeyRepo.AssertExpectations(t)
+}
+
+func TestJWTManager_RejectsTokenFromUnknownKey(t *testing.T) {
+	signer := jwt.NewManager(jwtConfig(jwt.EdDSA, ""))
+	key, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+	signer.SetKeys([]*jwt.Key{key})
+	token, _, err := signer.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	require.NoError(t, err)
+
+	verifier := jwt.NewManager(jwtConfig(jwt.EdDSA, ""))
+	other, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+	verifier.SetKeys([]*jwt.Key{other})
+
+	_, err = verifier.ValidateToken(token)
+	assert.Error(t, err)
+
+	_, err = jwt.NewManager(jwtConfig(jwt.EdDSA, "")).ValidateToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTManager_ReloadsKeysOnUnknownKey(t *testing.T) {
+	signer := jwt.NewManager(jwtConfig(jwt.EdDSA, ""))
+	key, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+	signer.SetKeys([]*jwt.Key{key})
+	token, _, err := signer.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+	require.NoError(t, err)
+
+	verifier := jwt.NewManager(jwtConfig(jwt.EdDSA, ""))
+	other, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+	verifier.SetKeys([]*jwt.Key{other})
+
+	reloads := 0
+	verifier.ReloadKeysOnMiss(func() error {
+		reloads++
+		return nil
+	}, time.Hour)
+
+	// A key that is not in the store either is looked for once per interval.
+	_, err = verifier.ValidateToken(token)
+	assert.Error(t, err)
+	_, err = verifier.ValidateToken(token)
+	assert.Error(t, err)
+	assert.Equal(t, 1, reloads)
+
+	verifier.ReloadKeysOnMiss(func() error {
+		reloads++
+		verifier.SetKeys([]*jwt.Key{key, other})
+		return nil
+	}, 0)
+
+	claims, err := verifier.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user123", claims.UserID)
+	assert.Equal(t, 2, reloads)
+}
+
+func TestJWTManager_SignsOnlyWithPublishedKey(t *testing.T) {
+	cfg := jwtConfig(jwt.EdDSA, "")
+	cfg.PublishDelay = 5 * time.Minute
+	manager := jwt.NewManager(cfg)
+
+	oldKey, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+	oldKey.Created = time.Now().Add(-time.Hour)
+	newKey, err := jwt.GenerateKey(jwt.EdDSA)
+	require.NoError(t, err)
+
+	signedBy := func(key *jwt.Key) bool {
+		token, _, err := manager.GenerateToken("user123", "testuser", "family1", domain.DefaultScopes)
+		require.NoError(t, err)
+		verifier := jwt.NewManager(cfg)
+		verifier.SetKeys([]*jwt.Key{key})
+		_, err = verifier.ValidateToken(token)
+		return err == nil
+	}
+
+	// The first key signs right away, as there is no other.
+	manager.SetKeys([]*jwt.Key{newKey})
+	assert.True(t, signedBy(newKey))
+
+	// A new key is published at once but signs only after the delay.
+	manager.SetKeys([]*jwt.Key{newKey, oldKey})
+	assert.Len(t, manager.JWKS().Keys, 2)
+	assert.True(t, signedBy(oldKey))
+
+	newKey.Created = time.Now().Add(-6 * time.Minute)
+	assert.True(t, signedBy(newKey))
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrInvalidLink      = errors.New("invalid share link")
+	ErrLinkExpired      = errors.New("share link has expired")
+	ErrLinkPassword     = errors.New("share link password is missing or incorrect")
+	ErrInvalidDownloads = errors.New("max_downloads must not be negative")
+)
+
+const linkPrefixLength = 8
+
+type LinkService interface {
+	CreateLink(ctx context.Context, docID, userID, login string, expires *time.Time, password string, maxDownloads int) (*domain.ShareLink, string, error)
+	GetLinks(ctx context.Context, docID, userID, login string) ([]domain.ShareLink, error)
+	RevokeLink(ctx context.Context, docID, linkID, userID, login string) error
+	GetAccesses(ctx context.Context, docID, linkID, userID, login string) ([]domain.LinkAccess, error)
+	OpenLink(ctx context.Context, token, password string, access domain.LinkAccess, download func(*domain.Document) bool) (*domain.Document, io.ReadSeekCloser, error)
+}
+
+type linkService struct {
+	linkRepo    repository.ShareLinkRepository
+	docRepo     repository.DocumentRepository
+	access      *AccessControl
+	auditor     *Auditor
+	attemptRepo repository.LoginAttemptRepository
+	blobStore   repository.BlobStore
+	throttle    LoginThrottle
+}
+
+func NewLinkService(
+	linkRepo repository.ShareLinkRepository,
+	docRepo repository.DocumentRepository,
+	access *AccessControl,
+	auditor *Auditor,
+	attemptRepo repository.LoginAttemptRepository,
+	blobStore repository.BlobStore,
+	throttle LoginThrottle,
+) LinkService {
+	return &linkService{
+		linkRepo:    linkRepo,
+		docRepo:     docRepo,
+		access:      access,
+		auditor:     auditor,
+		attemptRepo: attemptRepo,
+		blobStore:   blobStore,
+		throttle:    throttle,
+	}
+}
+
+func linkKey(id string) string { return "link:" + id }
+
+// CreateLink creates a share link to the document and returns it together
+// with its token, which is not kept and cannot be shown again. The owner and
+// co-owners may do so. A maxDownloads of 0 means no limit.
+func (s *linkService) CreateLink(ctx context.Context, docID, userID, login string, expires *time.Time, password string, maxDownloads int) (link *domain.ShareLink, token string, err error) {
+	defer func() {
+		event := domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditShare, DocumentID: docID, Detail: "create link"}
+		if link != nil {
+			event.Detail += " " + link.ID
+		}
+		s.auditor.record(ctx, event, err)
+	}()
+
+	if err := s.checkCoOwner(ctx, docID, userID, login); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if expires != nil && !expires.After(now) {
+		return nil, "", ErrInvalidExpiry
+	}
+	if maxDownloads < 0 {
+		return nil, "", ErrInvalidDownloads
+	}
+	if len(password) > maxPasswordLength {
+		return nil, "", fmt.Errorf("%w: must be at most %d bytes long", ErrWeakPassword, maxPasswordLength)
+	}
+
+	token = randomToken()
+	link = &domain.ShareLink{
+		ID:         utils.GenerateID(),
+		DocumentID: docID,
+		Prefix:     token[:linkPrefixLength],
+		Protected:  password != "",
+		Created:    now,
+		Expires:    expires,
+		CreatedBy:  userID,
+		Hash:       utils.Checksum([]byte(token)),
+	}
+	if maxDownloads > 0 {
+		link.MaxDownloads = &maxDownloads
+	}
+	if password != "" {
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, "", err
+		}
+		link.Password = hash
+	}
+
+	if err := s.linkRepo.CreateLink(ctx, link); err != nil {
+		return nil, "", err
+	}
+
+	return link, token, nil
+}
+
+func (s *linkService) GetLinks(ctx context.Context, docID, userID, login string) ([]domain.ShareLink, error) {
+	if err := s.checkCoOwner(ctx, docID, userID, login); err != nil {
+		return nil, err
+	}
+
+	return s.linkRepo.GetDocumentLinks(ctx, docID)
+}
+
+// RevokeLink stops the link from working; it stays listed with its accesses.
+func (s *linkService) RevokeLink(ctx context.Context, docID, linkID, userID, login string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditShare, DocumentID: docID,
+			Detail: "revoke link " + linkID}, err)
+	}()
+
+	if err := s.checkCoOwner(ctx, docID, userID, login); err != nil {
+		return err
+	}
+
+	return s.linkRepo.RevokeLink(ctx, docID, linkID, time.Now())
+}
+
+// GetAccesses lists the attempts to open the link, most recent first.
+func (s *linkService) GetAccesses(ctx context.Context, docID, linkID, userID, login string) ([]domain.LinkAccess, error) {
+	links, err := s.GetLinks(ctx, docID, userID, login)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link.ID == linkID {
+			return s.linkRepo.GetAccesses(ctx, linkID)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// OpenLink returns the document a share link points to and, for file
+// documents, a reader over its content that the caller must close. Every
+// attempt to open an existing link is logged, and wrong passwords are
+// throttled per link like failed logins. download reports whether the request
+// gets the whole document rather than a part of it or a not-modified answer;
+// only such requests count against the download limit.
+func (s *linkService) OpenLink(ctx context.Context, token, password string, access domain.LinkAccess, download func(*domain.Document) bool) (*domain.Document, io.ReadSeekCloser, error) {
+	link, err := s.linkRepo.GetLinkByHash(ctx, utils.Checksum([]byte(token)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidLink
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access.LinkID = link.ID
+	access.Time = time.Now()
+
+	doc, content, err := s.openLink(ctx, link, password, &access, download)
+
+	// Failing to log the access is no reason to refuse it.
+	s.linkRepo.LogAccess(ctx, &access)
+	s.auditor.record(ctx, domain.AuditEvent{Action: domain.AuditRead, DocumentID: link.DocumentID,
+		Detail: "link " + link.ID}, err)
+
+	return doc, content, err
+}
+
+func (s *linkService) openLink(ctx context.Context, link *domain.ShareLink, password string, access *domain.LinkAccess, download func(*domain.Document) bool) (*domain.Document, io.ReadSeekCloser, error) {
+	access.Outcome = domain.LinkFailed
+
+	if outcome := linkState(link, access.Time); outcome != "" {
+		access.Outcome = outcome
+		return nil, nil, ErrLinkExpired
+	}
+
+	if link.Protected {
+		if outcome, err := s.checkLinkPassword(ctx, link, password); err != nil {
+			if outcome != "" {
+				access.Outcome = outcome
+			}
+			return nil, nil, err
+		}
+	}
+
+	doc, err := s.docRepo.GetDocumentByID(ctx, link.DocumentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The content is opened first, so a download that cannot be served is
+	// not counted.
+	var content io.ReadSeekCloser
+	if doc.File {
+		content, err = s.blobStore.Get(ctx, doc.StorageKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if download(doc) {
+		counted, err := s.linkRepo.UseLink(ctx, link.ID, access.Time)
+		if err == nil && !counted {
+			// Another request may have taken the last download in the meantime.
+			access.Outcome = domain.LinkUsedUp
+			err = ErrLinkExpired
+		}
+		if err != nil {
+			if content != nil {
+				content.Close()
+			}
+			return nil, nil, err
+		}
+	}
+
+	access.Outcome = domain.LinkServed
+	return doc, content, nil
+}
+
+// checkLinkPassword returns the outcome to log along with the error for a
+// missing, wrong or throttled password.
+func (s *linkService) checkLinkPassword(ctx context.Context, link *domain.ShareLink, password string) (string, error) {
+	lockedFor, err := s.attemptRepo.LockedFor(ctx, linkKey(link.ID))
+	if err != nil {
+		return "", err
+	}
+	if lockedFor > 0 {
+		return domain.LinkThrottled, &ThrottledError{RetryAfter: lockedFor}
+	}
+
+	// Asking for the password is not a failed attempt.
+	if password == "" {
+		return domain.LinkNoPassword, ErrLinkPassword
+	}
+
+	if !utils.CheckPasswordHash(password, link.Password) {
+		failures, err := s.attemptRepo.FailLogin(ctx, linkKey(link.ID), s.throttle.Window)
+		if err != nil {
+			return "", err
+		}
+		if delay := s.throttle.loginDelay(failures); delay > 0 {
+			if err := s.attemptRepo.Lock(ctx, linkKey(link.ID), delay); err != nil {
+				return "", err
+			}
+		}
+		return domain.LinkWrongPassword, ErrLinkPassword
+	}
+
+	return "", s.attemptRepo.ResetLogin(ctx, linkKey(link.ID))
+}
+
+func (s *linkService) checkCoOwner(ctx context.Context, docID, userID, login string) error {
+	doc, err := s.docRepo.GetDocumentByID(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	return s.access.authorize(ctx, doc, s.access.principal(userID, login), domain.RoleCoOwner)
+}
+
+// linkState returns the outcome for a link that can no longer be used, or ""
+// for one that can.
+func linkState(link *domain.ShareLink, now time.Time) string {
+	switch {
+	case link.Revoked != nil:
+		return domain.LinkRevoked
+	case link.Expires != nil && !link.Expires.After(now):
+		return domain.LinkExpired
+	case link.MaxDownloads != nil && link.Downloads >= *link.MaxDownloads:
+		return domain.LinkUsedUp
+	default:
+		return ""
+	}
+}
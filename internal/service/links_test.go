@@ -0,0 +1,266 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newLinkService(linkRepo *mocks.MockShareLinkRepository, docRepo *mocks.MockDocumentRepository,
+	attemptRepo *mocks.MockLoginAttemptRepository, blobStore *mocks.MockBlobStore) service.LinkService {
+	return service.NewLinkService(linkRepo, docRepo, testAccess(), testAuditor(), attemptRepo, blobStore, testThrottle)
+}
+
+// loggedOutcome matches the access logged with the outcome.
+func loggedOutcome(outcome string) interface{} {
+	return mock.MatchedBy(func(a *domain.LinkAccess) bool {
+		return a.Outcome == outcome && a.LinkID == "link1" && a.IP == "10.0.0.1"
+	})
+}
+
+func TestLinkService_CreateLink(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, nil, nil)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "owner1",
+		Grant: []domain.Grant{{Grantee: "viewer", Role: domain.RoleViewer}},
+	}, nil)
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.AnythingOfType("*domain.ShareLink")).Return(nil)
+
+	_, _, err := linkService.CreateLink(context.Background(), "123", "user2", "viewer", nil, "", 0)
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	_, _, err = linkService.CreateLink(context.Background(), "123", "owner1", "owner", nil, "", -1)
+	assert.ErrorIs(t, err, service.ErrInvalidDownloads)
+
+	past := time.Now().Add(-time.Minute)
+	_, _, err = linkService.CreateLink(context.Background(), "123", "owner1", "owner", &past, "", 0)
+	assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+
+	link, token, err := linkService.CreateLink(context.Background(), "123", "owner1", "owner", nil, "secret", 5)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, link.Prefix))
+	assert.Equal(t, utils.Checksum([]byte(token)), link.Hash)
+	assert.True(t, link.Protected)
+	assert.True(t, utils.CheckPasswordHash("secret", link.Password))
+	assert.Equal(t, 5, *link.MaxDownloads)
+	mockLinkRepo.AssertNumberOfCalls(t, "CreateLink", 1)
+}
+
+// download answers whether a request downloads the whole document.
+func download(full bool) func(*domain.Document) bool {
+	return func(*domain.Document) bool { return full }
+}
+
+func TestLinkService_OpenLink_Success(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, nil, mockBlobStore)
+
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, utils.Checksum([]byte("token"))).Return(&domain.ShareLink{
+		ID:         "link1",
+		DocumentID: "123",
+	}, nil)
+	mockLinkRepo.On("UseLink", mock.Anything, "link1", mock.Anything).Return(true, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:         "123",
+		File:       true,
+		StorageKey: "blob1",
+	}, nil)
+	mockBlobStore.On("Get", mock.Anything, "blob1").Return(nopSeekCloser{strings.NewReader("content")}, nil)
+	mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(domain.LinkServed)).Return(nil)
+
+	doc, content, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "123", doc.ID)
+	assert.NotNil(t, content)
+	mockLinkRepo.AssertExpectations(t)
+}
+
+func TestLinkService_OpenLink_Unusable(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	maxDownloads := 2
+
+	tests := []struct {
+		name    string
+		link    domain.ShareLink
+		outcome string
+	}{
+		{"revoked", domain.ShareLink{Revoked: &expired}, domain.LinkRevoked},
+		{"expired", domain.ShareLink{Expires: &expired}, domain.LinkExpired},
+		{"used up", domain.ShareLink{MaxDownloads: &maxDownloads, Downloads: 2}, domain.LinkUsedUp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLinkRepo := new(mocks.MockShareLinkRepository)
+			linkService := newLinkService(mockLinkRepo, new(mocks.MockDocumentRepository), nil, nil)
+
+			link := tt.link
+			link.ID = "link1"
+			mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&link, nil)
+			mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(tt.outcome)).Return(nil)
+
+			_, _, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+
+			assert.ErrorIs(t, err, service.ErrLinkExpired)
+			mockLinkRepo.AssertExpectations(t)
+			mockLinkRepo.AssertNotCalled(t, "UseLink")
+		})
+	}
+}
+
+func TestLinkService_OpenLink_LastDownloadTaken(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, nil, nil)
+
+	maxDownloads := 1
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&domain.ShareLink{
+		ID:           "link1",
+		MaxDownloads: &maxDownloads,
+	}, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, mock.Anything).Return(&domain.Document{ID: "123"}, nil)
+	mockLinkRepo.On("UseLink", mock.Anything, "link1", mock.Anything).Return(false, nil)
+	mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(domain.LinkUsedUp)).Return(nil)
+
+	doc, _, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+
+	assert.ErrorIs(t, err, service.ErrLinkExpired)
+	assert.Nil(t, doc)
+}
+
+func TestLinkService_OpenLink_PartialNotCounted(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, nil, mockBlobStore)
+
+	maxDownloads := 1
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&domain.ShareLink{
+		ID:           "link1",
+		DocumentID:   "123",
+		MaxDownloads: &maxDownloads,
+	}, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:         "123",
+		File:       true,
+		StorageKey: "blob1",
+	}, nil)
+	mockBlobStore.On("Get", mock.Anything, "blob1").Return(nopSeekCloser{strings.NewReader("content")}, nil)
+	mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(domain.LinkServed)).Return(nil)
+
+	_, content, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(false))
+
+	assert.NoError(t, err)
+	assert.NotNil(t, content)
+	mockLinkRepo.AssertNotCalled(t, "UseLink")
+}
+
+func TestLinkService_OpenLink_Password(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockAttemptRepo := newAttemptRepo()
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, mockAttemptRepo, nil)
+
+	hash, _ := utils.HashPassword("secret")
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&domain.ShareLink{
+		ID:         "link1",
+		DocumentID: "123",
+		Protected:  true,
+		Password:   hash,
+	}, nil)
+	mockLinkRepo.On("LogAccess", mock.Anything, mock.Anything).Return(nil)
+	mockAttemptRepo.On("FailLogin", mock.Anything, "link:link1", 24*time.Hour).Return(int64(1), nil)
+	mockLinkRepo.On("UseLink", mock.Anything, "link1", mock.Anything).Return(true, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{ID: "123", JSON: `{}`}, nil)
+
+	_, _, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+	assert.ErrorIs(t, err, service.ErrLinkPassword)
+	mockAttemptRepo.AssertNotCalled(t, "FailLogin")
+	mockLinkRepo.AssertCalled(t, "LogAccess", mock.Anything, loggedOutcome(domain.LinkNoPassword))
+
+	_, _, err = linkService.OpenLink(context.Background(), "token", "wrong", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+	assert.ErrorIs(t, err, service.ErrLinkPassword)
+	mockAttemptRepo.AssertNumberOfCalls(t, "FailLogin", 1)
+	mockLinkRepo.AssertCalled(t, "LogAccess", mock.Anything, loggedOutcome(domain.LinkWrongPassword))
+	mockLinkRepo.AssertNotCalled(t, "UseLink")
+
+	doc, _, err := linkService.OpenLink(context.Background(), "token", "secret", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, doc.JSON)
+	mockAttemptRepo.AssertCalled(t, "ResetLogin", mock.Anything, "link:link1")
+}
+
+func TestLinkService_OpenLink_Throttled(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	linkService := newLinkService(mockLinkRepo, new(mocks.MockDocumentRepository), mockAttemptRepo, nil)
+
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&domain.ShareLink{
+		ID:        "link1",
+		Protected: true,
+		Password:  "hash",
+	}, nil)
+	mockAttemptRepo.On("LockedFor", mock.Anything, "link:link1").Return(time.Minute, nil)
+	mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(domain.LinkThrottled)).Return(nil)
+
+	_, _, err := linkService.OpenLink(context.Background(), "token", "secret", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+
+	var throttled *service.ThrottledError
+	assert.ErrorAs(t, err, &throttled)
+	assert.ErrorIs(t, err, service.ErrTooManyAttempts)
+	mockLinkRepo.AssertExpectations(t)
+}
+
+func TestLinkService_OpenLink_UnknownToken(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	linkService := newLinkService(mockLinkRepo, new(mocks.MockDocumentRepository), nil, nil)
+
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(nil, repository.ErrNotFound)
+
+	_, _, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{}, download(true))
+
+	assert.ErrorIs(t, err, service.ErrInvalidLink)
+	mockLinkRepo.AssertNotCalled(t, "LogAccess")
+}
+
+func TestLinkService_OpenLink_ContentUnavailableNotCounted(t *testing.T) {
+	mockLinkRepo := new(mocks.MockShareLinkRepository)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	linkService := newLinkService(mockLinkRepo, mockDocRepo, nil, mockBlobStore)
+
+	mockLinkRepo.On("GetLinkByHash", mock.Anything, mock.Anything).Return(&domain.ShareLink{
+		ID:         "link1",
+		DocumentID: "123",
+	}, nil)
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:         "123",
+		File:       true,
+		StorageKey: "blob1",
+	}, nil)
+	mockBlobStore.On("Get", mock.Anything, "blob1").Return(nil, errors.New("storage unavailable"))
+	mockLinkRepo.On("LogAccess", mock.Anything, loggedOutcome(domain.LinkFailed)).Return(nil)
+
+	_, _, err := linkService.OpenLink(context.Background(), "token", "", domain.LinkAccess{IP: "10.0.0.1"}, download(true))
+
+	assert.Error(t, err)
+	mockLinkRepo.AssertNotCalled(t, "UseLink")
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/totp"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+	ErrInvalidMFACode  = errors.New("invalid MFA code")
+	ErrMFAEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled  = errors.New("two-factor authentication is not enabled")
+	ErrMFAUnavailable  = errors.New("two-factor authentication is handled by the identity provider")
+)
+
+const (
+	totpIssuer = "document-service"
+	// totpSkew is how many steps a code may be off, to allow for clock drift.
+	totpSkew = 1
+
+	mfaChallengeTTL = 5 * time.Minute
+	// maxMFAFailures wrong codes end the challenge so codes cannot be guessed.
+	maxMFAFailures = 5
+
+	recoveryCodeCount = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// challenge starts the second login step for a user with TOTP enabled.
+func (s *authService) challenge(ctx context.Context, user *domain.User, scopes []string) (*domain.MFAChallenge, error) {
+	token := randomToken()
+
+	login := &domain.PendingLogin{UserID: user.ID, Login: user.Login, Scopes: scopes}
+	if err := s.challengeRepo.SaveChallenge(ctx, utils.Checksum([]byte(token)), login, mfaChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAChallenge{Token: token, ExpiresIn: int64(mfaChallengeTTL.Seconds())}, nil
+}
+
+// VerifyMFA finishes a login that returned an MFA challenge. The code is a
+// TOTP code or one of the recovery codes. Wrong codes count as failed logins,
+// throttled per login and per client IP like wrong passwords.
+func (s *authService) VerifyMFA(ctx context.Context, ip, mfaToken, code string) (_ *domain.TokenPair, err error) {
+	var user *domain.User
+	defer func() {
+		event := domain.AuditEvent{Action: domain.AuditLogin, Detail: "mfa"}
+		if err != nil {
+			event.Action = domain.AuditFailedLogin
+		}
+		if user != nil {
+			event.ActorID, event.Actor = user.ID, user.Login
+		}
+		s.auditor.record(ctx, event, err)
+	}()
+
+	hash := utils.Checksum([]byte(mfaToken))
+	login, err := s.challengeRepo.GetChallenge(ctx, hash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkThrottle(ctx, ip, login.Login); err != nil {
+		return nil, err
+	}
+
+	user, err = s.userRepo.GetUserByID(ctx, login.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkSecondFactor(ctx, user, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		if failErr := s.failLogin(ctx, ip, login.Login); failErr != nil {
+			return nil, failErr
+		}
+		failures, failErr := s.challengeRepo.FailChallenge(ctx, hash, mfaChallengeTTL)
+		if failErr != nil {
+			return nil, failErr
+		}
+		if failures >= maxMFAFailures {
+			if err := s.challengeRepo.DeleteChallenge(ctx, hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.challengeRepo.DeleteChallenge(ctx, hash); err != nil {
+		return nil, err
+	}
+	if err := s.attemptRepo.ResetLogin(ctx, loginKey(login.Login)); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user.ID, user.Login, utils.GenerateID(), login.Scopes)
+}
+
+// EnrollTOTP generates a new secret for the user. It is not used for login
+// until ConfirmTOTP shows the authenticator app was set up with it.
+func (s *authService) EnrollTOTP(ctx context.Context, userID string) (*domain.TOTPEnrollment, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.OIDCSubject != "" {
+		return nil, ErrMFAUnavailable
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAEnabled
+	}
+
+	secret := totp.GenerateSecret()
+	err = s.userRepo.SetTOTPSecret(ctx, userID, secret)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFAEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP once the user enters a code from the newly set up
+// app and returns recovery codes, which are shown only this once.
+func (s *authService) ConfirmTOTP(ctx context.Context, userID, code string) (_ []string, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditAccount, Detail: "totp enable"}, err)
+	}()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		hashes[i] = utils.Checksum([]byte(normalizeRecoveryCode(codes[i])))
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns off the second factor; a valid code is required so a
+// stolen session cannot do it.
+func (s *authService) DisableTOTP(ctx context.Context, userID, code string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditAccount, Detail: "totp disable"}, err)
+	}()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+	return s.userRepo.DisableTOTP(ctx, userID)
+}
+
+// checkSecondFactor accepts a TOTP code that has not been used yet or an
+// unused recovery code.
+func (s *authService) checkSecondFactor(ctx context.Context, user *domain.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+	code = strings.TrimSpace(code)
+
+	var used bool
+	var err error
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew); ok {
+		used, err = s.userRepo.UseTOTPStep(ctx, user.ID, step)
+	} else {
+		hash := utils.Checksum([]byte(normalizeRecoveryCode(code)))
+		used, err = s.userRepo.UseRecoveryCode(ctx, user.ID, hash)
+	}
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// randomToken returns a token for a one-time use such as an MFA challenge.
+func randomToken() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// newRecoveryCode returns a code such as "k3m7q-x2wpa".
+func newRecoveryCode() string {
+	bytes := make([]byte, 7)
+	rand.Read(bytes)
+	code := strings.ToLower(recoveryEncoding.EncodeToString(bytes))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,236 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/totp"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const totpSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func newMFAAuthService(userRepo *mocks.MockUserRepository, challengeRepo *mocks.MockMFAChallengeRepository,
+	sessionRepo *mocks.MockSessionRepository, refreshRepo *mocks.MockRefreshTokenRepository) service.AuthService {
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+	return service.NewAuthService(userRepo, sessionRepo, refreshRepo, nil, challengeRepo, newAttemptRepo(), nil,
+		jwtManager, testAuditor(), service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+}
+
+func TestAuthService_Authenticate_MFAChallenge(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockChallengeRepo := new(mocks.MockMFAChallengeRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockAttemptRepo := newAttemptRepo()
+	authService := service.NewAuthService(mockUserRepo, mockSessionRepo, nil, nil, mockChallengeRepo, mockAttemptRepo, nil,
+		jwt.NewManager(jwtConfig(jwt.HS256, "test-secret")), testAuditor(), service.OIDCConfig{}, service.LoginThrottle{},
+		service.PasswordPolicy{}, "admin-token")
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(&domain.User{
+		ID: "user123", Login: "testuser", Password: hash, TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockChallengeRepo.On("SaveChallenge", mock.Anything, mock.AnythingOfType("string"), &domain.PendingLogin{
+		UserID: "user123", Login: "testuser", Scopes: domain.DefaultScopes,
+	}, 5*time.Minute).Return(nil)
+
+	result, err := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", nil)
+
+	require.NoError(t, err)
+	assert.Nil(t, result.TokenPair)
+	require.NotNil(t, result.MFA)
+	assert.NotEmpty(t, result.MFA.Token)
+	mockChallengeRepo.AssertCalled(t, "SaveChallenge", mock.Anything, utils.Checksum([]byte(result.MFA.Token)),
+		mock.Anything, mock.Anything)
+	mockSessionRepo.AssertNotCalled(t, "AddSession")
+	// The password alone does not reset the failed attempts.
+	mockAttemptRepo.AssertNotCalled(t, "ResetLogin", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyMFA_TOTP(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockChallengeRepo := new(mocks.MockMFAChallengeRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	authService := newMFAAuthService(mockUserRepo, mockChallengeRepo, mockSessionRepo, mockRefreshRepo)
+
+	hash := utils.Checksum([]byte("mfa-token"))
+	step := totp.Step(time.Now())
+	code, err := totp.Code(totpSecret, step)
+	require.NoError(t, err)
+
+	mockChallengeRepo.On("GetChallenge", mock.Anything, hash).Return(&domain.PendingLogin{
+		UserID: "user123", Login: "testuser", Scopes: []string{domain.ScopeDocsRead},
+	}, nil)
+	mockChallengeRepo.On("DeleteChallenge", mock.Anything, hash).Return(nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{
+		ID: "user123", Login: "testuser", TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockUserRepo.On("UseTOTPStep", mock.Anything, "user123", step).Return(true, nil).Once()
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	tokens, err := authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", code)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.ScopeDocsRead, tokens.Scope)
+	mockChallengeRepo.AssertExpectations(t)
+
+	// The same code cannot be used twice.
+	mockUserRepo.On("UseTOTPStep", mock.Anything, "user123", step).Return(false, nil)
+	mockChallengeRepo.On("FailChallenge", mock.Anything, hash, 5*time.Minute).Return(int64(1), nil)
+
+	_, err = authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+}
+
+func TestAuthService_VerifyMFA_RecoveryCode(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockChallengeRepo := new(mocks.MockMFAChallengeRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	authService := newMFAAuthService(mockUserRepo, mockChallengeRepo, mockSessionRepo, mockRefreshRepo)
+
+	hash := utils.Checksum([]byte("mfa-token"))
+	mockChallengeRepo.On("GetChallenge", mock.Anything, hash).Return(&domain.PendingLogin{UserID: "user123", Login: "testuser"}, nil)
+	mockChallengeRepo.On("DeleteChallenge", mock.Anything, hash).Return(nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{
+		ID: "user123", Login: "testuser", TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockUserRepo.On("UseRecoveryCode", mock.Anything, "user123", utils.Checksum([]byte("abcdefghij"))).Return(true, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	_, err := authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", " ABCDE-fghij ")
+
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_VerifyMFA_TooManyFailures(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockChallengeRepo := new(mocks.MockMFAChallengeRepository)
+	authService := newMFAAuthService(mockUserRepo, mockChallengeRepo, nil, nil)
+
+	hash := utils.Checksum([]byte("mfa-token"))
+	mockChallengeRepo.On("GetChallenge", mock.Anything, hash).Return(&domain.PendingLogin{UserID: "user123", Login: "testuser"}, nil)
+	mockChallengeRepo.On("FailChallenge", mock.Anything, hash, 5*time.Minute).Return(int64(5), nil)
+	mockChallengeRepo.On("DeleteChallenge", mock.Anything, hash).Return(nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{
+		ID: "user123", Login: "testuser", TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockUserRepo.On("UseRecoveryCode", mock.Anything, "user123", mock.Anything).Return(false, nil)
+
+	_, err := authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", "wrong")
+
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	mockChallengeRepo.AssertCalled(t, "DeleteChallenge", mock.Anything, hash)
+
+	mockChallengeRepo.On("GetChallenge", mock.Anything, utils.Checksum([]byte("unknown"))).Return(nil, repository.ErrNotFound)
+	_, err = authService.VerifyMFA(context.Background(), "127.0.0.1", "unknown", "000000")
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+}
+
+func TestAuthService_VerifyMFA_Throttled(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockChallengeRepo := new(mocks.MockMFAChallengeRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := service.NewAuthService(mockUserRepo, nil, nil, nil, mockChallengeRepo, mockAttemptRepo, nil,
+		jwt.NewManager(jwtConfig(jwt.HS256, "test-secret")), testAuditor(), service.OIDCConfig{}, testThrottle,
+		service.PasswordPolicy{}, "admin-token")
+
+	hash := utils.Checksum([]byte("mfa-token"))
+	mockChallengeRepo.On("GetChallenge", mock.Anything, hash).Return(&domain.PendingLogin{UserID: "user123", Login: "testuser"}, nil)
+	mockChallengeRepo.On("FailChallenge", mock.Anything, hash, 5*time.Minute).Return(int64(1), nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{
+		ID: "user123", Login: "testuser", TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockUserRepo.On("UseRecoveryCode", mock.Anything, "user123", mock.Anything).Return(false, nil)
+	mockAttemptRepo.On("LockedFor", mock.Anything, "login:testuser").Return(time.Duration(0), nil).Once()
+	mockAttemptRepo.On("LockedFor", mock.Anything, "ip:127.0.0.1").Return(time.Duration(0), nil).Once()
+	mockAttemptRepo.On("FailLogin", mock.Anything, "login:testuser", 24*time.Hour).Return(int64(4), nil)
+	mockAttemptRepo.On("FailLogin", mock.Anything, "ip:127.0.0.1", 24*time.Hour).Return(int64(4), nil)
+	mockAttemptRepo.On("Lock", mock.Anything, "login:testuser", time.Second).Return(nil)
+
+	// A wrong code counts against the login like a wrong password.
+	_, err := authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", "wrong")
+
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	mockAttemptRepo.AssertCalled(t, "FailLogin", mock.Anything, "ip:127.0.0.1", 24*time.Hour)
+	mockAttemptRepo.AssertCalled(t, "Lock", mock.Anything, "login:testuser", time.Second)
+
+	// While the login is locked no code is checked.
+	mockAttemptRepo.On("LockedFor", mock.Anything, "login:testuser").Return(time.Second, nil)
+
+	_, err = authService.VerifyMFA(context.Background(), "127.0.0.1", "mfa-token", "wrong")
+
+	assert.ErrorIs(t, err, service.ErrTooManyAttempts)
+	mockUserRepo.AssertNumberOfCalls(t, "UseRecoveryCode", 1)
+}
+
+func TestAuthService_EnrollTOTP(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	authService := newMFAAuthService(mockUserRepo, nil, nil, nil)
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123", Login: "testuser"}, nil)
+	mockUserRepo.On("SetTOTPSecret", mock.Anything, "user123", mock.AnythingOfType("string")).Return(nil)
+
+	enrollment, err := authService.EnrollTOTP(context.Background(), "user123")
+
+	require.NoError(t, err)
+	assert.Len(t, enrollment.Secret, 32)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/document-service:testuser?"))
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	mockUserRepo.AssertCalled(t, "SetTOTPSecret", mock.Anything, "user123", enrollment.Secret)
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user456").Return(&domain.User{ID: "user456", TOTPEnabled: true}, nil)
+	_, err = authService.EnrollTOTP(context.Background(), "user456")
+	assert.ErrorIs(t, err, service.ErrMFAEnabled)
+}
+
+func TestAuthService_ConfirmTOTP(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	authService := newMFAAuthService(mockUserRepo, nil, nil, nil)
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(totpSecret, step)
+	require.NoError(t, err)
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123", TOTPSecret: totpSecret}, nil)
+	mockUserRepo.On("EnableTOTP", mock.Anything, "user123", step, mock.AnythingOfType("[]string")).Return(nil)
+
+	_, err = authService.ConfirmTOTP(context.Background(), "user123", "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	codes, err := authService.ConfirmTOTP(context.Background(), "user123", code)
+
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	hashes := mockUserRepo.Calls[len(mockUserRepo.Calls)-1].Arguments.Get(3).([]string)
+	assert.Equal(t, utils.Checksum([]byte(strings.ReplaceAll(codes[0], "-", ""))), hashes[0])
+}
+
+func TestAuthService_DisableTOTP_RequiresCode(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	authService := newMFAAuthService(mockUserRepo, nil, nil, nil)
+
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{
+		ID: "user123", TOTPSecret: totpSecret, TOTPEnabled: true,
+	}, nil)
+	mockUserRepo.On("UseRecoveryCode", mock.Anything, "user123", mock.Anything).Return(false, nil)
+
+	err := authService.DisableTOTP(context.Background(), "user123", "wrong")
+
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	mockUserRepo.AssertNotCalled(t, "DisableTOTP")
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) AddEvent(ctx context.Context, event *domain.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// GetEvents passes the events the expectation returns to fn.
+func (m *MockAuditRepository) GetEvents(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEvent) error) error {
+	args := m.Called(ctx, filter, fn)
+	if events, ok := args.Get(0).([]domain.AuditEvent); ok {
+		for i := range events {
+			if err := fn(&events[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
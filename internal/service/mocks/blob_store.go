@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBlobStore struct {
+	mock.Mock
+}
+
+func (m *MockBlobStore) Put(ctx context.Context, key string, r io.Reader) (*repository.BlobInfo, error) {
+	args := m.Called(ctx, key, r)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.BlobInfo), args.Error(1)
+}
+
+func (m *MockBlobStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadSeekCloser), args.Error(1)
+}
+
+func (m *MockBlobStore) Delete(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockBlobStore) Stat(ctx context.Context, key string) (*repository.BlobInfo, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.BlobInfo), args.Error(1)
+}
@@ -12,17 +12,17 @@ type MockCacheRepository struct {
 	mock.Mock
 }
 
-func (m *MockCacheRepository) SetDocuments(ctx context.Context, key string, docs []domain.Document, expiration time.Duration) error {
-	args := m.Called(ctx, key, docs, expiration)
+func (m *MockCacheRepository) SetDocuments(ctx context.Context, key string, page *domain.DocumentPage, expiration time.Duration) error {
+	args := m.Called(ctx, key, page, expiration)
 	return args.Error(0)
 }
 
-func (m *MockCacheRepository) GetDocuments(ctx context.Context, key string) ([]domain.Document, error) {
+func (m *MockCacheRepository) GetDocuments(ctx context.Context, key string) (*domain.DocumentPage, error) {
 	args := m.Called(ctx, key)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]domain.Document), args.Error(1)
+	return args.Get(0).(*domain.DocumentPage), args.Error(1)
 }
 
 func (m *MockCacheRepository) SetDocument(ctx context.Context, key string, doc *domain.Document, expiration time.Duration) error {
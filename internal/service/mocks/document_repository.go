@@ -24,20 +24,73 @@ func (m *MockDocumentRepository) GetDocumentByID(ctx context.Context, id string)
 	return args.Get(0).(*domain.Document), args.Error(1)
 }
 
-func (m *MockDocumentRepository) GetUserDocuments(ctx context.Context, login string, limit int) ([]domain.Document, error) {
-	args := m.Called(ctx, login, limit)
+func (m *MockDocumentRepository) GetUserDocuments(ctx context.Context, userID string, groupIDs []string, opts domain.ListOptions) (*domain.DocumentPage, error) {
+	args := m.Called(ctx, userID, groupIDs, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DocumentPage), args.Error(1)
+}
+
+func (m *MockDocumentRepository) SearchDocuments(ctx context.Context, userID string, groupIDs []string, query string, limit int) ([]domain.SearchResult, error) {
+	args := m.Called(ctx, userID, groupIDs, query, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.SearchResult), args.Error(1)
+}
+
+func (m *MockDocumentRepository) GetFolderDocuments(ctx context.Context, owner, folderID string) ([]domain.Document, error) {
+	args := m.Called(ctx, owner, folderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]domain.Document), args.Error(1)
 }
 
-func (m *MockDocumentRepository) DeleteDocument(ctx context.Context, id, owner string) error {
-	args := m.Called(ctx, id, owner)
+func (m *MockDocumentRepository) GetDocumentByName(ctx context.Context, owner, folderID, name string) (*domain.Document, error) {
+	args := m.Called(ctx, owner, folderID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Document), args.Error(1)
+}
+
+func (m *MockDocumentRepository) UpdateDocumentMeta(ctx context.Context, doc *domain.Document) error {
+	args := m.Called(ctx, doc)
 	return args.Error(0)
 }
 
+func (m *MockDocumentRepository) DeleteDocument(ctx context.Context, id string) (*domain.Document, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Document), args.Error(1)
+}
+
 func (m *MockDocumentRepository) DocumentExists(ctx context.Context, id string) (bool, error) {
 	args := m.Called(ctx, id)
 	return args.Bool(0), args.Error(1)
 }
+
+func (m *MockDocumentRepository) AddVersion(ctx context.Context, version *domain.DocumentVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockDocumentRepository) GetVersions(ctx context.Context, docID string) ([]domain.DocumentVersion, error) {
+	args := m.Called(ctx, docID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.DocumentVersion), args.Error(1)
+}
+
+func (m *MockDocumentRepository) GetVersion(ctx context.Context, docID string, version int) (*domain.DocumentVersion, error) {
+	args := m.Called(ctx, docID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DocumentVersion), args.Error(1)
+}
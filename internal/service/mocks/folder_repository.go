@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFolderRepository struct {
+	mock.Mock
+}
+
+func (m *MockFolderRepository) CreateFolder(ctx context.Context, folder *domain.Folder) error {
+	args := m.Called(ctx, folder)
+	return args.Error(0)
+}
+
+func (m *MockFolderRepository) GetFolderByID(ctx context.Context, id string) (*domain.Folder, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Folder), args.Error(1)
+}
+
+func (m *MockFolderRepository) GetFolderByName(ctx context.Context, owner, parentID, name string) (*domain.Folder, error) {
+	args := m.Called(ctx, owner, parentID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Folder), args.Error(1)
+}
+
+func (m *MockFolderRepository) GetSubfolders(ctx context.Context, owner, parentID string) ([]domain.Folder, error) {
+	args := m.Called(ctx, owner, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Folder), args.Error(1)
+}
+
+func (m *MockFolderRepository) UpdateFolder(ctx context.Context, folder *domain.Folder) error {
+	args := m.Called(ctx, folder)
+	return args.Error(0)
+}
+
+func (m *MockFolderRepository) DeleteFolder(ctx context.Context, id, owner string) error {
+	args := m.Called(ctx, id, owner)
+	return args.Error(0)
+}
@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGroupRepository struct {
+	mock.Mock
+}
+
+func (m *MockGroupRepository) CreateGroup(ctx context.Context, group *domain.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) GetGroupByName(ctx context.Context, name string) (*domain.Group, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) GetUserGroups(ctx context.Context, userID string) ([]domain.Group, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) AddMember(ctx context.Context, groupID, userID string) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) RemoveMember(ctx context.Context, groupID, userID string) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) DeleteGroup(ctx context.Context, id string) ([]string, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) FailLogin(ctx context.Context, key string, window time.Duration) (int64, error) {
+	args := m.Called(ctx, key, window)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	args := m.Called(ctx, key, duration)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) ResetLogin(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
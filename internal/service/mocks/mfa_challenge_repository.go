@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFAChallengeRepository struct {
+	mock.Mock
+}
+
+func (m *MockMFAChallengeRepository) SaveChallenge(ctx context.Context, hash string, login *domain.PendingLogin, ttl time.Duration) error {
+	args := m.Called(ctx, hash, login, ttl)
+	return args.Error(0)
+}
+
+func (m *MockMFAChallengeRepository) GetChallenge(ctx context.Context, hash string) (*domain.PendingLogin, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PendingLogin), args.Error(1)
+}
+
+func (m *MockMFAChallengeRepository) FailChallenge(ctx context.Context, hash string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, hash, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMFAChallengeRepository) DeleteChallenge(ctx context.Context, hash string) error {
+	args := m.Called(ctx, hash)
+	return args.Error(0)
+}
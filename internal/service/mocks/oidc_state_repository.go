@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCStateRepository struct {
+	mock.Mock
+}
+
+func (m *MockOIDCStateRepository) SaveOIDCState(ctx context.Context, state string, oidcState *domain.OIDCState, ttl time.Duration) error {
+	args := m.Called(ctx, state, oidcState, ttl)
+	return args.Error(0)
+}
+
+func (m *MockOIDCStateRepository) TakeOIDCState(ctx context.Context, state string) (*domain.OIDCState, error) {
+	args := m.Called(ctx, state)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OIDCState), args.Error(1)
+}
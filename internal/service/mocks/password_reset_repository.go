@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) SaveResetToken(ctx context.Context, hash, userID string, ttl time.Duration) error {
+	args := m.Called(ctx, hash, userID, ttl)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) TakeResetToken(ctx context.Context, hash string) (string, error) {
+	args := m.Called(ctx, hash)
+	return args.String(0), args.Error(1)
+}
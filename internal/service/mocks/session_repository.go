@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) AddSession(ctx context.Context, userID, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSession(ctx context.Context, userID, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSessions(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
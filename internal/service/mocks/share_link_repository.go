@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockShareLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockShareLinkRepository) CreateLink(ctx context.Context, link *domain.ShareLink) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) GetLinkByHash(ctx context.Context, hash string) (*domain.ShareLink, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) GetDocumentLinks(ctx context.Context, docID string) ([]domain.ShareLink, error) {
+	args := m.Called(ctx, docID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) RevokeLink(ctx context.Context, docID, id string, at time.Time) error {
+	args := m.Called(ctx, docID, id, at)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) UseLink(ctx context.Context, id string, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) LogAccess(ctx context.Context, access *domain.LinkAccess) error {
+	args := m.Called(ctx, access)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) GetAccesses(ctx context.Context, linkID string) ([]domain.LinkAccess, error) {
+	args := m.Called(ctx, linkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.LinkAccess), args.Error(1)
+}
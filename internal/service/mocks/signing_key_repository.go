@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSigningKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockSigningKeyRepository) GetSigningKeys(ctx context.Context, now time.Time) ([]domain.SigningKey, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) RotateSigningKey(ctx context.Context, key *domain.SigningKey, notBefore, retires time.Time) error {
+	args := m.Called(ctx, key, notBefore, retires)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) DeleteRetiredKeys(ctx context.Context, now time.Time) error {
+	args := m.Called(ctx, now)
+	return args.Error(0)
+}
@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUploadRepository struct {
+	mock.Mock
+}
+
+func (m *MockUploadRepository) CreateUpload(ctx context.Context, upload *domain.Upload) error {
+	args := m.Called(ctx, upload)
+	return args.Error(0)
+}
+
+func (m *MockUploadRepository) GetUpload(ctx context.Context, id string) (*domain.Upload, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Upload), args.Error(1)
+}
+
+func (m *MockUploadRepository) AppendChunk(ctx context.Context, id string, offset int64, key string, size int64, expires time.Time) error {
+	args := m.Called(ctx, id, offset, key, size, expires)
+	return args.Error(0)
+}
+
+func (m *MockUploadRepository) SetUploadState(ctx context.Context, id, from, to string) error {
+	args := m.Called(ctx, id, from, to)
+	return args.Error(0)
+}
+
+func (m *MockUploadRepository) DeleteUpload(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUploadRepository) GetExpiredUploads(ctx context.Context, now time.Time) ([]domain.Upload, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Upload), args.Error(1)
+}
@@ -24,7 +24,53 @@ func (m *MockUserRepository) GetUserByLogin(ctx context.Context, login string) (
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserBySubject(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	args := m.Called(ctx, issuer, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
 func (m *MockUserRepository) UserExists(ctx context.Context, login string) (bool, error) {
 	args := m.Called(ctx, login)
 	return args.Bool(0), args.Error(1)
 }
+
+func (m *MockUserRepository) SetPassword(ctx context.Context, userID, hash string) error {
+	args := m.Called(ctx, userID, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, userID, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodes []string) error {
+	args := m.Called(ctx, userID, step, recoveryCodes)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DisableTOTP(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	args := m.Called(ctx, userID, hash)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/oidc"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrOIDCDisabled     = errors.New("OIDC login is not configured")
+	ErrInvalidOIDCState = errors.New("invalid or expired OIDC state")
+	ErrInvalidIDToken   = oidc.ErrInvalidIDToken
+	ErrOIDCExchange     = oidc.ErrExchange
+	ErrOIDCLogin        = errors.New("cannot provision user")
+)
+
+const maxLoginLength = 50
+
+// OIDCConfig enables login through an identity provider. Users are created on
+// their first sign-in with the login taken from LoginClaim of the ID token.
+type OIDCConfig struct {
+	Provider   *oidc.Provider
+	LoginClaim string
+	StateTTL   time.Duration
+}
+
+// OIDCLoginURL starts the authorization code flow with PKCE and returns the
+// provider URL to redirect the user to. The scopes are checked against the
+// user once they are known, on callback.
+func (s *authService) OIDCLoginURL(ctx context.Context, scopes []string) (string, error) {
+	if s.oidc.Provider == nil {
+		return "", ErrOIDCDisabled
+	}
+
+	for _, scope := range scopes {
+		if !domain.ValidScope(scope) {
+			return "", fmt.Errorf("%w %q", ErrInvalidScope, scope)
+		}
+	}
+
+	state := oidc.NewVerifier()
+	oidcState := &domain.OIDCState{
+		Verifier: oidc.NewVerifier(),
+		Nonce:    oidc.NewVerifier(),
+		Scopes:   scopes,
+	}
+
+	url, err := s.oidc.Provider.AuthCodeURL(ctx, state, oidcState.Nonce, oidcState.Verifier)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.oidcStateRepo.SaveOIDCState(ctx, state, oidcState, s.oidc.StateTTL); err != nil {
+		return "", err
+	}
+
+	return url, nil
+}
+
+// OIDCCallback finishes the flow started by OIDCLoginURL: it exchanges the
+// code, verifies the ID token and issues a token pair as Authenticate does.
+func (s *authService) OIDCCallback(ctx context.Context, code, state string) (_ *domain.TokenPair, err error) {
+	var user *domain.User
+	defer func() {
+		event := domain.AuditEvent{Action: domain.AuditLogin, Detail: "oidc"}
+		if err != nil {
+			event.Action = domain.AuditFailedLogin
+		}
+		if user != nil {
+			event.ActorID, event.Actor = user.ID, user.Login
+		}
+		s.auditor.record(ctx, event, err)
+	}()
+
+	if s.oidc.Provider == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	oidcState, err := s.oidcStateRepo.TakeOIDCState(ctx, state)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.oidc.Provider.Exchange(ctx, code, oidcState.Verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := s.oidc.Provider.VerifyIDToken(ctx, tokens.IDToken, oidcState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.oidcUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := oidcState.Scopes
+	if len(scopes) == 0 {
+		scopes = domain.DefaultScopes
+	}
+	scopes, err = grantScopes(user, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user.ID, user.Login, utils.GenerateID(), scopes)
+}
+
+// oidcUser returns the user the ID token was issued for, creating it on the
+// first sign-in.
+func (s *authService) oidcUser(ctx context.Context, idToken *oidc.IDToken) (*domain.User, error) {
+	user, err := s.userRepo.GetUserBySubject(ctx, idToken.Issuer, idToken.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	login := oidcLogin(idToken.Claim(s.oidc.LoginClaim))
+	if !isValidLogin(login) {
+		return nil, fmt.Errorf("%w: claim %q does not hold a valid login", ErrOIDCLogin, s.oidc.LoginClaim)
+	}
+
+	exists, err := s.userRepo.UserExists(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: login %q is taken", ErrOIDCLogin, login)
+	}
+
+	user = &domain.User{
+		ID:          utils.GenerateID(),
+		Login:       login,
+		Created:     time.Now(),
+		OIDCIssuer:  idToken.Issuer,
+		OIDCSubject: idToken.Subject,
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// oidcLogin maps a claim value to a login: the local part of an email
+// address, with the characters a login cannot have removed.
+func oidcLogin(claim string) string {
+	claim, _, _ = strings.Cut(claim, "@")
+
+	login := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, claim)
+
+	if len(login) > maxLoginLength {
+		login = login[:maxLoginLength]
+	}
+	return login
+}
@@ -0,0 +1,248 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProvider is a minimal OpenID Connect provider: Authorize stands in for
+// the user signing in and returns the code the provider would redirect with.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *jwt.Key
+	claims gojwt.MapClaims
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+func newMockProvider(t *testing.T, claims gojwt.MapClaims) *mockProvider {
+	key, err := jwt.GenerateKey(jwt.RS256)
+	require.NoError(t, err)
+
+	p := &mockProvider{t: t, key: key, claims: claims, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                           p.server.URL,
+			"authorization_endpoint":           p.server.URL + "/authorize",
+			"token_endpoint":                   p.server.URL + "/token",
+			"jwks_uri":                         p.server.URL + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwt.JWKS{Keys: []jwt.JWK{p.key.JWK()}})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) Authorize(authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	require.NoError(p.t, err)
+	query := u.Query()
+	assert.Equal(p.t, "S256", query.Get("code_challenge_method"))
+
+	code = oidc.NewVerifier()
+	p.mu.Lock()
+	p.codes[code] = query
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	request, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	if !ok || oidc.Challenge(r.PostFormValue("code_verifier")) != request.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := gojwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   request.Get("client_id"),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": request.Get("nonce"),
+	}
+	for name, value := range p.claims {
+		claims[name] = value
+	}
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.key.ID
+	idToken, err := token.SignedString(p.key.Private)
+	require.NoError(p.t, err)
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "access_token": "access", "token_type": "Bearer"})
+}
+
+func newOIDCAuthService(p *mockProvider, userRepo *mocks.MockUserRepository, stateRepo *mocks.MockOIDCStateRepository,
+	sessionRepo *mocks.MockSessionRepository, refreshRepo *mocks.MockRefreshTokenRepository) service.AuthService {
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      p.server.URL,
+		ClientID:    "document-service",
+		RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		Scopes:      []string{"openid", "profile"},
+	})
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+	return service.NewAuthService(userRepo, sessionRepo, refreshRepo, stateRepo, nil, nil, nil, jwtManager, testAuditor(),
+		service.OIDCConfig{Provider: provider, LoginClaim: "preferred_username", StateTTL: 10 * time.Minute},
+		service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+}
+
+// expectStates makes the state repository keep what is saved and hand it out
+// once.
+func expectStates(stateRepo *mocks.MockOIDCStateRepository) {
+	states := map[string]*domain.OIDCState{}
+	stateRepo.On("SaveOIDCState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.OIDCState"), 10*time.Minute).
+		Run(func(args mock.Arguments) {
+			states[args.String(1)] = args.Get(2).(*domain.OIDCState)
+			stateRepo.On("TakeOIDCState", mock.Anything, args.String(1)).Return(states[args.String(1)], nil).Once()
+		}).Return(nil)
+}
+
+func TestAuthService_OIDC_ProvisionsUser(t *testing.T) {
+	provider := newMockProvider(t, gojwt.MapClaims{"sub": "f3a1", "preferred_username": "alice.smith@example.com"})
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockStateRepo := new(mocks.MockOIDCStateRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	authService := newOIDCAuthService(provider, mockUserRepo, mockStateRepo, mockSessionRepo, mockRefreshRepo)
+
+	expectStates(mockStateRepo)
+	mockUserRepo.On("GetUserBySubject", mock.Anything, provider.server.URL, "f3a1").Return(nil, repository.ErrNotFound)
+	mockUserRepo.On("UserExists", mock.Anything, "alicesmith").Return(false, nil)
+	mockUserRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(user *domain.User) bool {
+		return user.Login == "alicesmith" && user.OIDCSubject == "f3a1" && user.Password == ""
+	})).Return(nil)
+	mockSessionRepo.On("AddSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	authURL, err := authService.OIDCLoginURL(context.Background(), []string{domain.ScopeDocsRead})
+	require.NoError(t, err)
+	code, state := provider.Authorize(authURL)
+
+	tokens, err := authService.OIDCCallback(context.Background(), code, state)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.ScopeDocsRead, tokens.Scope)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_OIDC_ExistingUser(t *testing.T) {
+	provider := newMockProvider(t, gojwt.MapClaims{"sub": "f3a1", "preferred_username": "renamed"})
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockStateRepo := new(mocks.MockOIDCStateRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	authService := newOIDCAuthService(provider, mockUserRepo, mockStateRepo, mockSessionRepo, mockRefreshRepo)
+
+	expectStates(mockStateRepo)
+	mockUserRepo.On("GetUserBySubject", mock.Anything, provider.server.URL, "f3a1").Return(&domain.User{ID: "user123", Login: "alice"}, nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	authURL, err := authService.OIDCLoginURL(context.Background(), nil)
+	require.NoError(t, err)
+	code, state := provider.Authorize(authURL)
+
+	tokens, err := authService.OIDCCallback(context.Background(), code, state)
+
+	require.NoError(t, err)
+	claims, err := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret")).ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Login)
+	assert.Equal(t, domain.DefaultScopes, claims.Scopes())
+	mockUserRepo.AssertNotCalled(t, "CreateUser")
+}
+
+func TestAuthService_OIDC_RejectsTamperedState(t *testing.T) {
+	provider := newMockProvider(t, gojwt.MapClaims{"sub": "f3a1", "preferred_username": "alice"})
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockStateRepo := new(mocks.MockOIDCStateRepository)
+	authService := newOIDCAuthService(provider, mockUserRepo, mockStateRepo, nil, nil)
+
+	var saved *domain.OIDCState
+	mockStateRepo.On("SaveOIDCState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(2).(*domain.OIDCState) }).Return(nil)
+
+	authURL, err := authService.OIDCLoginURL(context.Background(), nil)
+	require.NoError(t, err)
+
+	// A verifier that does not match the challenge is refused by the provider.
+	code, _ := provider.Authorize(authURL)
+	mockStateRepo.On("TakeOIDCState", mock.Anything, "wrong-verifier").
+		Return(&domain.OIDCState{Verifier: "other", Nonce: saved.Nonce}, nil).Once()
+	_, err = authService.OIDCCallback(context.Background(), code, "wrong-verifier")
+	assert.ErrorIs(t, err, service.ErrOIDCExchange)
+
+	// An ID token issued for another login attempt has the wrong nonce.
+	code, _ = provider.Authorize(authURL)
+	mockStateRepo.On("TakeOIDCState", mock.Anything, "wrong-nonce").
+		Return(&domain.OIDCState{Verifier: saved.Verifier, Nonce: "other"}, nil).Once()
+	_, err = authService.OIDCCallback(context.Background(), code, "wrong-nonce")
+	assert.ErrorIs(t, err, service.ErrInvalidIDToken)
+
+	mockStateRepo.On("TakeOIDCState", mock.Anything, "unknown").Return(nil, repository.ErrNotFound)
+	_, err = authService.OIDCCallback(context.Background(), code, "unknown")
+	assert.ErrorIs(t, err, service.ErrInvalidOIDCState)
+
+	mockUserRepo.AssertNotCalled(t, "CreateUser")
+}
+
+func TestAuthService_OIDC_LoginTaken(t *testing.T) {
+	provider := newMockProvider(t, gojwt.MapClaims{"sub": "f3a1", "preferred_username": "alice"})
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockStateRepo := new(mocks.MockOIDCStateRepository)
+	authService := newOIDCAuthService(provider, mockUserRepo, mockStateRepo, nil, nil)
+
+	expectStates(mockStateRepo)
+	mockUserRepo.On("GetUserBySubject", mock.Anything, provider.server.URL, "f3a1").Return(nil, repository.ErrNotFound)
+	mockUserRepo.On("UserExists", mock.Anything, "alice").Return(true, nil)
+
+	authURL, err := authService.OIDCLoginURL(context.Background(), nil)
+	require.NoError(t, err)
+	code, state := provider.Authorize(authURL)
+
+	_, err = authService.OIDCCallback(context.Background(), code, state)
+
+	assert.ErrorIs(t, err, service.ErrOIDCLogin)
+	mockUserRepo.AssertNotCalled(t, "CreateUser")
+}
+
+func TestAuthService_OIDC_Disabled(t *testing.T) {
+	authService := service.NewAuthService(nil, nil, nil, nil, nil, nil, nil, jwt.NewManager(jwtConfig(jwt.HS256, "test-secret")), testAuditor(),
+		service.OIDCConfig{}, service.LoginThrottle{}, service.PasswordPolicy{}, "admin-token")
+
+	_, err := authService.OIDCLoginURL(context.Background(), nil)
+	assert.ErrorIs(t, err, service.ErrOIDCDisabled)
+}
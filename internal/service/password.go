@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrWeakPassword        = errors.New("password does not meet the policy")
+	ErrWrongPassword       = errors.New("current password is incorrect")
+	ErrInvalidResetToken   = errors.New("invalid or expired password reset token")
+	ErrPasswordUnavailable = errors.New("password is managed by the identity provider")
+)
+
+const (
+	passwordResetTTL = 24 * time.Hour
+	// maxPasswordLength is in bytes; bcrypt does not hash more than that.
+	maxPasswordLength = 72
+)
+
+// PasswordPolicy is what new passwords are checked against on registration,
+// change and reset.
+type PasswordPolicy struct {
+	MinLength int
+	// RequireCharacterClasses asks for an uppercase and a lowercase letter,
+	// a digit and a special character.
+	RequireCharacterClasses bool
+	// Breached holds passwords known from data breaches, which are refused.
+	Breached map[string]struct{}
+}
+
+// ReadPasswordList reads a file with one password per line, such as a list
+// of breached passwords.
+func ReadPasswordList(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimRight(scanner.Text(), "\r"); password != "" {
+			passwords[password] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return passwords, nil
+}
+
+func (p PasswordPolicy) isValidPassword(password string) error {
+	if utf8.RuneCountInString(password) < max(p.MinLength, 1) {
+		return fmt.Errorf("%w: must be at least %d characters long", ErrWeakPassword, max(p.MinLength, 1))
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: must be at most %d bytes long", ErrWeakPassword, maxPasswordLength)
+	}
+
+	if p.RequireCharacterClasses {
+		hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
+		hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
+		hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
+		hasSpecial := regexp.MustCompile(`[^a-zA-Z0-9]`).MatchString(password)
+
+		if !hasUpper || !hasLower || !hasDigit || !hasSpecial {
+			return fmt.Errorf("%w: must contain uppercase and lowercase letter, digit and special character", ErrWeakPassword)
+		}
+	}
+
+	if _, ok := p.Breached[password]; ok {
+		return fmt.Errorf("%w: appears in a list of breached passwords", ErrWeakPassword)
+	}
+	return nil
+}
+
+// ChangePassword sets a new password after checking the current one, which is
+// throttled like a login. Every session of the user is revoked, and the
+// caller gets a new token pair with the scopes it had.
+func (s *authService) ChangePassword(ctx context.Context, ip, userID, current, password string, scopes []string) (_ *domain.TokenPair, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Action: domain.AuditAccount, Detail: "password change"}, err)
+	}()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Password == "" {
+		return nil, ErrPasswordUnavailable
+	}
+
+	if err := s.passwords.isValidPassword(password); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkThrottle(ctx, ip, user.Login); err != nil {
+		return nil, err
+	}
+	if !checkPassword(user, current) {
+		if err := s.failLogin(ctx, ip, user.Login); err != nil {
+			return nil, err
+		}
+		return nil, ErrWrongPassword
+	}
+
+	if err := s.setPassword(ctx, user, password); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user.ID, user.Login, utils.GenerateID(), dropAdmin(scopes, user.Admin))
+}
+
+// IssuePasswordReset returns a one-time token with which the user can set a
+// new password without knowing the current one.
+func (s *authService) IssuePasswordReset(ctx context.Context, login string) (_ *domain.PasswordReset, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{Action: domain.AuditAdmin, Detail: "password reset " + login}, err)
+	}()
+
+	user, err := s.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user.Password == "" {
+		return nil, ErrPasswordUnavailable
+	}
+
+	token := randomToken()
+	if err := s.resetRepo.SaveResetToken(ctx, utils.Checksum([]byte(token)), user.ID, passwordResetTTL); err != nil {
+		return nil, err
+	}
+
+	return &domain.PasswordReset{Token: token, ExpiresIn: int64(passwordResetTTL.Seconds())}, nil
+}
+
+// ResetPassword sets a new password with a token from IssuePasswordReset.
+// Every session of the user is revoked and the login is unlocked.
+func (s *authService) ResetPassword(ctx context.Context, token, password string) (err error) {
+	var user *domain.User
+	defer func() {
+		event := domain.AuditEvent{Action: domain.AuditAccount, Detail: "password reset"}
+		if user != nil {
+			event.ActorID, event.Actor = user.ID, user.Login
+		}
+		s.auditor.record(ctx, event, err)
+	}()
+
+	if err := s.passwords.isValidPassword(password); err != nil {
+		return err
+	}
+
+	userID, err := s.resetRepo.TakeResetToken(ctx, utils.Checksum([]byte(token)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err = s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, user, password); err != nil {
+		return err
+	}
+	return s.attemptRepo.ResetLogin(ctx, loginKey(user.Login))
+}
+
+func (s *authService) setPassword(ctx context.Context, user *domain.User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	return s.LogoutAll(ctx, user.ID)
+}
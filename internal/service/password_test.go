@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testPasswordPolicy = service.PasswordPolicy{
+	MinLength:               8,
+	RequireCharacterClasses: true,
+	Breached:                map[string]struct{}{"Password1!": {}},
+}
+
+func newPasswordAuthService(userRepo *mocks.MockUserRepository, sessionRepo *mocks.MockSessionRepository,
+	refreshRepo *mocks.MockRefreshTokenRepository, attemptRepo *mocks.MockLoginAttemptRepository,
+	resetRepo *mocks.MockPasswordResetRepository) service.AuthService {
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+	return service.NewAuthService(userRepo, sessionRepo, refreshRepo, nil, nil, attemptRepo, resetRepo, jwtManager, testAuditor(),
+		service.OIDCConfig{}, testThrottle, testPasswordPolicy, "admin-token")
+}
+
+func TestAuthService_Register_PasswordPolicy(t *testing.T) {
+	authService := newPasswordAuthService(nil, nil, nil, nil, nil)
+
+	for _, password := range []string{"Pa1!", "password123!", "Password1!", strings.Repeat("Aa1!", 19)} {
+		err := authService.Register(context.Background(), "admin-token", "testuser", password, false)
+		assert.ErrorIs(t, err, service.ErrWeakPassword, password)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	authService := newPasswordAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, newAttemptRepo(), nil)
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123", Login: "testuser", Password: hash}, nil)
+	mockUserRepo.On("SetPassword", mock.Anything, "user123", mock.AnythingOfType("string")).Return(nil)
+	mockRefreshRepo.On("RevokeUserTokens", mock.Anything, "user123").Return(nil)
+	mockSessionRepo.On("RevokeSessions", mock.Anything, "user123").Return(nil)
+	mockSessionRepo.On("AddSession", mock.Anything, "user123", mock.Anything, mock.Anything).Return(nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+
+	tokens, err := authService.ChangePassword(context.Background(), "127.0.0.1", "user123", "Password123!",
+		"NewPassword456?", []string{domain.ScopeDocsRead, domain.ScopeAdmin})
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.ScopeDocsRead, tokens.Scope)
+	stored := mockUserRepo.Calls[1].Arguments.String(2)
+	assert.True(t, utils.CheckPasswordHash("NewPassword456?", stored))
+	mockSessionRepo.AssertCalled(t, "RevokeSessions", mock.Anything, "user123")
+}
+
+func TestAuthService_ChangePassword_WrongPassword(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := newPasswordAuthService(mockUserRepo, nil, nil, mockAttemptRepo, nil)
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(&domain.User{ID: "user123", Login: "testuser", Password: hash}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user456").Return(&domain.User{ID: "user456", Login: "oidcuser", OIDCSubject: "sub"}, nil)
+	mockAttemptRepo.On("LockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	mockAttemptRepo.On("FailLogin", mock.Anything, mock.Anything, 24*time.Hour).Return(int64(1), nil)
+
+	_, err := authService.ChangePassword(context.Background(), "127.0.0.1", "user123", "wrong", "NewPassword456?", nil)
+	assert.ErrorIs(t, err, service.ErrWrongPassword)
+	mockAttemptRepo.AssertCalled(t, "FailLogin", mock.Anything, "login:testuser", 24*time.Hour)
+	mockUserRepo.AssertNotCalled(t, "SetPassword")
+
+	_, err = authService.ChangePassword(context.Background(), "127.0.0.1", "user456", "", "NewPassword456?", nil)
+	assert.ErrorIs(t, err, service.ErrPasswordUnavailable)
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockSessionRepo := new(mocks.MockSessionRepository)
+	mockRefreshRepo := new(mocks.MockRefreshTokenRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	authService := newPasswordAuthService(mockUserRepo, mockSessionRepo, mockRefreshRepo, mockAttemptRepo, mockResetRepo)
+
+	user := &domain.User{ID: "user123", Login: "testuser", Password: "hash"}
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(user, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, "user123").Return(user, nil)
+	mockResetRepo.On("SaveResetToken", mock.Anything, mock.AnythingOfType("string"), "user123", 24*time.Hour).Return(nil)
+
+	reset, err := authService.IssuePasswordReset(context.Background(), "testuser")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(24*60*60), reset.ExpiresIn)
+	hash := utils.Checksum([]byte(reset.Token))
+	mockResetRepo.AssertCalled(t, "SaveResetToken", mock.Anything, hash, "user123", 24*time.Hour)
+
+	mockResetRepo.On("TakeResetToken", mock.Anything, hash).Return("user123", nil).Once()
+	mockResetRepo.On("TakeResetToken", mock.Anything, hash).Return("", repository.ErrNotFound)
+	mockUserRepo.On("SetPassword", mock.Anything, "user123", mock.AnythingOfType("string")).Return(nil)
+	mockRefreshRepo.On("RevokeUserTokens", mock.Anything, "user123").Return(nil)
+	mockSessionRepo.On("RevokeSessions", mock.Anything, "user123").Return(nil)
+	mockAttemptRepo.On("ResetLogin", mock.Anything, "login:testuser").Return(nil)
+
+	err = authService.ResetPassword(context.Background(), reset.Token, "short")
+	assert.ErrorIs(t, err, service.ErrWeakPassword)
+	mockResetRepo.AssertNotCalled(t, "TakeResetToken", mock.Anything, mock.Anything)
+
+	err = authService.ResetPassword(context.Background(), reset.Token, "NewPassword456?")
+	assert.NoError(t, err)
+	mockAttemptRepo.AssertExpectations(t)
+	mockSessionRepo.AssertExpectations(t)
+
+	err = authService.ResetPassword(context.Background(), reset.Token, "NewPassword456?")
+	assert.ErrorIs(t, err, service.ErrInvalidResetToken)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+)
+
+var (
+	ErrInvalidScope    = errors.New("invalid scope")
+	ErrScopeNotAllowed = errors.New("scope not allowed")
+)
+
+// grantScopes validates the requested scopes and removes duplicates. Only
+// admins can be granted the admin scope.
+func grantScopes(user *domain.User, requested []string) ([]string, error) {
+	var scopes []string
+	for _, scope := range requested {
+		if !domain.ValidScope(scope) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidScope, scope)
+		}
+		if scope == domain.ScopeAdmin && !user.Admin {
+			return nil, fmt.Errorf("%w: %q requires an admin account", ErrScopeNotAllowed, scope)
+		}
+		if !contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// checkHeldScopes fails with ErrScopeNotAllowed if a scope is requested that
+// the credential making the request does not hold.
+func checkHeldScopes(requested, held []string) error {
+	for _, scope := range requested {
+		if !contains(held, scope) {
+			return fmt.Errorf("%w: %q is not granted to the current token", ErrScopeNotAllowed, scope)
+		}
+	}
+	return nil
+}
+
+// dropAdmin removes the admin scope from credentials whose user is no longer
+// an admin.
+func dropAdmin(scopes []string, admin bool) []string {
+	if admin || !contains(scopes, domain.ScopeAdmin) {
+		return scopes
+	}
+
+	var kept []string
+	for _, scope := range scopes {
+		if scope != domain.ScopeAdmin {
+			kept = append(kept, scope)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// ThrottledError is returned while logins for the login or the client IP are
+// refused after failed attempts.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// LoginThrottle configures the backoff after failed logins. Each failure past
+// the free attempts doubles the delay, starting at BaseDelay and capped at
+// MaxDelay. A login that reaches LockoutThreshold failures is locked for
+// LockoutDuration or until an admin unlocks it. Failures are counted within
+// Window.
+type LoginThrottle struct {
+	FreeAttempts     int64
+	IPFreeAttempts   int64
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int64
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+func loginKey(login string) string { return "login:" + login }
+func ipKey(ip string) string       { return "ip:" + ip }
+
+// checkThrottle refuses the attempt before any password is compared, so
+// locked out clients cost no bcrypt work.
+func (s *authService) checkThrottle(ctx context.Context, ip, login string) error {
+	for _, key := range []string{loginKey(login), ipKey(ip)} {
+		lockedFor, err := s.attemptRepo.LockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+		if lockedFor > 0 {
+			return &ThrottledError{RetryAfter: lockedFor}
+		}
+	}
+	return nil
+}
+
+// failLogin counts a failed attempt for the login, whether it exists or not,
+// and for the IP, and locks them if they are past their free attempts.
+func (s *authService) failLogin(ctx context.Context, ip, login string) error {
+	failures, err := s.attemptRepo.FailLogin(ctx, loginKey(login), s.throttle.Window)
+	if err != nil {
+		return err
+	}
+	if delay := s.throttle.loginDelay(failures); delay > 0 {
+		if err := s.attemptRepo.Lock(ctx, loginKey(login), delay); err != nil {
+			return err
+		}
+	}
+
+	failures, err = s.attemptRepo.FailLogin(ctx, ipKey(ip), s.throttle.Window)
+	if err != nil {
+		return err
+	}
+	if delay := s.throttle.backoff(failures, s.throttle.IPFreeAttempts); delay > 0 {
+		return s.attemptRepo.Lock(ctx, ipKey(ip), delay)
+	}
+	return nil
+}
+
+// loginDelay is how long a login has to wait after its failures: the backoff,
+// or the lockout once there are too many.
+func (t LoginThrottle) loginDelay(failures int64) time.Duration {
+	if t.LockoutThreshold > 0 && failures >= t.LockoutThreshold {
+		return t.LockoutDuration
+	}
+	return t.backoff(failures, t.FreeAttempts)
+}
+
+func (t LoginThrottle) backoff(failures, free int64) time.Duration {
+	if failures <= free {
+		return 0
+	}
+
+	// Past 2^20 times the base delay the cap applies anyway.
+	delay := t.BaseDelay << min(failures-free-1, 20)
+	if delay > t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	return delay
+}
+
+// UnlockLogin lifts the lockout of a login and resets its failures.
+func (s *authService) UnlockLogin(ctx context.Context, login string) (err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{Action: domain.AuditAdmin, Detail: "unlock " + login}, err)
+	}()
+
+	return s.attemptRepo.ResetLogin(ctx, loginKey(login))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// checkPassword compares the password against a fixed hash when there is no
+// user or the user has no password, so the response time does not tell
+// whether the login exists.
+func checkPassword(user *domain.User, password string) bool {
+	if user == nil || user.Password == "" {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = utils.HashPassword(utils.GenerateID())
+		})
+		utils.CheckPasswordHash(password, dummyHash)
+		return false
+	}
+	return utils.CheckPasswordHash(password, user.Password)
+}
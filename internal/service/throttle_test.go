@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/jwt"
+	"github.com/mibrgmv/document-service/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testThrottle = service.LoginThrottle{
+	FreeAttempts:     3,
+	IPFreeAttempts:   20,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Hour,
+	Window:           24 * time.Hour,
+}
+
+// newAttemptRepo lets every login through; failures are counted as the first.
+func newAttemptRepo() *mocks.MockLoginAttemptRepository {
+	attemptRepo := new(mocks.MockLoginAttemptRepository)
+	attemptRepo.On("LockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	attemptRepo.On("FailLogin", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+	attemptRepo.On("ResetLogin", mock.Anything, mock.Anything).Return(nil)
+	return attemptRepo
+}
+
+func newThrottledAuthService(userRepo *mocks.MockUserRepository, attemptRepo *mocks.MockLoginAttemptRepository) service.AuthService {
+	jwtManager := jwt.NewManager(jwtConfig(jwt.HS256, "test-secret"))
+	return service.NewAuthService(userRepo, nil, nil, nil, nil, attemptRepo, nil, jwtManager, testAuditor(),
+		service.OIDCConfig{}, testThrottle, service.PasswordPolicy{}, "admin-token")
+}
+
+func TestAuthService_Authenticate_UnknownLogin(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := newThrottledAuthService(mockUserRepo, mockAttemptRepo)
+
+	hash, _ := utils.HashPassword("Password123!")
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(&domain.User{ID: "user123", Login: "testuser", Password: hash}, nil)
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "nobody").Return(nil, repository.ErrNotFound)
+	mockAttemptRepo.On("LockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	mockAttemptRepo.On("FailLogin", mock.Anything, mock.Anything, 24*time.Hour).Return(int64(1), nil)
+
+	_, wrongPasswordErr := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "wrong", nil)
+	_, unknownLoginErr := authService.Authenticate(context.Background(), "127.0.0.1", "nobody", "wrong", nil)
+
+	assert.ErrorIs(t, wrongPasswordErr, service.ErrInvalidCredentials)
+	assert.Equal(t, wrongPasswordErr, unknownLoginErr)
+	mockAttemptRepo.AssertCalled(t, "FailLogin", mock.Anything, "login:nobody", 24*time.Hour)
+	mockAttemptRepo.AssertCalled(t, "FailLogin", mock.Anything, "ip:127.0.0.1", 24*time.Hour)
+	mockAttemptRepo.AssertNotCalled(t, "Lock")
+}
+
+func TestAuthService_Authenticate_Backoff(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := newThrottledAuthService(mockUserRepo, mockAttemptRepo)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "testuser").Return(nil, repository.ErrNotFound)
+	mockAttemptRepo.On("LockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	mockAttemptRepo.On("FailLogin", mock.Anything, "login:testuser", 24*time.Hour).Return(int64(5), nil).Once()
+	mockAttemptRepo.On("FailLogin", mock.Anything, "login:testuser", 24*time.Hour).Return(int64(9), nil).Once()
+	mockAttemptRepo.On("FailLogin", mock.Anything, "login:testuser", 24*time.Hour).Return(int64(10), nil).Once()
+	mockAttemptRepo.On("FailLogin", mock.Anything, "ip:127.0.0.1", 24*time.Hour).Return(int64(1), nil)
+	mockAttemptRepo.On("Lock", mock.Anything, "login:testuser", mock.Anything).Return(nil)
+
+	for range 3 {
+		_, err := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "wrong", nil)
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	}
+
+	mockAttemptRepo.AssertCalled(t, "Lock", mock.Anything, "login:testuser", 2*time.Second)
+	mockAttemptRepo.AssertCalled(t, "Lock", mock.Anything, "login:testuser", 30*time.Second)
+	mockAttemptRepo.AssertCalled(t, "Lock", mock.Anything, "login:testuser", time.Hour)
+}
+
+func TestAuthService_Authenticate_LockedOut(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := newThrottledAuthService(mockUserRepo, mockAttemptRepo)
+
+	mockAttemptRepo.On("LockedFor", mock.Anything, "login:testuser").Return(30*time.Second, nil)
+
+	_, err := authService.Authenticate(context.Background(), "127.0.0.1", "testuser", "Password123!", nil)
+
+	assert.ErrorIs(t, err, service.ErrTooManyAttempts)
+	var throttled *service.ThrottledError
+	require.True(t, errors.As(err, &throttled))
+	assert.Equal(t, 30*time.Second, throttled.RetryAfter)
+	mockUserRepo.AssertNotCalled(t, "GetUserByLogin")
+}
+
+func TestAuthService_UnlockLogin(t *testing.T) {
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepository)
+	authService := newThrottledAuthService(nil, mockAttemptRepo)
+
+	mockAttemptRepo.On("ResetLogin", mock.Anything, "login:testuser").Return(nil)
+
+	err := authService.UnlockLogin(context.Background(), "testuser")
+
+	assert.NoError(t, err)
+	mockAttemptRepo.AssertExpectations(t)
+}
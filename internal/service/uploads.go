@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var (
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadTooLarge = errors.New("upload exceeds maximum size")
+	ErrUploadFinished = errors.New("upload is already finished")
+)
+
+// UploadService implements resumable uploads. Chunks are stored as separate
+// blobs and stitched together into a regular document once the declared
+// length has been received.
+type UploadService interface {
+	CreateUpload(ctx context.Context, meta *domain.DocumentMeta, length int64, owner string) (*domain.Upload, error)
+	GetUpload(ctx context.Context, id, owner string) (*domain.Upload, error)
+	WriteChunk(ctx context.Context, id, owner string, offset int64, r io.Reader) (*domain.Upload, *domain.Document, error)
+	TerminateUpload(ctx context.Context, id, owner string) error
+	ExpireUploads(ctx context.Context) (int, error)
+}
+
+type uploadService struct {
+	uploadRepo repository.UploadRepository
+	folderRepo repository.FolderRepository
+	blobStore  repository.BlobStore
+	docService DocumentService
+	access     *AccessControl
+	ttl        time.Duration
+	maxSize    int64
+}
+
+func NewUploadService(
+	uploadRepo repository.UploadRepository,
+	folderRepo repository.FolderRepository,
+	blobStore repository.BlobStore,
+	docService DocumentService,
+	access *AccessControl,
+	ttl time.Duration,
+	maxSize int64,
+) UploadService {
+	return &uploadService{
+		uploadRepo: uploadRepo,
+		folderRepo: folderRepo,
+		blobStore:  blobStore,
+		docService: docService,
+		access:     access,
+		ttl:        ttl,
+		maxSize:    maxSize,
+	}
+}
+
+func (s *uploadService) CreateUpload(ctx context.Context, meta *domain.DocumentMeta, length int64, owner string) (*domain.Upload, error) {
+	if length < 0 {
+		return nil, errors.New("invalid upload length")
+	}
+	if s.maxSize > 0 && length > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	// The folder and grants are checked again when the document is created,
+	// but a mistake in them should not surface only after the whole file has
+	// been sent.
+	if err := checkFolder(ctx, s.folderRepo, meta.FolderID, owner); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.access.resolveGrants(ctx, meta.Grant)
+	if err != nil {
+		return nil, err
+	}
+
+	meta.File = true
+	meta.Grant = grants
+	now := time.Now()
+
+	upload := &domain.Upload{
+		ID:      utils.GenerateID(),
+		Owner:   owner,
+		Meta:    *meta,
+		Length:  length,
+		Chunks:  []string{},
+		State:   domain.UploadInProgress,
+		Created: now,
+		Expires: now.Add(s.ttl),
+	}
+
+	if err := s.uploadRepo.CreateUpload(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func (s *uploadService) GetUpload(ctx context.Context, id, owner string) (*domain.Upload, error) {
+	upload, err := s.uploadRepo.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.Owner != owner {
+		return nil, ErrAccessDenied
+	}
+
+	if time.Now().After(upload.Expires) {
+		return nil, ErrNotFound
+	}
+
+	return upload, nil
+}
+
+// WriteChunk appends the bytes read from r at offset. When the upload becomes
+// complete it is turned into a document, which is returned alongside the
+// final upload state. If reading r fails, the bytes received until then are
+// still appended before the error is returned, so the client can resume from
+// the new offset.
+func (s *uploadService) WriteChunk(ctx context.Context, id, owner string, offset int64, r io.Reader) (*domain.Upload, *domain.Document, error) {
+	upload, err := s.GetUpload(ctx, id, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if offset != upload.Offset {
+		return nil, nil, ErrOffsetMismatch
+	}
+
+	if upload.Offset < upload.Length {
+		key := fmt.Sprintf("uploads/%s/%d", upload.ID, offset)
+		body := &partialReader{r: io.LimitReader(r, upload.Length-offset)}
+		// The request context is cancelled as soon as the client goes away,
+		// which must not discard what it sent until then.
+		storeCtx := context.WithoutCancel(ctx)
+		info, err := s.blobStore.Put(storeCtx, key, body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if info.Size > 0 {
+			expires := time.Now().Add(s.ttl)
+			err := s.uploadRepo.AppendChunk(storeCtx, upload.ID, offset, key, info.Size, expires)
+			if err != nil {
+				s.blobStore.Delete(storeCtx, key)
+				if errors.Is(err, repository.ErrConflict) {
+					return nil, nil, ErrOffsetMismatch
+				}
+				return nil, nil, err
+			}
+
+			upload.Offset += info.Size
+			upload.Chunks = append(upload.Chunks, key)
+			upload.Expires = expires
+		} else {
+			s.blobStore.Delete(storeCtx, key)
+		}
+
+		if body.err != nil {
+			return nil, nil, body.err
+		}
+	}
+
+	if upload.Offset < upload.Length {
+		return upload, nil, nil
+	}
+
+	doc, err := s.finish(ctx, upload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return upload, doc, nil
+}
+
+// partialReader ends at the first error of r as if the body were complete and
+// keeps the error, so that a chunk cut short can be stored up to where it
+// broke off.
+type partialReader struct {
+	r   io.Reader
+	err error
+}
+
+func (p *partialReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if err != nil && err != io.EOF {
+		p.err = err
+		err = io.EOF
+	}
+	return n, err
+}
+
+// finish turns the complete upload into a document. Concurrent requests may
+// both see the upload complete, so it is first moved to the finished state and
+// only the request that manages to do so creates the document.
+func (s *uploadService) finish(ctx context.Context, upload *domain.Upload) (*domain.Document, error) {
+	err := s.uploadRepo.SetUploadState(ctx, upload.ID, domain.UploadInProgress, domain.UploadFinished)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, ErrUploadFinished
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.createDocument(ctx, upload)
+	if err != nil {
+		// Let the client retry the last request.
+		s.uploadRepo.SetUploadState(ctx, upload.ID, domain.UploadFinished, domain.UploadInProgress)
+		return nil, err
+	}
+
+	s.remove(ctx, upload)
+	return doc, nil
+}
+
+func (s *uploadService) createDocument(ctx context.Context, upload *domain.Upload) (*domain.Document, error) {
+	readers := make([]io.Reader, 0, len(upload.Chunks))
+	for _, key := range upload.Chunks {
+		chunk, err := s.blobStore.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		defer chunk.Close()
+		readers = append(readers, chunk)
+	}
+
+	meta := upload.Meta
+	return s.docService.UploadDocument(ctx, &meta, io.MultiReader(readers...), "", upload.Owner)
+}
+
+func (s *uploadService) TerminateUpload(ctx context.Context, id, owner string) error {
+	upload, err := s.GetUpload(ctx, id, owner)
+	if err != nil {
+		return err
+	}
+
+	return s.remove(ctx, upload)
+}
+
+// ExpireUploads removes uploads that have not received data within the
+// configured time to live and returns how many were removed.
+func (s *uploadService) ExpireUploads(ctx context.Context) (int, error) {
+	uploads, err := s.uploadRepo.GetExpiredUploads(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range uploads {
+		if err := s.remove(ctx, &uploads[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(uploads), nil
+}
+
+func (s *uploadService) remove(ctx context.Context, upload *domain.Upload) error {
+	if err := s.uploadRepo.DeleteUpload(ctx, upload.ID); err != nil {
+		return err
+	}
+
+	for _, key := range upload.Chunks {
+		s.blobStore.Delete(ctx, key)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newUploadService(uploadRepo *mocks.MockUploadRepository, docRepo *mocks.MockDocumentRepository,
+	cacheRepo *mocks.MockCacheRepository, blobStore *mocks.MockBlobStore) service.UploadService {
+	access := testAccess()
+	folderRepo := new(mocks.MockFolderRepository)
+	docService := service.NewDocumentService(docRepo, folderRepo, access, testAuditor(), cacheRepo, blobStore)
+	return service.NewUploadService(uploadRepo, folderRepo, blobStore, docService, access, time.Hour, 100)
+}
+
+func TestUploadService_CreateUpload_Success(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), new(mocks.MockBlobStore))
+
+	mockUploadRepo.On("CreateUpload", mock.Anything, mock.MatchedBy(func(u *domain.Upload) bool {
+		return u.Owner == "user123" && u.Length == 10 && u.Offset == 0 && u.Meta.File && u.State == domain.UploadInProgress
+	})).Return(nil)
+
+	upload, err := uploadService.CreateUpload(context.Background(), &domain.DocumentMeta{Name: "video.mp4"}, 10, "user123")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, upload.ID)
+	assert.True(t, upload.Expires.After(time.Now()))
+	mockUploadRepo.AssertExpectations(t)
+}
+
+func TestUploadService_CreateUpload_TooLarge(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), new(mocks.MockBlobStore))
+
+	upload, err := uploadService.CreateUpload(context.Background(), &domain.DocumentMeta{Name: "video.mp4"}, 101, "user123")
+
+	assert.ErrorIs(t, err, service.ErrUploadTooLarge)
+	assert.Nil(t, upload)
+	mockUploadRepo.AssertNotCalled(t, "CreateUpload")
+}
+
+func TestUploadService_CreateUpload_UnknownGrantee(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	access := service.NewAccessControl(mockUserRepo, new(mocks.MockGroupRepository))
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	docService := service.NewDocumentService(new(mocks.MockDocumentRepository), mockFolderRepo,
+		access, testAuditor(), new(mocks.MockCacheRepository), mockBlobStore)
+	uploadService := service.NewUploadService(mockUploadRepo, mockFolderRepo, mockBlobStore, docService, access, time.Hour, 100)
+
+	mockUserRepo.On("GetUserByLogin", mock.Anything, "nobody").Return(nil, repository.ErrNotFound)
+
+	meta := &domain.DocumentMeta{Name: "video.mp4", Grant: []domain.Grant{{Grantee: "nobody"}}}
+	upload, err := uploadService.CreateUpload(context.Background(), meta, 10, "user123")
+
+	assert.ErrorIs(t, err, service.ErrUnknownUser)
+	assert.Nil(t, upload)
+	mockUploadRepo.AssertNotCalled(t, "CreateUpload")
+}
+
+func TestUploadService_CreateUpload_Folder(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	access := testAccess()
+	docService := service.NewDocumentService(new(mocks.MockDocumentRepository), mockFolderRepo,
+		access, testAuditor(), new(mocks.MockCacheRepository), mockBlobStore)
+	uploadService := service.NewUploadService(mockUploadRepo, mockFolderRepo, mockBlobStore, docService, access, time.Hour, 100)
+
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "f1").Return(&domain.Folder{ID: "f1", Owner: "owner1"}, nil)
+	mockFolderRepo.On("GetFolderByID", mock.Anything, "gone").Return(nil, repository.ErrNotFound)
+	mockUploadRepo.On("CreateUpload", mock.Anything, mock.AnythingOfType("*domain.Upload")).Return(nil)
+
+	_, err := uploadService.CreateUpload(context.Background(), &domain.DocumentMeta{Name: "video.mp4", FolderID: "f1"}, 10, "user123")
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	_, err = uploadService.CreateUpload(context.Background(), &domain.DocumentMeta{Name: "video.mp4", FolderID: "gone"}, 10, "owner1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	mockUploadRepo.AssertNotCalled(t, "CreateUpload")
+
+	upload, err := uploadService.CreateUpload(context.Background(), &domain.DocumentMeta{Name: "video.mp4", FolderID: "f1"}, 10, "owner1")
+	assert.NoError(t, err)
+	assert.Equal(t, "f1", upload.Meta.FolderID)
+}
+
+func TestUploadService_WriteChunk_OffsetMismatch(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), mockBlobStore)
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID: "up1", Owner: "user123", Length: 10, Offset: 4, Expires: time.Now().Add(time.Hour),
+	}, nil)
+
+	upload, doc, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 0, strings.NewReader("0123"))
+
+	assert.ErrorIs(t, err, service.ErrOffsetMismatch)
+	assert.Nil(t, upload)
+	assert.Nil(t, doc)
+	mockBlobStore.AssertNotCalled(t, "Put")
+}
+
+func TestUploadService_WriteChunk_AccessDenied(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), new(mocks.MockBlobStore))
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID: "up1", Owner: "otheruser", Length: 10, Expires: time.Now().Add(time.Hour),
+	}, nil)
+
+	_, _, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 0, strings.NewReader("0123"))
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+}
+
+func TestUploadService_WriteChunk_Partial(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	uploadService := newUploadService(mockUploadRepo, mockDocRepo, new(mocks.MockCacheRepository), mockBlobStore)
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID: "up1", Owner: "user123", Length: 10, Chunks: []string{}, Expires: time.Now().Add(time.Hour),
+	}, nil)
+	mockBlobStore.On("Put", mock.Anything, "uploads/up1/0", mock.Anything).Return(&repository.BlobInfo{Key: "uploads/up1/0", Size: 4}, nil)
+	mockUploadRepo.On("AppendChunk", mock.Anything, "up1", int64(0), "uploads/up1/0", int64(4), mock.Anything).Return(nil)
+
+	upload, doc, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 0, strings.NewReader("0123"))
+
+	assert.NoError(t, err)
+	assert.Nil(t, doc)
+	assert.Equal(t, int64(4), upload.Offset)
+	assert.Equal(t, []string{"uploads/up1/0"}, upload.Chunks)
+	mockUploadRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
+	mockDocRepo.AssertNotCalled(t, "CreateDocument")
+}
+
+func TestUploadService_WriteChunk_ConnectionDropped(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), mockBlobStore)
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID: "up1", Owner: "user123", Length: 10, Chunks: []string{}, Expires: time.Now().Add(time.Hour),
+	}, nil)
+
+	var stored string
+	mockBlobStore.On("Put", mock.Anything, "uploads/up1/0", mock.Anything).Run(func(args mock.Arguments) {
+		data, err := io.ReadAll(args.Get(2).(io.Reader))
+		assert.NoError(t, err)
+		stored = string(data)
+	}).Return(&repository.BlobInfo{Key: "uploads/up1/0", Size: 4}, nil)
+	mockUploadRepo.On("AppendChunk", mock.Anything, "up1", int64(0), "uploads/up1/0", int64(4), mock.Anything).Return(nil)
+
+	body := io.MultiReader(strings.NewReader("0123"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	_, _, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 0, body)
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "0123", stored)
+	mockUploadRepo.AssertExpectations(t)
+}
+
+func TestUploadService_WriteChunk_Completes(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	uploadService := newUploadService(mockUploadRepo, mockDocRepo, mockCacheRepo, mockBlobStore)
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID:      "up1",
+		Owner:   "user123",
+		Meta:    domain.DocumentMeta{Name: "video.mp4", File: true, Mime: "video/mp4"},
+		Length:  10,
+		Offset:  4,
+		Chunks:  []string{"uploads/up1/0"},
+		Expires: time.Now().Add(time.Hour),
+	}, nil)
+	mockBlobStore.On("Put", mock.Anything, "uploads/up1/4", mock.Anything).Return(&repository.BlobInfo{Key: "uploads/up1/4", Size: 6}, nil)
+	mockUploadRepo.On("AppendChunk", mock.Anything, "up1", int64(4), "uploads/up1/4", int64(6), mock.Anything).Return(nil)
+	mockUploadRepo.On("SetUploadState", mock.Anything, "up1", domain.UploadInProgress, domain.UploadFinished).Return(nil)
+	mockBlobStore.On("Get", mock.Anything, "uploads/up1/0").Return(nopSeekCloser{strings.NewReader("0123")}, nil)
+	mockBlobStore.On("Get", mock.Anything, "uploads/up1/4").Return(nopSeekCloser{strings.NewReader("456789")}, nil)
+
+	var stored string
+	mockBlobStore.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool {
+		return !strings.HasPrefix(key, "uploads/")
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		data, _ := io.ReadAll(args.Get(2).(io.Reader))
+		stored = string(data)
+	}).Return(&repository.BlobInfo{Key: "blob123", Size: 10, Checksum: "checksum"}, nil)
+	mockDocRepo.On("CreateDocument", mock.Anything, mock.MatchedBy(func(doc *domain.Document) bool {
+		return doc.Name == "video.mp4" && doc.Owner == "user123" && doc.StorageKey == "blob123"
+	})).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*user123*").Return(nil)
+	mockUploadRepo.On("DeleteUpload", mock.Anything, "up1").Return(nil)
+	mockBlobStore.On("Delete", mock.Anything, "uploads/up1/0").Return(nil)
+	mockBlobStore.On("Delete", mock.Anything, "uploads/up1/4").Return(nil)
+
+	upload, doc, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 4, strings.NewReader("456789"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), upload.Offset)
+	assert.NotNil(t, doc)
+	assert.Equal(t, "0123456789", stored)
+	mockUploadRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
+	mockDocRepo.AssertExpectations(t)
+}
+
+func TestUploadService_WriteChunk_AlreadyFinished(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	uploadService := newUploadService(mockUploadRepo, mockDocRepo, new(mocks.MockCacheRepository), mockBlobStore)
+
+	mockUploadRepo.On("GetUpload", mock.Anything, "up1").Return(&domain.Upload{
+		ID: "up1", Owner: "user123", Length: 10, Offset: 10, Chunks: []string{"uploads/up1/0"},
+		State: domain.UploadFinished, Expires: time.Now().Add(time.Hour),
+	}, nil)
+	mockUploadRepo.On("SetUploadState", mock.Anything, "up1", domain.UploadInProgress, domain.UploadFinished).
+		Return(repository.ErrConflict)
+
+	_, doc, err := uploadService.WriteChunk(context.Background(), "up1", "user123", 10, strings.NewReader(""))
+
+	assert.ErrorIs(t, err, service.ErrUploadFinished)
+	assert.Nil(t, doc)
+	mockDocRepo.AssertNotCalled(t, "CreateDocument")
+	mockUploadRepo.AssertNotCalled(t, "DeleteUpload")
+}
+
+func TestUploadService_ExpireUploads(t *testing.T) {
+	mockUploadRepo := new(mocks.MockUploadRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	uploadService := newUploadService(mockUploadRepo, new(mocks.MockDocumentRepository),
+		new(mocks.MockCacheRepository), mockBlobStore)
+
+	mockUploadRepo.On("GetExpiredUploads", mock.Anything, mock.Anything).Return([]domain.Upload{
+		{ID: "up1", Chunks: []string{"uploads/up1/0"}},
+		{ID: "up2", Chunks: []string{}},
+	}, nil)
+	mockUploadRepo.On("DeleteUpload", mock.Anything, "up1").Return(nil)
+	mockUploadRepo.On("DeleteUpload", mock.Anything, "up2").Return(nil)
+	mockBlobStore.On("Delete", mock.Anything, "uploads/up1/0").Return(nil)
+
+	n, err := uploadService.ExpireUploads(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	mockUploadRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
+}
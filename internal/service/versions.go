@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/pkg/diff"
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+var ErrNotDiffable = errors.New("diff is only available for JSON documents")
+
+// UpdateDocument stores new content for the document as its next version.
+// Editors may do so as well as the owner and co-owners.
+func (s *documentService) UpdateDocument(ctx context.Context, docID, userID, login string, content io.Reader, jsonData string) (_ *domain.Document, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditUpload, DocumentID: docID,
+			Detail: "new version"}, err)
+	}()
+
+	doc, err := s.documentFor(ctx, docID, userID, login, domain.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &domain.DocumentVersion{
+		DocumentID: doc.ID,
+		Mime:       doc.Mime,
+		Author:     userID,
+		Created:    time.Now(),
+	}
+
+	if doc.File {
+		content, text := captureText(doc.Mime, content)
+		info, err := s.blobStore.Put(ctx, utils.GenerateID(), content)
+		if err != nil {
+			return nil, err
+		}
+		version.StorageKey = info.Key
+		version.Size = info.Size
+		version.Checksum = info.Checksum
+		version.ContentText = text()
+	} else {
+		version.JSON = jsonData
+		version.Checksum = utils.Checksum([]byte(jsonData))
+	}
+
+	if err := s.docRepo.AddVersion(ctx, version); err != nil {
+		if version.StorageKey != "" {
+			s.blobStore.Delete(ctx, version.StorageKey)
+		}
+		return nil, err
+	}
+
+	applyVersion(doc, version)
+	s.invalidateDocument(ctx, doc)
+	return doc, nil
+}
+
+func (s *documentService) GetVersions(ctx context.Context, docID, userID, login string) ([]domain.DocumentVersion, error) {
+	if _, err := s.getDocument(ctx, docID, userID, login); err != nil {
+		return nil, err
+	}
+
+	return s.docRepo.GetVersions(ctx, docID)
+}
+
+// GetVersion returns the document as it was at the given version and, for
+// file documents, a reader over that version's content.
+func (s *documentService) GetVersion(ctx context.Context, docID, userID, login string, version int) (_ *domain.Document, _ io.ReadSeekCloser, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditRead, DocumentID: docID,
+			Detail: fmt.Sprintf("version %d", version)}, err)
+	}()
+
+	doc, err := s.getDocument(ctx, docID, userID, login)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, err := s.docRepo.GetVersion(ctx, docID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	view := *doc
+	applyVersion(&view, v)
+
+	if !view.File {
+		return &view, nil, nil
+	}
+
+	content, err := s.blobStore.Get(ctx, view.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &view, content, nil
+}
+
+// RestoreVersion makes the content of an earlier version current again by
+// adding it as a new version. The blob is shared, not copied.
+func (s *documentService) RestoreVersion(ctx context.Context, docID, userID, login string, version int) (_ *domain.Document, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditUpload, DocumentID: docID,
+			Detail: fmt.Sprintf("restore version %d", version)}, err)
+	}()
+
+	doc, err := s.documentFor(ctx, docID, userID, login, domain.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.docRepo.GetVersion(ctx, docID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := *v
+	restored.Author = userID
+	restored.Created = time.Now()
+
+	if err := s.docRepo.AddVersion(ctx, &restored); err != nil {
+		return nil, err
+	}
+
+	applyVersion(doc, &restored)
+	s.invalidateDocument(ctx, doc)
+	return doc, nil
+}
+
+func (s *documentService) DiffVersions(ctx context.Context, docID, userID, login string, from, to int) (_ *domain.VersionDiff, err error) {
+	defer func() {
+		s.auditor.record(ctx, domain.AuditEvent{ActorID: userID, Actor: login, Action: domain.AuditRead, DocumentID: docID,
+			Detail: fmt.Sprintf("diff %d..%d", from, to)}, err)
+	}()
+
+	doc, err := s.getDocument(ctx, docID, userID, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.File {
+		return nil, ErrNotDiffable
+	}
+
+	a, err := s.docRepo.GetVersion(ctx, docID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.docRepo.GetVersion(ctx, docID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.VersionDiff{
+		From: from,
+		To:   to,
+		Unified: diff.Unified(fmt.Sprintf("v%d", from), fmt.Sprintf("v%d", to),
+			indentJSON(a.JSON), indentJSON(b.JSON), 3),
+	}
+
+	if changes, err := diff.JSON([]byte(a.JSON), []byte(b.JSON)); err == nil {
+		result.Changes = changes
+	}
+
+	return result, nil
+}
+
+func applyVersion(doc *domain.Document, v *domain.DocumentVersion) {
+	doc.Version = v.Version
+	doc.Updated = v.Created
+	doc.Mime = v.Mime
+	doc.StorageKey = v.StorageKey
+	doc.Size = v.Size
+	doc.Checksum = v.Checksum
+	doc.JSON = v.JSON
+	doc.ContentText = v.ContentText
+}
+
+// indentJSON pretty prints valid JSON so that the line diff points at the
+// changed values rather than at a single long line.
+func indentJSON(s string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return out.String()
+}
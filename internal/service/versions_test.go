@@ -0,0 +1,256 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibrgmv/document-service/internal/domain"
+	"github.com/mibrgmv/document-service/internal/repository"
+	"github.com/mibrgmv/document-service/internal/service"
+	"github.com/mibrgmv/document-service/internal/service/mocks"
+	"github.com/mibrgmv/document-service/pkg/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDocumentService_UpdateDocument_Success_File(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:         "123",
+		Name:       "test.txt",
+		Mime:       "text/plain",
+		File:       true,
+		Owner:      "user123",
+		StorageKey: "blob1",
+		Version:    1,
+	}, nil)
+	mockBlobStore.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(&repository.BlobInfo{
+		Key:      "blob2",
+		Size:     11,
+		Checksum: "checksum2",
+	}, nil)
+	mockDocRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(v *domain.DocumentVersion) bool {
+		return v.DocumentID == "123" && v.StorageKey == "blob2" && v.Author == "user123"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.DocumentVersion).Version = 2
+	}).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "doc:123*").Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, "docs:*user123*").Return(nil)
+
+	doc, err := docService.UpdateDocument(context.Background(), "123", "user123", "testuser", strings.NewReader("new content"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, doc.Version)
+	assert.Equal(t, "blob2", doc.StorageKey)
+	assert.Equal(t, int64(11), doc.Size)
+	mockDocRepo.AssertExpectations(t)
+	mockCacheRepo.AssertExpectations(t)
+	mockBlobStore.AssertExpectations(t)
+}
+
+func TestDocumentService_UpdateDocument_NotOwner(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:     "123",
+		Owner:  "owner1",
+		Public: true,
+	}, nil)
+
+	_, err := docService.UpdateDocument(context.Background(), "123", "user123", "testuser", nil, `{"a":1}`)
+
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+	mockDocRepo.AssertNotCalled(t, "AddVersion")
+	mockBlobStore.AssertNotCalled(t, "Put")
+}
+
+func TestDocumentService_UpdateDocument_AddVersionFails_RemovesBlob(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:    "123",
+		File:  true,
+		Owner: "user123",
+	}, nil)
+	mockBlobStore.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(&repository.BlobInfo{Key: "blob2"}, nil)
+	mockDocRepo.On("AddVersion", mock.Anything, mock.Anything).Return(errors.New("database error"))
+	mockBlobStore.On("Delete", mock.Anything, "blob2").Return(nil)
+
+	_, err := docService.UpdateDocument(context.Background(), "123", "user123", "testuser", strings.NewReader("x"), "")
+
+	assert.Error(t, err)
+	mockBlobStore.AssertExpectations(t)
+	mockCacheRepo.AssertNotCalled(t, "DeletePattern")
+}
+
+func TestDocumentService_UpdateDocument_Roles(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:     "123",
+		Owner:  "owner1",
+		Public: true,
+		Grant: []domain.Grant{
+			{Grantee: "editor", Role: domain.RoleEditor, UserID: "user1"},
+			{Grantee: "commenter", Role: domain.RoleCommenter, UserID: "user2"},
+		},
+	}, nil)
+	mockDocRepo.On("AddVersion", mock.Anything, mock.AnythingOfType("*domain.DocumentVersion")).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := docService.UpdateDocument(context.Background(), "123", "user2", "commenter", nil, `{"a":1}`)
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	_, err = docService.UpdateDocument(context.Background(), "123", "user3", "stranger", nil, `{"a":1}`)
+	assert.ErrorIs(t, err, service.ErrAccessDenied)
+
+	doc, err := docService.UpdateDocument(context.Background(), "123", "user1", "editor", nil, `{"a":1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, doc.JSON)
+	mockDocRepo.AssertNumberOfCalls(t, "AddVersion", 1)
+}
+
+func TestDocumentService_RestoreVersion_Success(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockDocRepo.On("GetDocumentByID", mock.Anything, "123").Return(&domain.Document{
+		ID:         "123",
+		File:       true,
+		Owner:      "user123",
+		StorageKey: "blob3",
+		Version:    3,
+	}, nil)
+	mockDocRepo.On("GetVersion", mock.Anything, "123", 1).Return(&domain.DocumentVersion{
+		DocumentID: "123",
+		Version:    1,
+		StorageKey: "blob1",
+		Checksum:   "checksum1",
+		Author:     "someone",
+	}, nil)
+	mockDocRepo.On("AddVersion", mock.Anything, mock.MatchedBy(func(v *domain.DocumentVersion) bool {
+		return v.StorageKey == "blob1" && v.Checksum == "checksum1" && v.Author == "user123"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.DocumentVersion).Version = 4
+	}).Return(nil)
+	mockCacheRepo.On("DeletePattern", mock.Anything, mock.Anything).Return(nil)
+
+	doc, err := docService.RestoreVersion(context.Background(), "123", "user123", "testuser", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, doc.Version)
+	assert.Equal(t, "blob1", doc.StorageKey)
+	mockDocRepo.AssertExpectations(t)
+	mockBlobStore.AssertNotCalled(t, "Put")
+}
+
+func TestDocumentService_GetVersion_File(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	current := &domain.Document{
+		ID:         "123",
+		Name:       "test.txt",
+		File:       true,
+		Owner:      "user123",
+		StorageKey: "blob2",
+		Version:    2,
+	}
+	created := time.Now().Add(-time.Hour)
+
+	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(current, nil)
+	mockDocRepo.On("GetVersion", mock.Anything, "123", 1).Return(&domain.DocumentVersion{
+		DocumentID: "123",
+		Version:    1,
+		StorageKey: "blob1",
+		Created:    created,
+	}, nil)
+	mockBlobStore.On("Get", mock.Anything, "blob1").Return(nopSeekCloser{strings.NewReader("old")}, nil)
+
+	doc, content, err := docService.GetVersion(context.Background(), "123", "user123", "testuser", 1)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, content)
+	assert.Equal(t, 1, doc.Version)
+	assert.Equal(t, created, doc.Updated)
+	assert.Equal(t, "test.txt", doc.Name)
+	assert.Equal(t, 2, current.Version, "cached document must not be modified")
+	mockBlobStore.AssertExpectations(t)
+}
+
+func TestDocumentService_DiffVersions_JSON(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(&domain.Document{
+		ID:    "123",
+		Owner: "user123",
+	}, nil)
+	mockDocRepo.On("GetVersion", mock.Anything, "123", 1).Return(&domain.DocumentVersion{
+		Version: 1,
+		JSON:    `{"name":"a","tags":["x"]}`,
+	}, nil)
+	mockDocRepo.On("GetVersion", mock.Anything, "123", 2).Return(&domain.DocumentVersion{
+		Version: 2,
+		JSON:    `{"name":"b","tags":["x"],"size":3}`,
+	}, nil)
+
+	result, err := docService.DiffVersions(context.Background(), "123", "user123", "testuser", 1, 2)
+
+	assert.NoError(t, err)
+	assert.Contains(t, result.Unified, `-  "name": "a",`)
+	assert.Contains(t, result.Unified, `+  "name": "b",`)
+	assert.ElementsMatch(t, []diff.Change{
+		{Op: "replace", Path: "/name", Old: "a", New: "b"},
+		{Op: "add", Path: "/size", New: json.Number("3")},
+	}, result.Changes)
+}
+
+func TestDocumentService_DiffVersions_File(t *testing.T) {
+	mockDocRepo := new(mocks.MockDocumentRepository)
+	mockFolderRepo := new(mocks.MockFolderRepository)
+	mockCacheRepo := new(mocks.MockCacheRepository)
+	mockBlobStore := new(mocks.MockBlobStore)
+	docService := service.NewDocumentService(mockDocRepo, mockFolderRepo, testAccess(), testAuditor(), mockCacheRepo, mockBlobStore)
+
+	mockCacheRepo.On("GetDocument", mock.Anything, "doc:123:user123").Return(&domain.Document{
+		ID:    "123",
+		File:  true,
+		Owner: "user123",
+	}, nil)
+
+	_, err := docService.DiffVersions(context.Background(), "123", "user123", "testuser", 1, 2)
+
+	assert.ErrorIs(t, err, service.ErrNotDiffable)
+	mockDocRepo.AssertNotCalled(t, "GetVersion")
+}
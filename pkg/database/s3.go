@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"github.com/mibrgmv/document-service/internal/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func NewS3(cfg *config.Config) (*minio.Client, error) {
+	client, err := minio.New(cfg.Storage.S3.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.Storage.S3.AccessKey, cfg.Storage.S3.SecretKey, ""),
+		Secure: cfg.Storage.S3.UseSSL,
+		Region: cfg.Storage.S3.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, cfg.Storage.S3.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Storage.S3.Bucket, minio.MakeBucketOptions{Region: cfg.Storage.S3.Region}); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Println("connected to s3")
+	return client, nil
+}
@@ -0,0 +1,242 @@
+// Package diff computes line based and structural JSON differences.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type op byte
+
+const (
+	opEqual  op = ' '
+	opDelete op = '-'
+	opInsert op = '+'
+)
+
+type edit struct {
+	op   op
+	line string
+}
+
+// Unified returns a unified diff of a and b with the given number of context
+// lines. It returns an empty string when the inputs are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for _, h := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.fromLine, h.fromCount), hunkRange(h.toLine, h.toCount))
+		for _, e := range h.edits {
+			out.WriteByte(byte(e.op))
+			out.WriteString(e.line)
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineEdits finds a shortest edit script with the linear space variant of the
+// Myers algorithm: it finds the middle snake of the edit graph, then diffs the
+// lines before and after it, so memory stays proportional to the input.
+func lineEdits(a, b []string) []edit {
+	edits := make([]edit, 0, max(len(a), len(b)))
+	return appendEdits(edits, a, b)
+}
+
+func appendEdits(edits []edit, a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		edits = append(edits, edit{op: opEqual, line: a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			edits = append(edits, edit{op: opInsert, line: line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			edits = append(edits, edit{op: opDelete, line: line})
+		}
+	default:
+		x, y := middleSnake(a, b)
+		edits = appendEdits(edits, a[:x], b[:y])
+		edits = appendEdits(edits, a[x:], b[y:])
+	}
+
+	for _, line := range common {
+		edits = append(edits, edit{op: opEqual, line: line})
+	}
+	return edits
+}
+
+// middleSnake runs the Myers search from both corners of the edit graph at
+// once and returns the point where the two paths meet, which lies on a
+// shortest path. a and b must be non-empty and differ in their first and last
+// lines, so the point always splits the graph into two smaller ones.
+func middleSnake(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths meet while extending forward, otherwise
+	// while extending backward.
+	odd := delta%2 != 0
+	// The diagonals that left the graph are skipped in later rounds.
+	var fStart, fEnd, bStart, bEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(backward) && backward[i] != -1 && x >= n-backward[i] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(forward) && forward[i] != -1 {
+					fx := forward[i]
+					if fx >= n-x {
+						return fx, fx - (delta - k)
+					}
+				}
+			}
+		}
+	}
+
+	// Only reached when nothing is shared: every line of a is replaced.
+	return n, 0
+}
+
+type hunk struct {
+	fromLine, fromCount int
+	toLine, toCount     int
+	edits               []edit
+}
+
+// hunks groups changes separated by at most 2*context unchanged lines and
+// surrounds each group with up to context unchanged lines.
+func hunks(edits []edit, context int) []hunk {
+	fromLines := make([]int, len(edits))
+	toLines := make([]int, len(edits))
+	var changes []int
+
+	fromLine, toLine := 1, 1
+	for i, e := range edits {
+		fromLines[i], toLines[i] = fromLine, toLine
+		if e.op != opInsert {
+			fromLine++
+		}
+		if e.op != opDelete {
+			toLine++
+		}
+		if e.op != opEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	var result []hunk
+	for i := 0; i < len(changes); {
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j]-1 <= 2*context {
+			j++
+		}
+
+		start := max(changes[i]-context, 0)
+		end := min(changes[j]+context+1, len(edits))
+
+		h := hunk{fromLine: fromLines[start], toLine: toLines[start]}
+		for _, e := range edits[start:end] {
+			h.edits = append(h.edits, e)
+			if e.op != opInsert {
+				h.fromCount++
+			}
+			if e.op != opDelete {
+				h.toCount++
+			}
+		}
+		result = append(result, h)
+
+		i = j + 1
+	}
+
+	return result
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
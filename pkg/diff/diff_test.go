@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{"both empty", "", "", 3, ""},
+		{"from empty", "", "a\nb\n", 3, "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\nb\n", "", 3, "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"missing final newline", "a\nb", "a\nb\n", 3, ""},
+		{"insert at start", "b\nc\n", "a\nb\nc\n", 1, "@@ -1 +1,2 @@\n+a\n b\n"},
+		{"insert at end", "a\nb\n", "a\nb\nc\n", 1, "@@ -2 +2,2 @@\n b\n+c\n"},
+		{"delete at start", "a\nb\nc\n", "b\nc\n", 1, "@@ -1,2 +1 @@\n-a\n b\n"},
+		{"delete at end", "a\nb\nc\n", "a\nb\n", 1, "@@ -2,2 +2 @@\n b\n-c\n"},
+		{
+			"interleaved",
+			"a\nb\nc\nd\n", "a\nB\nc\nD\n", 0,
+			"@@ -2 +2 @@\n-b\n+B\n@@ -4 +4 @@\n-d\n+D\n",
+		},
+		{
+			"close hunks merge",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\nX\n3\n4\n5\nY\n7\n8\n9\n", 2,
+			"@@ -1,8 +1,8 @@\n 1\n-2\n+X\n 3\n 4\n 5\n-6\n+Y\n 7\n 8\n",
+		},
+		{
+			"gap of twice the context merges",
+			"1\n2\n3\n4\n5\n6\n", "1\nX\n3\n4\nY\n6\n", 1,
+			"@@ -1,6 +1,6 @@\n 1\n-2\n+X\n 3\n 4\n-5\n+Y\n 6\n",
+		},
+		{
+			"distant hunks stay apart",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\nX\n3\n4\n5\nY\n7\n8\n9\n", 1,
+			"@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n@@ -5,3 +5,3 @@\n 5\n-6\n+Y\n 7\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = "--- old\n+++ new\n" + want
+			}
+			assert.Equal(t, want, Unified("old", "new", tt.a, tt.b, tt.context))
+		})
+	}
+}
+
+// TestLineEdits_Script checks on random inputs that the edit script turns the
+// old lines into the new ones and is as short as possible.
+func TestLineEdits_Script(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() []string {
+		lines := make([]string, rng.IntN(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(4)))
+		}
+		return lines
+	}
+
+	for range 1000 {
+		a, b := randomLines(), randomLines()
+		edits := lineEdits(a, b)
+
+		var from, to []string
+		changes := 0
+		for _, e := range edits {
+			if e.op != opInsert {
+				from = append(from, e.line)
+			}
+			if e.op != opDelete {
+				to = append(to, e.line)
+			}
+			if e.op != opEqual {
+				changes++
+			}
+		}
+
+		require.True(t, slices.Equal(a, from), "old text not kept: %q -> %q", a, b)
+		require.True(t, slices.Equal(b, to), "new text not produced: %q -> %q", a, b)
+		require.Equal(t, len(a)+len(b)-2*lcs(a, b), changes, "edit script not shortest: %q -> %q", a, b)
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Change
+	}{
+		{"equal", `{"a": 1, "b": [true]}`, `{"b": [true], "a": 1}`, []Change{}},
+		{"key added", `{"a": 1}`, `{"a": 1, "b": "x"}`, []Change{{Op: "add", Path: "/b", New: "x"}}},
+		{"key removed", `{"a": 1, "b": "x"}`, `{"a": 1}`, []Change{{Op: "remove", Path: "/b", Old: "x"}}},
+		{"value changed", `{"a": 1}`, `{"a": 2}`, []Change{{Op: "replace", Path: "/a", Old: json.Number("1"), New: json.Number("2")}}},
+		{"type changed", `{"a": 1}`, `{"a": "1"}`, []Change{{Op: "replace", Path: "/a", Old: json.Number("1"), New: "1"}}},
+		{
+			"nested keys in order",
+			`{"b": {"c": null}, "a": 1}`, `{"b": {"d": false}, "a": 1}`,
+			[]Change{{Op: "remove", Path: "/b/c"}, {Op: "add", Path: "/b/d", New: false}},
+		},
+		{
+			"array elements",
+			`{"a": [1, 2, 3]}`, `{"a": [1, 5]}`,
+			[]Change{
+				{Op: "replace", Path: "/a/1", Old: json.Number("2"), New: json.Number("5")},
+				{Op: "remove", Path: "/a/2", Old: json.Number("3")},
+			},
+		},
+		{"pointer escaping", `{}`, `{"a/b~c": 1}`, []Change{{Op: "add", Path: "/a~1b~0c", New: json.Number("1")}}},
+		{"root replaced", `{"a": 1}`, `[1]`, []Change{{Op: "replace", Path: "", Old: map[string]any{"a": json.Number("1")}, New: []any{json.Number("1")}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := JSON([]byte(tt.a), []byte(tt.b))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, changes)
+		})
+	}
+}
+
+func TestJSON_Invalid(t *testing.T) {
+	_, err := JSON([]byte(`{"a": 1}`), []byte(`{"a": `))
+	assert.Error(t, err)
+
+	_, err = JSON([]byte(strings.Repeat("[", 3)), []byte(`[]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change is a single structural difference between two JSON values. Path is
+// a JSON Pointer (RFC 6901) and Op is one of add, remove or replace.
+type Change struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// JSON compares two JSON documents. Objects are compared key by key, arrays
+// element by element.
+func JSON(a, b []byte) ([]Change, error) {
+	var left, right any
+	if err := decode(a, &left); err != nil {
+		return nil, err
+	}
+	if err := decode(b, &right); err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	compare("", left, right, &changes)
+	return changes, nil
+}
+
+func decode(data []byte, v *any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func compare(path string, a, b any, changes *[]Change) {
+	switch left := a.(type) {
+	case map[string]any:
+		right, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(left)+len(right))
+		for key := range left {
+			keys = append(keys, key)
+		}
+		for key := range right {
+			if _, ok := left[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := path + "/" + escapePointer(key)
+			lv, inLeft := left[key]
+			rv, inRight := right[key]
+			switch {
+			case !inRight:
+				*changes = append(*changes, Change{Op: "remove", Path: child, Old: lv})
+			case !inLeft:
+				*changes = append(*changes, Change{Op: "add", Path: child, New: rv})
+			default:
+				compare(child, lv, rv, changes)
+			}
+		}
+		return
+
+	case []any:
+		right, ok := b.([]any)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < len(left) || i < len(right); i++ {
+			child := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(right):
+				*changes = append(*changes, Change{Op: "remove", Path: child, Old: left[i]})
+			case i >= len(left):
+				*changes = append(*changes, Change{Op: "add", Path: child, New: right[i]})
+			default:
+				compare(child, left[i], right[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Op: "replace", Path: path, Old: a, New: b})
+	}
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
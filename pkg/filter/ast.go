@@ -0,0 +1,127 @@
+// Package filter parses filter expressions such as
+//
+//	mime ~ "image/*" and created > 2026-01-01 and not public = true
+//
+// into an AST that callers validate against their fields and compile into a
+// query language of their own.
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a node of a parsed expression: *Logical, *Not or *Comparison.
+type Expr interface {
+	String() string
+}
+
+// Logical joins two expressions with "and" or "or".
+type Logical struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+func (e *Logical) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+type Not struct {
+	Expr Expr
+}
+
+func (e *Not) String() string {
+	return "not " + e.Expr.String()
+}
+
+// Op is a comparison operator. Match and NotMatch compare strings against a
+// glob where * matches any run of characters and ? a single one.
+type Op string
+
+const (
+	Eq        Op = "="
+	NotEq     Op = "!="
+	Less      Op = "<"
+	LessEq    Op = "<="
+	Greater   Op = ">"
+	GreaterEq Op = ">="
+	Match     Op = "~"
+	NotMatch  Op = "!~"
+)
+
+type Comparison struct {
+	Field string
+	Op    Op
+	Value Value
+	Pos   int
+}
+
+func (e *Comparison) String() string {
+	return e.Field + " " + string(e.Op) + " " + e.Value.String()
+}
+
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Bool
+	Time
+)
+
+func (k Kind) String() string {
+	switch k {
+	case String:
+		return "string"
+	case Number:
+		return "number"
+	case Bool:
+		return "boolean"
+	default:
+		return "date"
+	}
+}
+
+// Value is a literal. Only the field matching Kind is set. DateOnly marks
+// times written as a calendar date, which cover the whole day.
+type Value struct {
+	Kind     Kind
+	Str      string
+	Num      int64
+	Bool     bool
+	Time     time.Time
+	DateOnly bool
+	Pos      int
+}
+
+func (v Value) String() string {
+	switch v.Kind {
+	case String:
+		return Quote(v.Str)
+	case Number:
+		return strconv.FormatInt(v.Num, 10)
+	case Bool:
+		return strconv.FormatBool(v.Bool)
+	default:
+		if v.DateOnly {
+			return v.Time.Format(time.DateOnly)
+		}
+		return v.Time.Format(time.RFC3339Nano)
+	}
+}
+
+// Quote returns s as a string literal of the filter language.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Error is a syntax or validation error. Pos is the 1-based byte offset of
+// the offending token.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokLiteral
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	input string
+	off   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.off < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.off:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.off += size
+	}
+
+	start := l.off
+	pos := start + 1
+	if start >= len(l.input) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+
+	c := l.input[start]
+	switch {
+	case c == '(':
+		l.off++
+		return token{kind: tokLParen, text: "(", pos: pos}, nil
+	case c == ')':
+		l.off++
+		return token{kind: tokRParen, text: ")", pos: pos}, nil
+	case c == '"':
+		return l.string(pos)
+	case strings.HasPrefix(l.input[start:], "!=") || strings.HasPrefix(l.input[start:], "!~") ||
+		strings.HasPrefix(l.input[start:], "<=") || strings.HasPrefix(l.input[start:], ">="):
+		l.off += 2
+		return token{kind: tokOp, text: l.input[start:l.off], pos: pos}, nil
+	case c == '=' || c == '<' || c == '>' || c == '~':
+		l.off++
+		return token{kind: tokOp, text: l.input[start:l.off], pos: pos}, nil
+	case isIdentStart(c):
+		for l.off < len(l.input) && isIdentPart(l.input[l.off]) {
+			l.off++
+		}
+		return token{kind: tokIdent, text: l.input[start:l.off], pos: pos}, nil
+	case isDigit(c) || c == '-':
+		// Numbers and dates: 42, -1, 2026-01-01, 2026-01-01T10:00:00Z.
+		l.off++
+		for l.off < len(l.input) && isLiteralPart(l.input[l.off]) {
+			l.off++
+		}
+		return token{kind: tokLiteral, text: l.input[start:l.off], pos: pos}, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.input[start:])
+	return token{}, errorf(pos, "unexpected character %q", r)
+}
+
+func (l *lexer) string(pos int) (token, error) {
+	var b strings.Builder
+	l.off++
+	for l.off < len(l.input) {
+		c := l.input[l.off]
+		switch c {
+		case '"':
+			l.off++
+			return token{kind: tokString, text: b.String(), pos: pos}, nil
+		case '\\':
+			if l.off+1 >= len(l.input) {
+				return token{}, errorf(pos, "unterminated string")
+			}
+			next := l.input[l.off+1]
+			if next != '"' && next != '\\' {
+				return token{}, errorf(l.off+1, "unknown escape \\%c", next)
+			}
+			b.WriteByte(next)
+			l.off += 2
+		default:
+			b.WriteByte(c)
+			l.off++
+		}
+	}
+	return token{}, errorf(pos, "unterminated string")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLiteralPart(c byte) bool {
+	return isIdentPart(c) || c == '-' || c == ':' || c == '.' || c == '+'
+}
@@ -0,0 +1,193 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDepth bounds the nesting of parentheses and "not".
+const maxDepth = 32
+
+// Parse parses a filter expression:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~" | "!~"
+//	value      = "quoted string" | number | date | true | false
+//
+// Keywords are case insensitive. Dates are YYYY-MM-DD or RFC 3339.
+func Parse(input string) (Expr, error) {
+	p := &parser{lex: lexer{input: input}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	expr, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, errorf(p.tok.pos, "unexpected %s", p.tok.describe())
+	}
+	return expr, nil
+}
+
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) keyword(word string) bool {
+	return p.tok.kind == tokIdent && strings.EqualFold(p.tok.text, word)
+}
+
+func (p *parser) or(depth int) (Expr, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and(depth int) (Expr, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, errorf(p.tok.pos, "expression is nested too deeply")
+	}
+
+	switch {
+	case p.keyword("not"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+
+	case p.tok.kind == tokLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, errorf(p.tok.pos, "expected \")\", found %s", p.tok.describe())
+		}
+		return expr, p.advance()
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	if p.tok.kind != tokIdent || p.keyword("and") || p.keyword("or") {
+		return nil, errorf(p.tok.pos, "expected field name, found %s", p.tok.describe())
+	}
+	cmp := &Comparison{Field: strings.ToLower(p.tok.text), Pos: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokOp {
+		return nil, errorf(p.tok.pos, "expected operator after %q, found %s", cmp.Field, p.tok.describe())
+	}
+	cmp.Op = Op(p.tok.text)
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	cmp.Value = value
+	return cmp, p.advance()
+}
+
+func (p *parser) value() (Value, error) {
+	tok := p.tok
+	v := Value{Pos: tok.pos}
+
+	switch tok.kind {
+	case tokString:
+		v.Kind = String
+		v.Str = tok.text
+		return v, nil
+
+	case tokIdent:
+		switch strings.ToLower(tok.text) {
+		case "true", "false":
+			v.Kind = Bool
+			v.Bool = strings.EqualFold(tok.text, "true")
+			return v, nil
+		}
+		return v, errorf(tok.pos, "expected value, found %s (quote strings with \")", tok.describe())
+
+	case tokLiteral:
+		if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			v.Kind = Number
+			v.Num = n
+			return v, nil
+		}
+		if t, err := time.Parse(time.DateOnly, tok.text); err == nil {
+			v.Kind = Time
+			v.Time = t
+			v.DateOnly = true
+			return v, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, tok.text); err == nil {
+			v.Kind = Time
+			v.Time = t.UTC()
+			return v, nil
+		}
+		return v, errorf(tok.pos, "invalid number or date %s", tok.describe())
+	}
+
+	return v, errorf(tok.pos, "expected value, found %s", tok.describe())
+}
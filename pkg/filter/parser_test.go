@@ -0,0 +1,119 @@
+package filter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mibrgmv/document-service/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"comparison", `name = "report"`, `name = "report"`},
+		{"and binds tighter than or", `size = 1 or size = 2 and size = 3`, `(size = 1 or (size = 2 and size = 3))`},
+		{"and is left associative", `size = 1 and size = 2 and size = 3`, `((size = 1 and size = 2) and size = 3)`},
+		{"not binds tighter than and", `not public = true and file = false`, `(not public = true and file = false)`},
+		{"parentheses", `(size = 1 or size = 2) and size = 3`, `((size = 1 or size = 2) and size = 3)`},
+		{"not of a group", `not (size = 1 or size = 2)`, `not (size = 1 or size = 2)`},
+		{"keywords and fields are case insensitive", `NAME = "a" AND Public = TRUE`, `(name = "a" and public = true)`},
+		{"escaped quote and backslash", `name = "say \"hi\" \\ bye"`, `name = "say \"hi\" \\ bye"`},
+		{"glob", `mime ~ "image/*"`, `mime ~ "image/*"`},
+		{"negative number", `size >= -1`, `size >= -1`},
+		{"date", `created > 2026-01-01`, `created > 2026-01-01`},
+		{"time in UTC", `created < 2026-01-01T10:00:00+02:00`, `created < 2026-01-01T08:00:00Z`},
+		{"operators without spaces", `size!=1 and name!~"*.tmp"`, `(size != 1 and name !~ "*.tmp")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := filter.Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr.String())
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		msg   string
+	}{
+		{"empty", ``, 1, `expected field name, found end of filter`},
+		{"missing value", `name =`, 7, `expected value, found end of filter`},
+		{"unquoted string", `name = report`, 8, `quote strings with "`},
+		{"missing operator", `name "a"`, 6, `expected operator after "name"`},
+		{"keyword as field", `and = 1`, 1, `expected field name, found "and"`},
+		{"unclosed parenthesis", `(size = 1`, 10, `expected ")", found end of filter`},
+		{"trailing input", `size = 1 size = 2`, 10, `unexpected "size"`},
+		{"unterminated string", `name = "abc`, 8, `unterminated string`},
+		{"unknown escape", `name = "a\n"`, 10, `unknown escape \n`},
+		{"unexpected character", `name # 1`, 6, `unexpected character '#'`},
+		{"invalid literal", `size = 12abc`, 8, `invalid number or date "12abc"`},
+		{"nested too deeply", strings.Repeat("not ", 40) + `public = true`, 133, `nested too deeply`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filter.Parse(tt.input)
+
+			var filterErr *filter.Error
+			require.True(t, errors.As(err, &filterErr), "got %v", err)
+			assert.Equal(t, tt.pos, filterErr.Pos)
+			assert.Contains(t, filterErr.Msg, tt.msg)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fields := filter.Fields{
+		"name":    filter.String,
+		"public":  filter.Bool,
+		"size":    filter.Number,
+		"created": filter.Time,
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		msg   string
+	}{
+		{"valid", `name ~ "*.pdf" and (size > 10 or public = true) and created >= 2026-01-01`, ""},
+		{"unknown field", `owner = "alice"`, `unknown field "owner"`},
+		{"unknown field in not", `not owner = "alice"`, `unknown field "owner"`},
+		{"ordering a boolean", `public < true`, `operator < cannot be used with boolean field "public"`},
+		{"glob on a number", `size ~ "1*"`, `operator ~ cannot be used with number field "size"`},
+		{"wrong kind", `size = "10"`, `field "size" expects a number, got "10"`},
+		{"wrong kind on the right", `public = true or created = 5`, `field "created" expects a date, got 5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := filter.Parse(tt.input)
+			require.NoError(t, err)
+
+			err = filter.Validate(expr, fields)
+			if tt.msg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.msg)
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{``, `plain`, `with "quotes"`, `back\slash`, `both \"`} {
+		expr, err := filter.Parse(`name = ` + filter.Quote(s))
+		require.NoError(t, err)
+		assert.Equal(t, s, expr.(*filter.Comparison).Value.Str)
+	}
+}
@@ -0,0 +1,46 @@
+package filter
+
+// Fields maps the field names a filter may use to their kind.
+type Fields map[string]Kind
+
+// Validate checks that expr only uses known fields, compares them with values
+// of their kind and applies operators that make sense for it.
+func Validate(expr Expr, fields Fields) error {
+	switch e := expr.(type) {
+	case *Logical:
+		if err := Validate(e.Left, fields); err != nil {
+			return err
+		}
+		return Validate(e.Right, fields)
+
+	case *Not:
+		return Validate(e.Expr, fields)
+
+	case *Comparison:
+		kind, ok := fields[e.Field]
+		if !ok {
+			return errorf(e.Pos, "unknown field %q", e.Field)
+		}
+
+		switch e.Op {
+		case Eq, NotEq:
+		case Less, LessEq, Greater, GreaterEq:
+			if kind == Bool {
+				return errorf(e.Pos, "operator %s cannot be used with %s field %q", e.Op, kind, e.Field)
+			}
+		case Match, NotMatch:
+			if kind != String {
+				return errorf(e.Pos, "operator %s cannot be used with %s field %q", e.Op, kind, e.Field)
+			}
+		default:
+			return errorf(e.Pos, "unknown operator %q", e.Op)
+		}
+
+		if e.Value.Kind != kind {
+			return errorf(e.Value.Pos, "field %q expects a %s, got %s", e.Field, kind, e.Value)
+		}
+		return nil
+	}
+
+	return nil
+}
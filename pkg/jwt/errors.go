@@ -0,0 +1,19 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Every error ValidateToken returns wraps ErrInvalidToken; the wrapping
+// errors tell why the token was rejected.
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrTokenMalformed   = fmt.Errorf("%w: malformed", ErrInvalidToken)
+	ErrTokenSignature   = fmt.Errorf("%w: signature or key not accepted", ErrInvalidToken)
+	ErrTokenExpired     = fmt.Errorf("%w: expired", ErrInvalidToken)
+	ErrTokenNotValidYet = fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	ErrTokenAudience    = fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	ErrTokenIssuer      = fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	ErrTokenClaims      = fmt.Errorf("%w: missing or invalid claims", ErrInvalidToken)
+)
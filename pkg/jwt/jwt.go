@@ -1,56 +1,274 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mibrgmv/document-service/pkg/utils"
 )
 
+// Config sets up a Manager. Algorithm is the only one tokens are accepted
+// with: HS256 signs with Secret, RS256 and EdDSA with the keys given to
+// SetKeys. Leeway is the clock skew tolerated when checking exp, nbf and iat.
+// A new key only signs once it is PublishDelay old, so verifiers that cache
+// the keys have had the time to pick it up.
+type Config struct {
+	Algorithm         string
+	Secret            string
+	Issuer            string
+	Audience          string
+	Expiration        time.Duration
+	RefreshExpiration time.Duration
+	Leeway            time.Duration
+	PublishDelay      time.Duration
+}
+
 type Manager struct {
-	secret     string
-	expiration time.Duration
+	cfg    Config
+	parser *jwt.Parser
+
+	mu   sync.RWMutex
+	keys map[string]*Key
+
+	reloadMu       sync.Mutex
+	reload         func() error
+	reloadInterval time.Duration
+	lastReload     time.Time
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Login  string `json:"login"`
-	jwt.StandardClaims
+	UserID    string `json:"user_id"`
+	Login     string `json:"login"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
 }
 
-func NewManager(secret string, expiration time.Duration) *Manager {
+// Scopes returns the scopes of the space-separated scope claim (RFC 9068).
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+func NewManager(cfg Config) *Manager {
 	return &Manager{
-		secret:     secret,
-		expiration: expiration,
+		cfg: cfg,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{cfg.Algorithm}),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithLeeway(cfg.Leeway),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
 	}
 }
 
-func (m *Manager) GenerateToken(userID, login string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Login:  login,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(m.expiration).Unix(),
-			IssuedAt:  time.Now().Unix(),
+// GenerateToken issues an access token with a unique ID (jti) and returns it
+// along with its claims. The session ID ties it to the refresh token family
+// it was issued with.
+func (m *Manager) GenerateToken(userID, login, sessionID string, scopes []string) (string, *Claims, error) {
+	now := time.Now().Truncate(time.Second)
+	claims := &Claims{
+		UserID:    userID,
+		Login:     login,
+		SessionID: sessionID,
+		Scope:     strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateID(),
+			Issuer:    m.cfg.Issuer,
+			Audience:  jwt.ClaimStrings{m.cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.cfg.Expiration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secret))
+	if m.cfg.Algorithm == HS256 {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.cfg.Secret))
+		if err != nil {
+			return "", nil, err
+		}
+		return token, claims, nil
+	}
+
+	signer := m.signer(time.Now())
+	if signer == nil {
+		return "", nil, errors.New("no signing key")
+	}
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(signer.Algorithm), claims)
+	t.Header["kid"] = signer.ID
+	token, err := t.SignedString(signer.Private)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
 }
 
+// ValidateToken checks the signature and the registered claims of the token.
+// Errors wrap ErrInvalidToken.
 func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.secret), nil
-	})
+	claims := &Claims{}
+	if _, err := m.parser.ParseWithClaims(tokenString, claims, m.verificationKey); err != nil {
+		return nil, validationError(err)
+	}
 
-	if err != nil {
-		return nil, err
+	// The parser checks nbf and jti only when present; our tokens always
+	// carry them.
+	if claims.NotBefore == nil || claims.ID == "" {
+		return nil, ErrTokenClaims
+	}
+
+	return claims, nil
+}
+
+func validationError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenIssuer
+	default:
+		return ErrTokenClaims
+	}
+}
+
+// verificationKey picks the key for a token by its kid. The parser has
+// already rejected tokens signed with another algorithm than the configured.
+func (m *Manager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if m.cfg.Algorithm == HS256 {
+		return []byte(m.cfg.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.key(kid)
+	if !ok {
+		m.reloadKeys()
+		key, ok = m.key(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("key %q is not for %s", kid, token.Method.Alg())
 	}
+	return key.Private.Public(), nil
+}
+
+func (m *Manager) key(kid string) (*Key, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[kid]
+	return key, ok
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+// ReloadKeysOnMiss makes the manager call reload, which is expected to pass
+// the current keys to SetKeys, when a token names a key it does not know.
+// Another instance may have started signing with a new key before this one
+// has refreshed its keys. Tokens with made-up key IDs must not hammer the key
+// store, so reload is called at most once per interval.
+func (m *Manager) ReloadKeysOnMiss(reload func() error, interval time.Duration) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.reload = reload
+	m.reloadInterval = interval
+}
+
+// reloadKeys calls reload unless it was called within the interval. Requests
+// that arrive while it runs wait for it and then look the key up again.
+func (m *Manager) reloadKeys() {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if m.reload == nil || time.Since(m.lastReload) < m.reloadInterval {
+		return
 	}
+	m.lastReload = time.Now()
+	m.reload()
+}
+
+// SetKeys replaces the keys tokens are verified with. The newest key older
+// than PublishDelay signs new tokens; the others keep validating the tokens
+// they signed. Keys for another algorithm than the configured are ignored.
+func (m *Manager) SetKeys(keys []*Key) {
+	set := make(map[string]*Key, len(keys))
+	for _, key := range keys {
+		if key.Algorithm == m.cfg.Algorithm {
+			set[key.ID] = key
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = set
+}
+
+// signer returns the newest key published for PublishDelay by now. Until
+// there is one, such as right after the first key is made, the key published
+// the longest signs.
+func (m *Manager) signer(now time.Time) *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	published := now.Add(-m.cfg.PublishDelay)
+	var newest, oldest *Key
+	for _, key := range m.keys {
+		if !key.Created.After(published) && (newest == nil || key.Created.After(newest.Created)) {
+			newest = key
+		}
+		if oldest == nil || key.Created.Before(oldest.Created) {
+			oldest = key
+		}
+	}
+	if newest != nil {
+		return newest
+	}
+	return oldest
+}
+
+// JWKS returns the public keys tokens are verified with.
+func (m *Manager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created.After(keys[j].Created) })
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	return jwks
+}
+
+// GenerateRefreshToken returns an opaque refresh token, the hash to store in
+// its place and the time it expires.
+func (m *Manager) GenerateRefreshToken() (token, hash string, expiresAt time.Time) {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+
+	token = base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashRefreshToken(token), time.Now().Add(m.cfg.RefreshExpiration)
+}
 
-	return nil, errors.New("invalid token")
+func HashRefreshToken(token string) string {
+	return utils.Checksum([]byte(token))
 }
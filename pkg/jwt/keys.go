@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/mibrgmv/document-service/pkg/utils"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// Key is an asymmetric signing key. Tokens carry its ID in the kid header so
+// verifiers can pick the matching public key.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Created   time.Time
+}
+
+// GenerateKey creates a new key for the algorithm, RS256 or EdDSA.
+func GenerateKey(algorithm string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:        utils.GenerateID(),
+		Algorithm: algorithm,
+		Private:   private,
+		Created:   time.Now(),
+	}, nil
+}
+
+// MarshalPrivate encodes the private key as PKCS #8 PEM.
+func (k *Key) MarshalPrivate() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParseKey decodes a key encoded by MarshalPrivate and checks that it fits
+// the algorithm.
+func ParseKey(id, algorithm, privatePEM string, created time.Time) (*Key, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid key PEM")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id, Algorithm: algorithm, Created: created}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == RS256 {
+			key.Private = private
+		}
+	case ed25519.PrivateKey:
+		if algorithm == EdDSA {
+			key.Private = private
+		}
+	}
+	if key.Private == nil {
+		return nil, fmt.Errorf("key %s does not fit algorithm %q", id, algorithm)
+	}
+
+	return key, nil
+}
+
+// JWK is the public part of a key as published in a JWK set (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKSMaxAge is how long clients may cache the JWK set.
+const JWKSMaxAge = 5 * time.Minute
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *Key) JWK() JWK {
+	jwk := JWK{ID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+
+	switch public := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
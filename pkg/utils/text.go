@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxIndexedText caps how much of a text file is kept for full-text search.
+// Together with the leading part of the JSON that is indexed as well, the
+// search vector stays well below the 1 MB Postgres allows.
+const MaxIndexedText = 100 << 10
+
+// PlainText turns the leading bytes of a text file into a string Postgres
+// accepts: invalid UTF-8, including a rune cut off at the end, and NUL bytes
+// are dropped.
+func PlainText(data []byte) string {
+	if len(data) > MaxIndexedText {
+		data = data[:MaxIndexedText]
+	}
+
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "")
+	}
+	return strings.ReplaceAll(text, "\x00", "")
+}